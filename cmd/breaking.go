@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/pablor21/gonnotation/parser"
+	"github.com/pablor21/protoschemagen/plugin"
+)
+
+// Breaking compares the schema generated from the current sources against a baseline
+// descriptor set or .proto sources and exits with a non-zero status if wire-incompatible changes are found
+func Breaking() {
+	// Parse command line flags
+	configFile := flag.String("config", "", "Path to configuration file")
+	against := flag.String("against", "", "Baseline descriptor set or .proto file, or directory of descriptor sets and .proto sources")
+	flag.Parse()
+
+	if *against == "" {
+		log.Fatalf("The -against flag is required for the breaking command")
+	}
+
+	cfg := loadConfig(*configFile)
+
+	// Create multi-format generator
+	gen := parser.NewMultiFormatGenerator(cfg)
+
+	// Only check the schema, nothing is written
+	protoPlugin := plugin.NewPlugin(nil)
+	protoPlugin.CheckBreakingOnly(*against)
+	gen.RegisterPlugin(protoPlugin)
+
+	if err := gen.Generate(); err != nil {
+		log.Fatalf("Failed to check breaking changes: %v", err)
+	}
+
+	changes := protoPlugin.BreakingChanges()
+	if len(changes) == 0 {
+		fmt.Println("No breaking changes found")
+		return
+	}
+
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+	fmt.Printf("Found %d breaking change(s) against %s\n", len(changes), *against)
+	os.Exit(1)
+}
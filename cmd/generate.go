@@ -17,26 +17,59 @@ func Generate() {
 	configFile := flag.String("config", "", "Path to configuration file")
 	flag.Parse()
 
+	cfg := loadConfig(*configFile)
+
+	fmt.Printf("Config: %+v\n", cfg)
+	fmt.Printf("Packages: %v\n", cfg.Packages)
+
+	// Create multi-format generator
+	gen := parser.NewMultiFormatGenerator(cfg)
+
+	// Create protobuf plugin and register it
+	protoPlugin := plugin.NewPlugin(nil) // Use default config which will be overridden by cfg.Plugins
+
+	gen.RegisterPlugin(protoPlugin)
+
+	// Generate schema
+	if err := gen.Generate(); err != nil {
+		log.Fatalf("Failed to generate schema: %v", err)
+		os.Exit(1)
+	}
+
+	// After schema generation is complete, run protoc if stub generation is enabled
+	if protoPlugin.GetConfig().GenerateStubs != nil && protoPlugin.GetConfig().GenerateStubs.Enabled {
+		fmt.Println("Generating protobuf Go stubs...")
+		if err := plugin.GenerateProtobufGoFilesStandaloneWithConfigDir(protoPlugin.GetConfig().GenerateStubs, protoPlugin.GetConfig(), cfg.ConfigDir); err != nil {
+			log.Fatalf("Failed to generate protobuf Go files: %v", err)
+		}
+		fmt.Println("Protobuf Go stubs generation completed successfully!")
+	}
+
+	fmt.Println("Schema generation completed successfully!")
+}
+
+// loadConfig reads the configuration file, or builds the default configuration if none is given
+func loadConfig(configFile string) *parser.Config {
 	var cfg *parser.Config
 
-	if *configFile != "" {
+	if configFile != "" {
 		// Read configuration from file
-		fmt.Printf("Loading configuration from: %s\n", *configFile)
+		fmt.Printf("Loading configuration from: %s\n", configFile)
 
 		// Read the config file
-		data, err := os.ReadFile(*configFile)
+		data, err := os.ReadFile(configFile)
 		if err != nil {
-			log.Fatalf("Failed to read config file %s: %v", *configFile, err)
+			log.Fatalf("Failed to read config file %s: %v", configFile, err)
 		}
 
 		// Parse the config
 		cfg = &parser.Config{}
 		if err := yaml.Unmarshal(data, cfg); err != nil {
-			log.Fatalf("Failed to parse config file %s: %v", *configFile, err)
+			log.Fatalf("Failed to parse config file %s: %v", configFile, err)
 		}
 
 		// Set the config directory for relative path resolution
-		cfg.ConfigDir = filepath.Dir(*configFile)
+		cfg.ConfigDir = filepath.Dir(configFile)
 
 		// Enable debug logging
 		cfg.LogLevel = parser.Ptr(parser.LogLevelDebug)
@@ -69,33 +102,7 @@ func Generate() {
 		}
 	}
 
-	fmt.Printf("Config: %+v\n", cfg)
-	fmt.Printf("Packages: %v\n", cfg.Packages)
-
-	// Create multi-format generator
-	gen := parser.NewMultiFormatGenerator(cfg)
-
-	// Create protobuf plugin and register it
-	protoPlugin := plugin.NewPlugin(nil) // Use default config which will be overridden by cfg.Plugins
-
-	gen.RegisterPlugin(protoPlugin)
-
-	// Generate schema
-	if err := gen.Generate(); err != nil {
-		log.Fatalf("Failed to generate schema: %v", err)
-		os.Exit(1)
-	}
-
-	// After schema generation is complete, run protoc if stub generation is enabled
-	if protoPlugin.GetConfig().GenerateStubs != nil && protoPlugin.GetConfig().GenerateStubs.Enabled {
-		fmt.Println("Generating protobuf Go stubs...")
-		if err := plugin.GenerateProtobufGoFilesStandaloneWithConfigDir(protoPlugin.GetConfig().GenerateStubs, protoPlugin.GetConfig(), cfg.ConfigDir); err != nil {
-			log.Fatalf("Failed to generate protobuf Go files: %v", err)
-		}
-		fmt.Println("Protobuf Go stubs generation completed successfully!")
-	}
-
-	fmt.Println("Schema generation completed successfully!")
+	return cfg
 }
 
 func GenerateStubs() {
@@ -3,15 +3,17 @@ module github.com/pablor21/protoschemagen
 go 1.25.4
 
 require (
+	github.com/bufbuild/protocompile v0.14.1
 	github.com/pablor21/gonnotation v0.0.6
 	github.com/pablor21/goschemagen v0.0.7
+	golang.org/x/tools v0.39.0
+	google.golang.org/protobuf v1.36.9
 	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	golang.org/x/mod v0.30.0 // indirect
 	golang.org/x/sync v0.18.0 // indirect
-	golang.org/x/tools v0.39.0 // indirect
 )
 
 replace github.com/pablor21/goschemagen => ../goschemagen
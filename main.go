@@ -16,6 +16,10 @@ func main() {
 		// Remove "generate-stubs" from args so flag parsing works correctly
 		os.Args = append(os.Args[:1], os.Args[2:]...)
 		cmd.GenerateStubs()
+	} else if len(os.Args) > 1 && os.Args[1] == "breaking" {
+		// Remove "breaking" from args so flag parsing works correctly
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+		cmd.Breaking()
 	} else {
 		// Default behavior - just generate
 		cmd.Generate()
@@ -0,0 +1,451 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// BreakingChange describes a wire-incompatible difference between a baseline schema and the current one
+type BreakingChange struct {
+	Location string // Fully qualified element, e.g. "acme.v1.User.email"
+	Message  string
+}
+
+func (c BreakingChange) String() string {
+	return fmt.Sprintf("%s: %s", c.Location, c.Message)
+}
+
+// descriptorExtensions are the file extensions accepted when loading a baseline directory
+var descriptorExtensions = []string{".desc", ".pb", ".binpb"}
+
+// wireCompatibleTypes groups scalar types that share a wire encoding and can be swapped safely
+var wireCompatibleTypes = map[descriptorpb.FieldDescriptorProto_Type]string{
+	descriptorpb.FieldDescriptorProto_TYPE_INT32:    "varint",
+	descriptorpb.FieldDescriptorProto_TYPE_UINT32:   "varint",
+	descriptorpb.FieldDescriptorProto_TYPE_INT64:    "varint",
+	descriptorpb.FieldDescriptorProto_TYPE_UINT64:   "varint",
+	descriptorpb.FieldDescriptorProto_TYPE_BOOL:     "varint",
+	descriptorpb.FieldDescriptorProto_TYPE_ENUM:     "varint",
+	descriptorpb.FieldDescriptorProto_TYPE_SINT32:   "zigzag",
+	descriptorpb.FieldDescriptorProto_TYPE_SINT64:   "zigzag",
+	descriptorpb.FieldDescriptorProto_TYPE_FIXED32:  "fixed32",
+	descriptorpb.FieldDescriptorProto_TYPE_SFIXED32: "fixed32",
+	descriptorpb.FieldDescriptorProto_TYPE_FIXED64:  "fixed64",
+	descriptorpb.FieldDescriptorProto_TYPE_SFIXED64: "fixed64",
+	descriptorpb.FieldDescriptorProto_TYPE_STRING:   "bytes",
+	descriptorpb.FieldDescriptorProto_TYPE_BYTES:    "bytes",
+}
+
+// LoadDescriptorBaseline reads the baseline schema from a FileDescriptorSet or a .proto file, or
+// from every descriptor set (*.desc, *.pb, *.binpb) and .proto file found under a directory, e.g.
+// the output of a previous release. Imports of .proto files resolve relative to the directory.
+func LoadDescriptorBaseline(path string) (*descriptorpb.FileDescriptorSet, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline %s: %w", path, err)
+	}
+	if !info.IsDir() {
+		if isProtoFile(path) {
+			return compileProtoFiles(filepath.Dir(path), []string{filepath.Base(path)})
+		}
+		return readDescriptorSet(path)
+	}
+
+	baseline := &descriptorpb.FileDescriptorSet{}
+	seen := make(map[string]bool)
+	addFiles := func(set *descriptorpb.FileDescriptorSet) {
+		for _, fd := range set.File {
+			if !seen[fd.GetName()] {
+				seen[fd.GetName()] = true
+				baseline.File = append(baseline.File, fd)
+			}
+		}
+	}
+
+	var protoFiles []string
+	err = filepath.WalkDir(path, func(file string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if isProtoFile(file) {
+			rel, err := filepath.Rel(path, file)
+			if err != nil {
+				return err
+			}
+			protoFiles = append(protoFiles, filepath.ToSlash(rel))
+			return nil
+		}
+		if !isDescriptorFile(file) {
+			return nil
+		}
+		set, err := readDescriptorSet(file)
+		if err != nil {
+			return err
+		}
+		addFiles(set)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(protoFiles) > 0 {
+		set, err := compileProtoFiles(path, protoFiles)
+		if err != nil {
+			return nil, err
+		}
+		addFiles(set)
+	}
+	if len(baseline.File) == 0 {
+		return nil, fmt.Errorf("no descriptor sets or .proto files found in %s", path)
+	}
+	return baseline, nil
+}
+
+func isDescriptorFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range descriptorExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+func isProtoFile(path string) bool {
+	return strings.ToLower(filepath.Ext(path)) == ".proto"
+}
+
+func readDescriptorSet(path string) (*descriptorpb.FileDescriptorSet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read descriptor set %s: %w", path, err)
+	}
+	set := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(data, set); err != nil {
+		return nil, fmt.Errorf("failed to parse descriptor set %s: %w", path, err)
+	}
+	return set, nil
+}
+
+// compileProtoFiles parses .proto sources into descriptors, resolving their imports relative to
+// importPath and the well-known types from the protobuf runtime
+func compileProtoFiles(importPath string, files []string) (*descriptorpb.FileDescriptorSet, error) {
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			ImportPaths: []string{importPath},
+		}),
+	}
+	compiled, err := compiler.Compile(context.Background(), files...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile baseline .proto files in %s: %w", importPath, err)
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	for _, file := range compiled {
+		set.File = append(set.File, protodesc.ToFileDescriptorProto(file))
+	}
+	return set, nil
+}
+
+// descriptorIndex indexes the elements of a descriptor set by fully qualified name
+type descriptorIndex struct {
+	messages map[string]*descriptorpb.DescriptorProto
+	enums    map[string]*descriptorpb.EnumDescriptorProto
+	services map[string]*descriptorpb.ServiceDescriptorProto
+}
+
+func newDescriptorIndex(set *descriptorpb.FileDescriptorSet) *descriptorIndex {
+	idx := &descriptorIndex{
+		messages: make(map[string]*descriptorpb.DescriptorProto),
+		enums:    make(map[string]*descriptorpb.EnumDescriptorProto),
+		services: make(map[string]*descriptorpb.ServiceDescriptorProto),
+	}
+	if set == nil {
+		return idx
+	}
+
+	for _, file := range set.File {
+		// Bundled well-known types are not part of the schema
+		if _, err := protoregistry.GlobalFiles.FindFileByPath(file.GetName()); err == nil {
+			continue
+		}
+		prefix := ""
+		if file.GetPackage() != "" {
+			prefix = file.GetPackage() + "."
+		}
+		for _, msg := range file.MessageType {
+			idx.addMessage(prefix, msg)
+		}
+		for _, enum := range file.EnumType {
+			idx.enums[prefix+enum.GetName()] = enum
+		}
+		for _, service := range file.Service {
+			idx.services[prefix+service.GetName()] = service
+		}
+	}
+	return idx
+}
+
+func (idx *descriptorIndex) addMessage(prefix string, msg *descriptorpb.DescriptorProto) {
+	name := prefix + msg.GetName()
+	idx.messages[name] = msg
+	for _, nested := range msg.NestedType {
+		idx.addMessage(name+".", nested)
+	}
+	for _, enum := range msg.EnumType {
+		idx.enums[name+"."+enum.GetName()] = enum
+	}
+}
+
+// CheckBreaking compares the current schema against a baseline and returns the wire-incompatible
+// changes: removed messages, enums, services and RPCs, fields and enum values removed without
+// their number being reserved, field numbers reused with a different type, fields switched
+// between repeated and singular, and RPCs whose request/response type or streaming mode changed
+func CheckBreaking(baseline, current *descriptorpb.FileDescriptorSet) []BreakingChange {
+	before := newDescriptorIndex(baseline)
+	after := newDescriptorIndex(current)
+
+	var changes []BreakingChange
+	for _, name := range sortedKeys(before.messages) {
+		old := before.messages[name]
+		msg, ok := after.messages[name]
+		if !ok {
+			// Map entries go away with their field, which is reported on its own
+			if !old.GetOptions().GetMapEntry() {
+				changes = append(changes, BreakingChange{Location: name, Message: "message was removed"})
+			}
+			continue
+		}
+		changes = append(changes, checkMessage(name, old, msg)...)
+	}
+
+	for _, name := range sortedKeys(before.enums) {
+		enum, ok := after.enums[name]
+		if !ok {
+			changes = append(changes, BreakingChange{Location: name, Message: "enum was removed"})
+			continue
+		}
+		changes = append(changes, checkEnum(name, before.enums[name], enum)...)
+	}
+
+	for _, name := range sortedKeys(before.services) {
+		service, ok := after.services[name]
+		if !ok {
+			changes = append(changes, BreakingChange{Location: name, Message: "service was removed"})
+			continue
+		}
+		changes = append(changes, checkService(name, before.services[name], service)...)
+	}
+
+	return changes
+}
+
+func checkMessage(name string, old, msg *descriptorpb.DescriptorProto) []BreakingChange {
+	fields := make(map[int32]*descriptorpb.FieldDescriptorProto, len(msg.Field))
+	for _, f := range msg.Field {
+		fields[f.GetNumber()] = f
+	}
+
+	var changes []BreakingChange
+	for _, oldField := range old.Field {
+		location := name + "." + oldField.GetName()
+		field, ok := fields[oldField.GetNumber()]
+		if !ok {
+			if !isReservedMessageNumber(msg, oldField.GetNumber()) {
+				changes = append(changes, BreakingChange{
+					Location: location,
+					Message:  fmt.Sprintf("field %d was removed without reserving its number", oldField.GetNumber()),
+				})
+			}
+			continue
+		}
+
+		if !isCompatibleFieldType(oldField, field) {
+			changes = append(changes, BreakingChange{
+				Location: location,
+				Message: fmt.Sprintf("field number %d reused with a different type: was %s, now %s",
+					oldField.GetNumber(), describeFieldType(oldField), describeFieldType(field)),
+			})
+		}
+
+		wasRepeated := oldField.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+		isRepeated := field.GetLabel() == descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+		if wasRepeated != isRepeated {
+			changes = append(changes, BreakingChange{
+				Location: location,
+				Message:  fmt.Sprintf("field %d changed from %s to %s", oldField.GetNumber(), cardinality(wasRepeated), cardinality(isRepeated)),
+			})
+		}
+	}
+	return changes
+}
+
+func checkEnum(name string, old, enum *descriptorpb.EnumDescriptorProto) []BreakingChange {
+	numbers := make(map[int32]bool, len(enum.Value))
+	for _, v := range enum.Value {
+		numbers[v.GetNumber()] = true
+	}
+
+	var changes []BreakingChange
+	for _, oldValue := range old.Value {
+		if numbers[oldValue.GetNumber()] || isReservedEnumNumber(enum, oldValue.GetNumber()) {
+			continue
+		}
+		changes = append(changes, BreakingChange{
+			Location: name + "." + oldValue.GetName(),
+			Message:  fmt.Sprintf("enum value %d was removed without reserving its number", oldValue.GetNumber()),
+		})
+	}
+	return changes
+}
+
+func checkService(name string, old, service *descriptorpb.ServiceDescriptorProto) []BreakingChange {
+	methods := make(map[string]*descriptorpb.MethodDescriptorProto, len(service.Method))
+	for _, m := range service.Method {
+		methods[m.GetName()] = m
+	}
+
+	var changes []BreakingChange
+	for _, oldMethod := range old.Method {
+		location := name + "." + oldMethod.GetName()
+		method, ok := methods[oldMethod.GetName()]
+		if !ok {
+			changes = append(changes, BreakingChange{Location: location, Message: "RPC was removed"})
+			continue
+		}
+
+		if oldMethod.GetInputType() != method.GetInputType() {
+			changes = append(changes, BreakingChange{
+				Location: location,
+				Message:  fmt.Sprintf("request type changed from %s to %s", oldMethod.GetInputType(), method.GetInputType()),
+			})
+		}
+		if oldMethod.GetOutputType() != method.GetOutputType() {
+			changes = append(changes, BreakingChange{
+				Location: location,
+				Message:  fmt.Sprintf("response type changed from %s to %s", oldMethod.GetOutputType(), method.GetOutputType()),
+			})
+		}
+		if oldMethod.GetClientStreaming() != method.GetClientStreaming() || oldMethod.GetServerStreaming() != method.GetServerStreaming() {
+			changes = append(changes, BreakingChange{
+				Location: location,
+				Message:  fmt.Sprintf("streaming mode changed from %s to %s", streamingMode(oldMethod), streamingMode(method)),
+			})
+		}
+	}
+	return changes
+}
+
+// isCompatibleFieldType checks if a field can change from old to field without breaking the wire format
+func isCompatibleFieldType(old, field *descriptorpb.FieldDescriptorProto) bool {
+	if old.GetType() == field.GetType() {
+		return old.GetTypeName() == field.GetTypeName()
+	}
+	oldGroup, ok := wireCompatibleTypes[old.GetType()]
+	return ok && oldGroup == wireCompatibleTypes[field.GetType()]
+}
+
+func describeFieldType(field *descriptorpb.FieldDescriptorProto) string {
+	if field.GetTypeName() != "" {
+		return strings.TrimPrefix(field.GetTypeName(), ".")
+	}
+	return strings.ToLower(strings.TrimPrefix(field.GetType().String(), "TYPE_"))
+}
+
+func cardinality(repeated bool) string {
+	if repeated {
+		return "repeated"
+	}
+	return "singular"
+}
+
+func streamingMode(method *descriptorpb.MethodDescriptorProto) string {
+	switch {
+	case method.GetClientStreaming() && method.GetServerStreaming():
+		return "bidirectional streaming"
+	case method.GetClientStreaming():
+		return "client streaming"
+	case method.GetServerStreaming():
+		return "server streaming"
+	default:
+		return "unary"
+	}
+}
+
+// isReservedMessageNumber checks the reserved ranges of a message (end is exclusive)
+func isReservedMessageNumber(msg *descriptorpb.DescriptorProto, number int32) bool {
+	for _, r := range msg.ReservedRange {
+		if number >= r.GetStart() && number < r.GetEnd() {
+			return true
+		}
+	}
+	return false
+}
+
+// isReservedEnumNumber checks the reserved ranges of an enum (end is inclusive)
+func isReservedEnumNumber(enum *descriptorpb.EnumDescriptorProto, number int32) bool {
+	for _, r := range enum.ReservedRange {
+		if number >= r.GetStart() && number <= r.GetEnd() {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveBreakingBaseline returns the baseline to check against and whether breaking changes are fatal.
+// A baseline given on the command line takes precedence over the breaking_check config section.
+func (g *Generator) resolveBreakingBaseline() (string, bool) {
+	if g.formatGen.breakingAgainst != "" {
+		return g.formatGen.breakingAgainst, false
+	}
+
+	cfg := g.formatGen.config.BreakingCheck
+	if cfg == nil || !cfg.Enabled || cfg.Against == "" {
+		return "", false
+	}
+	path := cfg.Against
+	if !filepath.IsAbs(path) && g.ctx.CoreConfig != nil && g.ctx.CoreConfig.ConfigDir != "" {
+		path = filepath.Join(g.ctx.CoreConfig.ConfigDir, path)
+	}
+	return path, cfg.FailOnBreaking
+}
+
+// checkBreakingChanges compares the generated schema against the configured baseline
+func (g *Generator) checkBreakingChanges() error {
+	against, failOnBreaking := g.resolveBreakingBaseline()
+	if against == "" {
+		return nil
+	}
+
+	baseline, err := LoadDescriptorBaseline(against)
+	if err != nil {
+		return err
+	}
+	current, err := g.BuildFileDescriptorSet()
+	if err != nil {
+		return fmt.Errorf("failed to build descriptor set: %w", err)
+	}
+
+	changes := CheckBreaking(baseline, current)
+	g.formatGen.breakingChanges = changes
+	for _, change := range changes {
+		g.ctx.Logger.Error(fmt.Sprintf("Breaking change: %s", change))
+	}
+
+	if failOnBreaking && len(changes) > 0 {
+		return fmt.Errorf("found %d breaking change(s) against %s", len(changes), against)
+	}
+	return nil
+}
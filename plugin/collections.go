@@ -0,0 +1,325 @@
+package plugin
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+	"unicode"
+)
+
+// collectionWrapper is a message synthesized for a list or map that is the value of another
+// collection, which protobuf can't declare directly. The collection is its values field.
+type collectionWrapper struct {
+	Name      string // Message name, e.g. StringList or StringInt32Map
+	KeyType   string // Proto key type of map wrappers, empty for lists
+	ValueType string // Proto type of the values
+}
+
+// isCollection checks if a type is a list or a map. Byte slices are bytes scalars.
+func isCollection(t ast.Expr) bool {
+	switch v := t.(type) {
+	case *ast.ArrayType:
+		return !isByteArray(v)
+	case *ast.MapType:
+		return true
+	}
+	return false
+}
+
+// isNestedCollection checks if a type is a list or map whose values are collections
+func isNestedCollection(t ast.Expr) bool {
+	switch v := t.(type) {
+	case *ast.ArrayType:
+		return !isByteArray(v) && isCollection(v.Elt)
+	case *ast.MapType:
+		return isCollection(v.Value)
+	}
+	return false
+}
+
+// wrapperName returns the name of the message wrapping a collection, after the proto types of
+// its keys and values: <Value>List for lists, <Key><Value>Map for maps ([]string -> StringList,
+// map[string][]int32 -> StringInt32ListMap)
+func (g *Generator) wrapperName(t ast.Expr) string {
+	if m, ok := t.(*ast.MapType); ok {
+		return wrapperTypeName(g.mapGoTypeToProto(m.Key)) + wrapperTypeName(g.collectionValueType(m.Value)) + "Map"
+	}
+	return wrapperTypeName(g.collectionValueType(t.(*ast.ArrayType).Elt)) + "List"
+}
+
+// wrapperTypeName turns a proto type into a part of a wrapper name: scalars are capitalized and
+// the package of qualified types is dropped (google.protobuf.Timestamp -> Timestamp)
+func wrapperTypeName(protoType string) string {
+	var name strings.Builder
+	for _, part := range strings.Split(protoType, ".") {
+		if part != "" && unicode.IsUpper(rune(part[0])) {
+			name.WriteString(part)
+		}
+	}
+	if name.Len() == 0 && protoType != "" {
+		name.WriteString(strings.ToUpper(protoType[:1]) + protoType[1:])
+	}
+	return name.String()
+}
+
+// collectionValueType returns the proto type of the values of a collection: the wrapper of the
+// values that are collections themselves
+func (g *Generator) collectionValueType(t ast.Expr) string {
+	if isCollection(t) {
+		return g.wrapperName(t)
+	}
+	return g.mapGoTypeToProto(t)
+}
+
+// collectionWrappers returns the wrappers the fields of a message need, declared inside it so
+// messages of different files never declare the same wrapper twice
+func (g *Generator) collectionWrappers(fields []messageField) []collectionWrapper {
+	wrappers := make(map[string]collectionWrapper)
+	var add func(t ast.Expr)
+	add = func(t ast.Expr) {
+		name := g.wrapperName(t)
+		if _, exists := wrappers[name]; exists {
+			return
+		}
+		wrapper := collectionWrapper{Name: name}
+		value := t
+		if m, ok := t.(*ast.MapType); ok {
+			wrapper.KeyType = g.mapGoTypeToProto(m.Key)
+			value = m.Value
+		} else {
+			value = t.(*ast.ArrayType).Elt
+		}
+		wrapper.ValueType = g.collectionValueType(value)
+		wrappers[name] = wrapper
+		if isCollection(value) {
+			add(value)
+		}
+	}
+
+	for _, mf := range fields {
+		switch v := mf.Field.Type.(type) {
+		case *ast.ArrayType:
+			if isNestedCollection(v) {
+				add(v.Elt)
+			}
+		case *ast.MapType:
+			if isNestedCollection(v) {
+				add(v.Value)
+			}
+		}
+	}
+
+	result := make([]collectionWrapper, 0, len(wrappers))
+	for _, name := range sortedKeys(wrappers) {
+		result = append(result, wrappers[name])
+	}
+	return result
+}
+
+// generateCollectionWrapper writes the declaration of a wrapper message
+func (g *Generator) generateCollectionWrapper(out *strings.Builder, wrapper collectionWrapper) {
+	g.useType(wrapper.ValueType)
+	fmt.Fprintf(out, "message %s {\n", wrapper.Name)
+	if wrapper.KeyType != "" {
+		fmt.Fprintf(out, "  map<%s, %s> values = 1;\n", wrapper.KeyType, wrapper.ValueType)
+	} else {
+		fmt.Fprintf(out, "  repeated %s values = 1;\n", wrapper.ValueType)
+	}
+	out.WriteString("}\n")
+}
+
+// CollectionConversionInfo converts a list or map whose values are collections, each value
+// converted to the wrapper message declared for it in the parent message
+type CollectionConversionInfo struct {
+	Name           string // Prefix of the helpers, the Go name of the wrapper (Matrix_DoubleListList)
+	GoType         string // e.g. [][]float64
+	ProtoType      string // e.g. []*pb.Matrix_DoubleList
+	KeyToProto     string // Conversion of the key (or index) k
+	KeyFromProto   string
+	ValueToProto   string // Conversion of the value v
+	ValueFromProto string
+}
+
+// collectionHelpers returns the prefix of the helpers converting a collection of a message
+func (g *StubGenerator) collectionHelpers(typeInfo *TypeInfo, t ast.Expr) string {
+	return g.protoTypeName(typeInfo) + "_" + g.mainGenerator.wrapperName(t)
+}
+
+// collectCollectionConversions returns the conversions of the fields holding collections of
+// collections, and of the collections nested in them
+func (g *StubGenerator) collectCollectionConversions() []*CollectionConversionInfo {
+	// Fields that can't be converted fail checkCollections before anything is written
+	conversions, _ := g.collectionConversions()
+
+	result := make([]*CollectionConversionInfo, 0, len(conversions))
+	for _, name := range sortedKeys(conversions) {
+		result = append(result, conversions[name])
+	}
+	return result
+}
+
+// checkCollections checks that the keys and values of the fields holding collections of
+// collections can be converted, their adapters wouldn't compile otherwise
+func (g *StubGenerator) checkCollections() error {
+	_, err := g.collectionConversions()
+	return err
+}
+
+// collectionConversions returns the conversions of the fields holding collections, by helpers
+// prefix, or the first field whose keys or values can't be converted
+func (g *StubGenerator) collectionConversions() (map[string]*CollectionConversionInfo, error) {
+	conversions := make(map[string]*CollectionConversionInfo)
+	for _, typeName := range sortedKeys(g.originalTypes) {
+		typeInfo := g.originalTypes[typeName]
+		for _, field := range typeInfo.Fields {
+			if field.collectionName == "" {
+				continue
+			}
+			if _, ok := g.collectionConversion(typeInfo, field.goType, conversions); !ok {
+				return conversions, fmt.Errorf("field %s.%s: can't convert the keys or values of %s", typeInfo.Name, field.GoName, field.Type)
+			}
+		}
+	}
+	return conversions, nil
+}
+
+// collectionConversion registers the conversion of a collection and of the collections nested in
+// it, and returns the prefix of its helpers
+func (g *StubGenerator) collectionConversion(typeInfo *TypeInfo, t ast.Expr, conversions map[string]*CollectionConversionInfo) (string, bool) {
+	name := g.collectionHelpers(typeInfo, t)
+	if _, exists := conversions[name]; exists {
+		return name, true
+	}
+
+	pkgAlias := g.getPackageAlias(typeInfo.Package)
+	conversion := &CollectionConversionInfo{
+		Name:         name,
+		GoType:       g.qualifiedGoType(t, pkgAlias),
+		KeyToProto:   "k",
+		KeyFromProto: "k",
+	}
+
+	var value ast.Expr
+	var protoKey string
+	if m, ok := t.(*ast.MapType); ok {
+		key, ok := g.scalarConversion(m.Key, pkgAlias)
+		if !ok {
+			return "", false
+		}
+		protoKey, conversion.KeyToProto, conversion.KeyFromProto = key.protoType, key.toProto("k"), key.fromProto("k")
+		value = m.Value
+	} else {
+		value = t.(*ast.ArrayType).Elt
+	}
+
+	protoValue, ok := g.collectionValue(typeInfo, value, conversions)
+	if !ok {
+		return "", false
+	}
+	conversion.ValueToProto, conversion.ValueFromProto = protoValue.toProto("v"), protoValue.fromProto("v")
+	if protoKey != "" {
+		conversion.ProtoType = fmt.Sprintf("map[%s]%s", protoKey, protoValue.protoType)
+	} else {
+		conversion.ProtoType = "[]" + protoValue.protoType
+	}
+	conversions[name] = conversion
+	return name, true
+}
+
+// valueConversion is the protobuf Go type of a collection key or value and its conversions,
+// formats applied to the converted expression
+type valueConversion struct {
+	protoType  string
+	toFormat   string
+	fromFormat string
+}
+
+func (c valueConversion) toProto(value string) string   { return fmt.Sprintf(c.toFormat, value) }
+func (c valueConversion) fromProto(value string) string { return fmt.Sprintf(c.fromFormat, value) }
+
+// collectionValue returns the conversion of the values of a collection: nested collections
+// convert to their wrapper, messages and enums through their helpers
+func (g *StubGenerator) collectionValue(typeInfo *TypeInfo, t ast.Expr, conversions map[string]*CollectionConversionInfo) (valueConversion, bool) {
+	if isCollection(t) {
+		name, ok := g.collectionConversion(typeInfo, t, conversions)
+		if !ok {
+			return valueConversion{}, false
+		}
+		wrapper := g.protobufAlias(typeInfo.Namespace) + "." + name
+		return valueConversion{
+			protoType:  "*" + wrapper,
+			toFormat:   "&" + wrapper + "{Values: " + name + "ToProto(%s)}",
+			fromFormat: name + "FromProto(%s.GetValues())",
+		}, true
+	}
+	return g.elementConversion(t, g.getPackageAlias(typeInfo.Package))
+}
+
+// elementConversion returns the conversion of a list or map value that isn't a collection:
+// mapped and builtin types through their helpers, messages and enums through their adapters,
+// scalars with a type conversion when the Go types differ
+func (g *StubGenerator) elementConversion(t ast.Expr, pkgAlias string) (valueConversion, bool) {
+	goType := g.getGoTypeName(t)
+	if mapping, ok := g.typeMapping(goType); ok && !isPointerOrSlice(goType) {
+		protoType := protoScalarGoTypes[mapping.ProtoType]
+		if mapping.GoType != "" {
+			protoType, _ = g.goReference(mapping.GoType)
+		}
+		toProto, _ := g.goReference(mapping.ToProto)
+		fromProto, _ := g.goReference(mapping.FromProto)
+		return valueConversion{protoType: protoType, toFormat: toProto + "(%s)", fromFormat: fromProto + "(%s)"}, protoType != ""
+	}
+	if t, ok := g.mainGenerator.timeType(strings.TrimPrefix(goType, "*")); ok {
+		conversion, _ := g.timeValueConversion(goType, "ToProto", "%s")
+		fromProto, _ := g.timeValueConversion(goType, "FromProto", "%s")
+		return valueConversion{protoType: g.timeProtoGoType(t), toFormat: conversion, fromFormat: fromProto}, true
+	}
+	if name, builtin, ok := g.builtinHelpers(goType); ok {
+		protoType, scalar := protoScalarGoTypes[builtin.proto]
+		pointer := strings.HasPrefix(goType, "*")
+		if pointer && !builtin.pointer {
+			// Pointers to the types converted by value are optional, which values aren't
+			return valueConversion{}, false
+		}
+		toProto, fromProto := g.builtinValueHelper(name, builtin, "ToProto"), g.builtinValueHelper(name, builtin, "FromProto")
+		if pointer {
+			toProto, fromProto = g.sanitizeTypeName(name)+"ToProto", g.sanitizeTypeName(name)+"FromProto"
+		}
+		return valueConversion{protoType: protoType, toFormat: toProto + "(%s)", fromFormat: fromProto + "(%s)"}, scalar
+	}
+
+	typeName := g.extractTypeName(strings.TrimPrefix(goType, "*"))
+	if valueInfo, ok := g.originalTypes[typeName]; ok {
+		protoType := g.protobufAlias(valueInfo.Namespace) + "." + g.protoTypeName(valueInfo)
+		switch {
+		case valueInfo.IsEnum:
+			return valueConversion{protoType: protoType, toFormat: typeName + "ToProto(%s)", fromFormat: typeName + "FromProto(%s)"}, !strings.HasPrefix(goType, "*")
+		case strings.HasPrefix(goType, "*"):
+			return valueConversion{
+				protoType:  "*" + protoType,
+				toFormat:   "ConvertPointerToProto_" + typeName + "(%s)",
+				fromFormat: "ConvertPointerFromProto_" + typeName + "(%s)",
+			}, true
+		default:
+			return valueConversion{protoType: "*" + protoType, toFormat: typeName + "ToProto(%s)", fromFormat: typeName + "FromProto(%s)"}, true
+		}
+	}
+	return g.scalarConversion(t, pkgAlias)
+}
+
+// scalarConversion returns the conversion of a Go type declared as a protobuf scalar: none when
+// the Go types match, a type conversion otherwise (int -> int32, type UserID string -> string)
+func (g *StubGenerator) scalarConversion(t ast.Expr, pkgAlias string) (valueConversion, bool) {
+	if _, pointer := t.(*ast.StarExpr); pointer {
+		return valueConversion{}, false
+	}
+	protoType, ok := protoScalarGoTypes[g.mainGenerator.mapGoTypeToProto(t)]
+	if !ok {
+		return valueConversion{}, false
+	}
+	goType := g.qualifiedGoType(t, pkgAlias)
+	if goType == protoType {
+		return valueConversion{protoType: protoType, toFormat: "%s", fromFormat: "%s"}, true
+	}
+	return valueConversion{protoType: protoType, toFormat: protoType + "(%s)", fromFormat: goType + "(%s)"}, true
+}
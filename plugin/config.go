@@ -19,7 +19,7 @@ type Config struct {
 	// Output is the directory where files will be generated
 	Output string `yaml:"output"`
 
-	// Output file name pattern (supports {schema_name}, {package}, {namespace})
+	// Output file name pattern (supports {schema_name}, {package}, {namespace}, {type_name})
 	// Example: "{schema_name}.proto" or "{package}.proto"
 	OutputFileName string `yaml:"output_file_name"`
 
@@ -27,20 +27,28 @@ type Config struct {
 	// Supported: proto, json-schema, markdown, typescript, descriptor
 	OutputFormats []string `yaml:"output_formats"`
 
-	// Generation strategy: "single", "follow", "package", "namespace"
+	// Generation strategy: "single", "multiple", "follow", "package", "namespace"
 	GenerationStrategy parser.GenStrategy `yaml:"generation_strategy"`
 
 	// Deprecated: use generation_strategy instead
 	GenStrategy parser.GenStrategy `yaml:"strategy,omitempty"`
 
 	// Protobuf-specific features
-	Syntax          string `yaml:"syntax"`           // proto2 or proto3 (default: proto3)
+	Syntax          string `yaml:"syntax"`           // proto2, proto3 or editions (default: proto3)
 	Package         string `yaml:"package"`          // Protobuf package name
 	GoPackage       string `yaml:"go_package"`       // Go package import path
 	JavaPackage     string `yaml:"java_package"`     // Java package name
 	JavaOuterClass  string `yaml:"java_outer_class"` // Java outer class name
 	OptimizeFor     string `yaml:"optimize_for"`     // SPEED, CODE_SIZE, LITE_RUNTIME
 	GenerateService bool   `yaml:"generate_service"` // Generate gRPC service definitions
+	OptionalStyle   string `yaml:"optional_style"`   // proto3 pointer scalars: "optional" (default) or "wrappers"
+
+	// Package and go_package of the files of each namespace (namespace strategy)
+	Namespaces map[string]NamespaceConfig `yaml:"namespaces"`
+
+	// Editions settings, used when syntax is "editions"
+	Edition  string          `yaml:"edition"`  // Edition to write (default: 2023)
+	Features EditionFeatures `yaml:"features"` // File-level feature defaults
 
 	// Field numbering
 	AutoNumberFields bool     `yaml:"auto_number_fields"` // Auto-assign field numbers
@@ -48,8 +56,23 @@ type Config struct {
 	ReservedNumbers  []int    `yaml:"reserved_numbers"`   // Reserved field numbers
 	ReservedNames    []string `yaml:"reserved_names"`     // Reserved field names
 
+	// Enum value naming and numbering
+	EnumStyle EnumStyleConfig `yaml:"enum_style"`
+
+	// Lockfile recording assigned field/enum value numbers, keeps auto-numbering stable
+	// across regenerations (e.g. "protoschemagen.lock.json"). Empty disables it.
+	LockFile string `yaml:"lock_file"`
+
+	// Breaking change detection against a previous descriptor set
+	BreakingCheck *BreakingCheckConfig `yaml:"breaking_check,omitempty"`
+
 	CustomImports []string `yaml:"custom_imports"` // Additional proto imports
 
+	// Proto files declaring the external types used by type_mappings, by proto type or
+	// package (e.g. "acme.money.Amount": "acme/money/amount.proto"). The files of the
+	// well-known and google.type types are imported automatically.
+	TypeImports map[string]string `yaml:"type_imports"`
+
 	// Additional file options (e.g., csharp_namespace, php_namespace, ruby_package, etc.)
 	// These will be written as "option <key> = "<value>";" in the generated proto file
 	Options map[string]string `yaml:"options"`
@@ -73,11 +96,18 @@ type StubConfig struct {
 	Templates                TemplateConfig    `yaml:"templates"`
 }
 
+// BreakingCheckConfig configures breaking change detection
+type BreakingCheckConfig struct {
+	Enabled        bool   `yaml:"enabled"`
+	Against        string `yaml:"against"`          // Descriptor set or .proto file, or directory of them
+	FailOnBreaking bool   `yaml:"fail_on_breaking"` // Fail the generation when breaking changes are found
+}
+
 // TypeMappingConfig configures how original types map to protobuf types
 type TypeMappingConfig struct {
-	AutoDetect        bool              `yaml:"auto_detect"`
-	PreserveTimeTypes bool              `yaml:"preserve_time_types"`
-	CustomMappings    map[string]string `yaml:"custom_mappings"`
+	AutoDetect        bool                   `yaml:"auto_detect"`
+	PreserveTimeTypes *bool                  `yaml:"preserve_time_types"` // Time types map to Timestamp and Duration (default), or to string and int64
+	CustomMappings    map[string]TypeMapping `yaml:"custom_mappings"`     // Go types that aren't parsed, see TypeMapping
 }
 
 func NewConfig() *Config {
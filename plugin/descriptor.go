@@ -0,0 +1,885 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pablor21/gonnotation/parser"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	// Register the well-known types so their descriptors can be bundled as dependencies
+	_ "google.golang.org/protobuf/types/known/anypb"
+	_ "google.golang.org/protobuf/types/known/durationpb"
+	_ "google.golang.org/protobuf/types/known/emptypb"
+	_ "google.golang.org/protobuf/types/known/fieldmaskpb"
+	_ "google.golang.org/protobuf/types/known/structpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+	_ "google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// Field numbers of the descriptor.proto messages, used to build SourceCodeInfo paths
+const (
+	fileMessageTypeTag    = 4
+	fileEnumTypeTag       = 5
+	fileServiceTag        = 6
+	fileExtensionTag      = 7
+	messageFieldTag       = 2
+	messageNestedTypeTag  = 3
+	messageOneofTag       = 8
+	enumValueTag          = 2
+	serviceMethodTag      = 2
+	mapEntryKeyNumber     = 1
+	mapEntryValueNumber   = 2
+	schemaImportDirPrefix = "schema/"
+)
+
+// scalarFieldTypes maps proto scalar type names to descriptor field types
+var scalarFieldTypes = map[string]descriptorpb.FieldDescriptorProto_Type{
+	"double":   descriptorpb.FieldDescriptorProto_TYPE_DOUBLE,
+	"float":    descriptorpb.FieldDescriptorProto_TYPE_FLOAT,
+	"int64":    descriptorpb.FieldDescriptorProto_TYPE_INT64,
+	"uint64":   descriptorpb.FieldDescriptorProto_TYPE_UINT64,
+	"int32":    descriptorpb.FieldDescriptorProto_TYPE_INT32,
+	"fixed64":  descriptorpb.FieldDescriptorProto_TYPE_FIXED64,
+	"fixed32":  descriptorpb.FieldDescriptorProto_TYPE_FIXED32,
+	"bool":     descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+	"string":   descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	"bytes":    descriptorpb.FieldDescriptorProto_TYPE_BYTES,
+	"uint32":   descriptorpb.FieldDescriptorProto_TYPE_UINT32,
+	"sfixed32": descriptorpb.FieldDescriptorProto_TYPE_SFIXED32,
+	"sfixed64": descriptorpb.FieldDescriptorProto_TYPE_SFIXED64,
+	"sint32":   descriptorpb.FieldDescriptorProto_TYPE_SINT32,
+	"sint64":   descriptorpb.FieldDescriptorProto_TYPE_SINT64,
+}
+
+// descriptorBuilder builds a FileDescriptorProto from the same data used to write the .proto file
+type descriptorBuilder struct {
+	g            *Generator
+	pkg          string
+	enumNames    map[string]bool
+	messageNames map[string]bool // top-level messages, used to qualify nested ones
+	locator      *sourceLocator
+	locations    []*descriptorpb.SourceCodeInfo_Location
+}
+
+// BuildFileDescriptorSet builds a serialized-ready google.protobuf.FileDescriptorSet for the schema,
+// with one file per generated .proto file. Imports that can be resolved (well-known types and
+// the other generated files) are bundled ahead of the files importing them, like
+// protoc --include_imports; other imports are only listed as dependencies.
+func (g *Generator) BuildFileDescriptorSet() (*descriptorpb.FileDescriptorSet, error) {
+	files, err := g.buildFileDescriptors()
+	if err != nil {
+		return nil, err
+	}
+
+	generated := make(map[string]*descriptorpb.FileDescriptorProto)
+	for _, file := range files {
+		generated[file.GetName()] = file
+	}
+
+	set := &descriptorpb.FileDescriptorSet{}
+	seen := make(map[string]bool)
+	var appendFile func(file *descriptorpb.FileDescriptorProto)
+	appendFile = func(file *descriptorpb.FileDescriptorProto) {
+		if seen[file.GetName()] {
+			return
+		}
+		seen[file.GetName()] = true
+		for _, dep := range file.Dependency {
+			if depFile, ok := generated[dep]; ok {
+				appendFile(depFile)
+			} else {
+				appendRegisteredFile(set, dep, seen)
+			}
+		}
+		set.File = append(set.File, file)
+	}
+	for _, file := range files {
+		appendFile(file)
+	}
+
+	return set, nil
+}
+
+// buildFileDescriptors builds the descriptors of the generated files: one per file group with the
+// multi-file strategies, each with the package and names of its own file
+func (g *Generator) buildFileDescriptors() ([]*descriptorpb.FileDescriptorProto, error) {
+	if g.files == nil {
+		file, err := g.BuildFileDescriptor()
+		if err != nil {
+			return nil, err
+		}
+		return []*descriptorpb.FileDescriptorProto{file}, nil
+	}
+
+	var files []*descriptorpb.FileDescriptorProto
+	for _, group := range g.files.groups {
+		groupGen := g.groupGenerator(group)
+		content, err := groupGen.Generate()
+		if err != nil {
+			return nil, fmt.Errorf("error generating proto file %s: %w", group.fileName, err)
+		}
+		// Groups without declarations aren't written
+		if len(content) == 0 {
+			continue
+		}
+		file, err := groupGen.buildFileDescriptor(content)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// appendRegisteredFile appends a registered file and its dependencies (dependencies first)
+func appendRegisteredFile(set *descriptorpb.FileDescriptorSet, path string, seen map[string]bool) {
+	if seen[path] {
+		return
+	}
+	seen[path] = true
+
+	fd, err := protoregistry.GlobalFiles.FindFileByPath(path)
+	if err != nil {
+		return // Not a known file, can't be bundled
+	}
+
+	imports := fd.Imports()
+	for i := 0; i < imports.Len(); i++ {
+		appendRegisteredFile(set, imports.Get(i).Path(), seen)
+	}
+	set.File = append(set.File, protodesc.ToFileDescriptorProto(fd))
+}
+
+// BuildFileDescriptor builds the FileDescriptorProto of the generated .proto file,
+// including source code info with the comments written to the file
+func (g *Generator) BuildFileDescriptor() (*descriptorpb.FileDescriptorProto, error) {
+	// Generate the proto source first so source locations match the written file
+	content, err := g.Generate()
+	if err != nil {
+		return nil, err
+	}
+	return g.buildFileDescriptor(content)
+}
+
+// buildFileDescriptor builds the descriptor of the generated proto source
+func (g *Generator) buildFileDescriptor(content []byte) (*descriptorpb.FileDescriptorProto, error) {
+	b := &descriptorBuilder{
+		g:            g,
+		pkg:          g.getPackageName(),
+		enumNames:    make(map[string]bool),
+		messageNames: make(map[string]bool),
+		locator:      newSourceLocator(string(content)),
+	}
+	for _, s := range g.ctx.Structs {
+		for _, messageName := range g.resolveMessageNames(s) {
+			b.messageNames[messageName] = true
+		}
+	}
+	for _, e := range g.ctx.Enums {
+		if !g.isEnumIgnored(e) {
+			b.enumNames[g.getEnumName(e)] = true
+		}
+	}
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name: proto.String(g.getDescriptorFileName()),
+	}
+	if b.pkg != "" {
+		file.Package = proto.String(b.pkg)
+	}
+	if g.isEditions() {
+		edition, ok := descriptorEditions[g.getEdition()]
+		if !ok {
+			return nil, fmt.Errorf("unsupported edition %q", g.getEdition())
+		}
+		file.Syntax = proto.String(SyntaxEditions)
+		file.Edition = edition.Enum()
+	} else if g.formatGen.config.Syntax == "proto3" {
+		file.Syntax = proto.String("proto3")
+	}
+
+	// Dependencies
+	for i, imp := range g.collectImports() {
+		file.Dependency = append(file.Dependency, imp.Path)
+		if imp.Public {
+			file.PublicDependency = append(file.PublicDependency, int32(i))
+		} else if imp.Weak {
+			file.WeakDependency = append(file.WeakDependency, int32(i))
+		}
+	}
+
+	file.Options = b.buildFileOptions()
+
+	// Declarations are built in the same order they are written to the .proto file
+	for _, s := range g.ctx.Structs {
+		for _, messageName := range g.resolveMessageNames(s) {
+			path := []int32{fileMessageTypeTag, int32(len(file.MessageType))}
+			file.MessageType = append(file.MessageType, b.buildMessage(s, messageName, path))
+		}
+	}
+
+	for _, e := range g.ctx.Enums {
+		if g.isEnumIgnored(e) {
+			continue
+		}
+		path := []int32{fileEnumTypeTag, int32(len(file.EnumType))}
+		file.EnumType = append(file.EnumType, b.buildEnum(e, path))
+	}
+
+	for _, group := range groupExtensions(g.collectExtensions()) {
+		b.locator.findBlock("extend " + group[0].Extendee + " {")
+		for _, ext := range group {
+			field := &descriptorpb.FieldDescriptorProto{
+				Name:     proto.String(ext.Name),
+				Number:   proto.Int32(int32(ext.Number)),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				JsonName: proto.String(protoJSONName(ext.Name)),
+				Extendee: proto.String(b.qualify(ext.Extendee)),
+			}
+			if ext.Repeated {
+				field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+			}
+			b.setFieldType(field, ext.Type)
+			path := []int32{fileExtensionTag, int32(len(file.Extension))}
+			b.addLocation(path, b.locator.findField(ext.Name, ext.Number), ext.Comment)
+			file.Extension = append(file.Extension, field)
+		}
+		b.locator.endBlock()
+	}
+
+	if g.formatGen.config.GenerateService {
+		for _, service := range g.services {
+			path := []int32{fileServiceTag, int32(len(file.Service))}
+			file.Service = append(file.Service, b.buildService(service, path))
+		}
+	}
+
+	if len(b.locations) > 0 {
+		file.SourceCodeInfo = &descriptorpb.SourceCodeInfo{Location: b.locations}
+	}
+
+	return file, nil
+}
+
+// getDescriptorFileName returns the import path of the generated file (relative to the schema dir)
+func (g *Generator) getDescriptorFileName() string {
+	name := g.getCurrentFileName()
+	if name == "" {
+		name = g.resolveFileName("schema", "schema")
+	}
+	return strings.TrimPrefix(name, schemaImportDirPrefix)
+}
+
+func (b *descriptorBuilder) buildFileOptions() *descriptorpb.FileOptions {
+	config := b.g.formatGen.config
+	options := b.g.collectFileOptions()
+	features := b.g.collectFileFeatures()
+	if len(options) == 0 && len(features) == 0 && config.OptimizeFor == "" {
+		return nil
+	}
+
+	fileOptions := &descriptorpb.FileOptions{}
+	if len(features) > 0 {
+		fileOptions.Features = &descriptorpb.FeatureSet{}
+		for _, feature := range features {
+			applyFeature(fileOptions.Features, feature.Name, feature.Value)
+		}
+	}
+	if config.OptimizeFor != "" {
+		if mode, ok := descriptorpb.FileOptions_OptimizeMode_value[strings.ToUpper(config.OptimizeFor)]; ok {
+			fileOptions.OptimizeFor = descriptorpb.FileOptions_OptimizeMode(mode).Enum()
+		}
+	}
+
+	keys := make([]string, 0, len(options))
+	for k := range options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := options[k]
+		switch k {
+		case "go_package":
+			fileOptions.GoPackage = proto.String(v)
+		case "java_package":
+			fileOptions.JavaPackage = proto.String(v)
+		case "java_outer_classname":
+			fileOptions.JavaOuterClassname = proto.String(v)
+		case "java_multiple_files":
+			fileOptions.JavaMultipleFiles = proto.Bool(v == "true")
+		case "csharp_namespace":
+			fileOptions.CsharpNamespace = proto.String(v)
+		case "objc_class_prefix":
+			fileOptions.ObjcClassPrefix = proto.String(v)
+		case "php_namespace":
+			fileOptions.PhpNamespace = proto.String(v)
+		case "ruby_package":
+			fileOptions.RubyPackage = proto.String(v)
+		case "swift_prefix":
+			fileOptions.SwiftPrefix = proto.String(v)
+		default:
+			fileOptions.UninterpretedOption = append(fileOptions.UninterpretedOption, uninterpretedOption(k, strconv.Quote(v)))
+		}
+	}
+	return fileOptions
+}
+
+func (b *descriptorBuilder) buildMessage(s *parser.StructInfo, messageName string, path []int32) *descriptorpb.DescriptorProto {
+	g := b.g
+	localName := localMessageName(messageName)
+	msg := &descriptorpb.DescriptorProto{Name: proto.String(localName)}
+	parentPackage := g.goPackage
+	g.goPackage = s.PackagePath
+	defer func() { g.goPackage = parentPackage }()
+	openBlocks := b.locator.openBlocks()
+	b.addLocation(path, b.locator.findBlock("message "+localName+" {"), g.getMessageDescription(s, localName))
+
+	// Nested messages are written before the fields, map entries are added after them
+	for _, nested := range g.collectNestedMessages(s, messageName) {
+		nestedPath := appendPath(path, messageNestedTypeTag, int32(len(msg.NestedType)))
+		msg.NestedType = append(msg.NestedType, b.buildMessage(nested.Struct, nested.Name, nestedPath))
+	}
+
+	fields, reservedNumbers := g.resolveMessageFields(s, messageName)
+
+	// Wrappers of nested collections are written after the nested messages
+	wrappers := make(map[string]bool)
+	for _, wrapper := range g.collectionWrappers(fields) {
+		wrappers[wrapper.Name] = true
+		wrapperPath := appendPath(path, messageNestedTypeTag, int32(len(msg.NestedType)))
+		msg.NestedType = append(msg.NestedType, b.buildCollectionWrapper(wrapper, messageName, wrappers, wrapperPath))
+	}
+
+	oneofIndexes := make(map[string]int32)
+	var syntheticOneofs []*descriptorpb.FieldDescriptorProto
+	for _, mf := range fields {
+		if mf.Oneof != "" {
+			if _, exists := oneofIndexes[mf.Oneof]; !exists {
+				oneofPath := appendPath(path, messageOneofTag, int32(len(msg.OneofDecl)))
+				b.addLocation(oneofPath, b.locator.findBlock("oneof "+mf.Oneof+" {"), "")
+				oneofIndexes[mf.Oneof] = int32(len(msg.OneofDecl))
+				msg.OneofDecl = append(msg.OneofDecl, &descriptorpb.OneofDescriptorProto{Name: proto.String(mf.Oneof)})
+			}
+		}
+
+		fieldName := g.getFieldName(mf.Field)
+		field := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(fieldName),
+			Number:   proto.Int32(int32(mf.Number)),
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			JsonName: proto.String(protoJSONName(fieldName)),
+		}
+
+		if keyType, valueType, ok := g.resolveMapTypes(mf.Field); ok {
+			// Maps are repeated fields of a synthesized nested entry message
+			entry := b.buildMapEntry(fieldName, keyType, scopedWrapperType(messageName, wrappers, valueType))
+			msg.NestedType = append(msg.NestedType, entry)
+			field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+			field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+			field.TypeName = proto.String(b.qualify(messageName) + "." + entry.GetName())
+		} else {
+			b.setFieldType(field, scopedWrapperType(messageName, wrappers, g.getProtoType(mf.Field)))
+			if g.isRepeated(mf.Field) {
+				field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+			} else if g.isProto2() && mf.Oneof == "" && g.isRequired(mf.Field) {
+				field.Label = descriptorpb.FieldDescriptorProto_LABEL_REQUIRED.Enum()
+			} else if g.isProto3Optional(mf.Field, mf.Oneof != "") {
+				// proto3 optional fields live in a synthetic oneof
+				field.Proto3Optional = proto.Bool(true)
+				syntheticOneofs = append(syntheticOneofs, field)
+			}
+		}
+
+		if mf.Oneof != "" {
+			field.OneofIndex = proto.Int32(oneofIndexes[mf.Oneof])
+		}
+
+		b.applyFieldOptions(field, g.resolveFieldOptions(mf.Field, mf.Oneof != ""))
+
+		fieldPath := appendPath(path, messageFieldTag, int32(len(msg.Field)))
+		b.addLocation(fieldPath, b.locator.findField(fieldName, mf.Number), g.getFieldDescription(mf.Field))
+		msg.Field = append(msg.Field, field)
+	}
+
+	// Synthetic oneofs must come after all real oneofs
+	for _, field := range syntheticOneofs {
+		field.OneofIndex = proto.Int32(int32(len(msg.OneofDecl)))
+		msg.OneofDecl = append(msg.OneofDecl, &descriptorpb.OneofDescriptorProto{Name: proto.String("_" + field.GetName())})
+	}
+
+	// Reserved ranges are half-open in descriptors
+	sort.Ints(reservedNumbers)
+	for _, r := range compactNumberRanges(reservedNumbers) {
+		msg.ReservedRange = append(msg.ReservedRange, &descriptorpb.DescriptorProto_ReservedRange{
+			Start: proto.Int32(int32(r[0])),
+			End:   proto.Int32(int32(r[1]) + 1),
+		})
+	}
+	msg.ReservedName = append(msg.ReservedName, g.getReservedNames(s, messageName)...)
+
+	// Extension ranges are half-open too
+	if g.formatGen.config.Syntax != "proto3" {
+		for _, r := range g.getExtensionRanges(s) {
+			msg.ExtensionRange = append(msg.ExtensionRange, &descriptorpb.DescriptorProto_ExtensionRange{
+				Start: proto.Int32(int32(r[0])),
+				End:   proto.Int32(int32(r[1]) + 1),
+			})
+		}
+	}
+
+	b.locator.endBlocksFrom(openBlocks)
+	return msg
+}
+
+// buildCollectionWrapper builds the message wrapping a nested collection, see collectionWrappers.
+// Wrappers are declared in the message using them (scope).
+func (b *descriptorBuilder) buildCollectionWrapper(wrapper collectionWrapper, scope string, wrappers map[string]bool, path []int32) *descriptorpb.DescriptorProto {
+	msg := &descriptorpb.DescriptorProto{Name: proto.String(wrapper.Name)}
+	openBlocks := b.locator.openBlocks()
+	b.addLocation(path, b.locator.findBlock("message "+wrapper.Name+" {"), "")
+
+	field := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String("values"),
+		Number:   proto.Int32(1),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum(),
+		JsonName: proto.String("values"),
+	}
+	valueType := scopedWrapperType(scope, wrappers, wrapper.ValueType)
+	if wrapper.KeyType != "" {
+		entry := b.buildMapEntry("values", wrapper.KeyType, valueType)
+		msg.NestedType = append(msg.NestedType, entry)
+		field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+		field.TypeName = proto.String(b.qualify(scope+"."+wrapper.Name) + "." + entry.GetName())
+	} else {
+		b.setFieldType(field, valueType)
+	}
+	b.addLocation(appendPath(path, messageFieldTag, 0), b.locator.findField("values", 1), "")
+	msg.Field = append(msg.Field, field)
+
+	b.locator.endBlocksFrom(openBlocks)
+	return msg
+}
+
+// scopedWrapperType qualifies the wrapper messages of a message with its name, the scope they
+// are declared in
+func scopedWrapperType(scope string, wrappers map[string]bool, protoType string) string {
+	if wrappers[protoType] {
+		return scope + "." + protoType
+	}
+	return protoType
+}
+
+// buildMapEntry synthesizes the nested entry message protoc generates for map fields
+func (b *descriptorBuilder) buildMapEntry(fieldName, keyType, valueType string) *descriptorpb.DescriptorProto {
+	key := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String("key"),
+		Number:   proto.Int32(mapEntryKeyNumber),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		JsonName: proto.String("key"),
+	}
+	b.setFieldType(key, keyType)
+
+	value := &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String("value"),
+		Number:   proto.Int32(mapEntryValueNumber),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		JsonName: proto.String("value"),
+	}
+	b.setFieldType(value, valueType)
+
+	return &descriptorpb.DescriptorProto{
+		Name:    proto.String(mapEntryName(fieldName)),
+		Field:   []*descriptorpb.FieldDescriptorProto{key, value},
+		Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+	}
+}
+
+func (b *descriptorBuilder) buildEnum(e *parser.EnumInfo, path []int32) *descriptorpb.EnumDescriptorProto {
+	g := b.g
+	enumName := g.getEnumName(e)
+	enum := &descriptorpb.EnumDescriptorProto{Name: proto.String(enumName)}
+	b.addLocation(path, b.locator.findBlock("enum "+enumName+" {"), g.getEnumDescription(e))
+
+	b.applyEnumOptions(enum, g.getEnumOptions(e))
+	if features := g.getEnumFeatures(e); len(features) > 0 {
+		if enum.Options == nil {
+			enum.Options = &descriptorpb.EnumOptions{}
+		}
+		enum.Options.Features = &descriptorpb.FeatureSet{}
+		for _, feature := range features {
+			applyFeature(enum.Options.Features, feature.Name, feature.Value)
+		}
+	}
+
+	values, reservedNumbers, reservedNames := g.resolveEnumValues(e)
+	for _, ev := range values {
+		valuePath := appendPath(path, enumValueTag, int32(len(enum.Value)))
+		b.addLocation(valuePath, b.locator.findLine(ev.Name+" = "+strconv.Itoa(ev.Number)), g.getEnumValueDescription(ev.Value))
+		value := &descriptorpb.EnumValueDescriptorProto{
+			Name:   proto.String(ev.Name),
+			Number: proto.Int32(int32(ev.Number)),
+		}
+		b.applyEnumValueOptions(value, g.getEnumValueOptions(ev.Value))
+		enum.Value = append(enum.Value, value)
+	}
+
+	// Enum reserved ranges are inclusive, unlike message ones
+	for _, r := range compactNumberRanges(reservedNumbers) {
+		enum.ReservedRange = append(enum.ReservedRange, &descriptorpb.EnumDescriptorProto_EnumReservedRange{
+			Start: proto.Int32(int32(r[0])),
+			End:   proto.Int32(int32(r[1])),
+		})
+	}
+	enum.ReservedName = append(enum.ReservedName, reservedNames...)
+
+	b.locator.endBlock()
+	return enum
+}
+
+func (b *descriptorBuilder) buildService(service ProtoService, path []int32) *descriptorpb.ServiceDescriptorProto {
+	svc := &descriptorpb.ServiceDescriptorProto{Name: proto.String(service.Name)}
+	b.addLocation(path, b.locator.findBlock("service "+service.Name+" {"), service.Comment)
+	parentPackage := b.g.goPackage
+	b.g.goPackage = service.PackagePath
+	defer func() { b.g.goPackage = parentPackage }()
+
+	for _, method := range service.Methods {
+		m := &descriptorpb.MethodDescriptorProto{
+			Name:       proto.String(method.Name),
+			InputType:  proto.String(b.qualify(b.g.rpcTypeName(method.InputType))),
+			OutputType: proto.String(b.qualify(b.g.rpcTypeName(method.OutputType))),
+		}
+		if method.ClientStream {
+			m.ClientStreaming = proto.Bool(true)
+		}
+		if method.ServerStream {
+			m.ServerStreaming = proto.Bool(true)
+		}
+
+		methodPath := appendPath(path, serviceMethodTag, int32(len(svc.Method)))
+		b.addLocation(methodPath, b.locator.findLine("rpc "+method.Name+"("), method.Comment)
+		svc.Method = append(svc.Method, m)
+	}
+
+	b.locator.endBlock()
+	return svc
+}
+
+// setFieldType sets the scalar type, or the resolved message/enum type name
+func (b *descriptorBuilder) setFieldType(field *descriptorpb.FieldDescriptorProto, protoType string) {
+	if scalar, ok := scalarFieldTypes[protoType]; ok {
+		field.Type = scalar.Enum()
+		return
+	}
+
+	if b.enumNames[protoType] {
+		field.Type = descriptorpb.FieldDescriptorProto_TYPE_ENUM.Enum()
+	} else if fd, err := protoregistry.GlobalFiles.FindDescriptorByName(protoreflect.FullName(protoType)); err == nil {
+		if _, isEnum := fd.(protoreflect.EnumDescriptor); isEnum {
+			field.Type = descriptorpb.FieldDescriptorProto_TYPE_ENUM.Enum()
+		} else {
+			field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+		}
+	} else {
+		field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+	}
+	field.TypeName = proto.String(b.qualify(protoType))
+}
+
+// qualify returns the fully qualified name (leading dot) of a type referenced in the file.
+// Names containing a dot are assumed to be qualified already (e.g. google.protobuf.Timestamp),
+// unless they start with a message of the file (nested messages such as User.Address).
+func (b *descriptorBuilder) qualify(typeName string) string {
+	if strings.HasPrefix(typeName, ".") {
+		return typeName
+	}
+	if first, _, nested := strings.Cut(typeName, "."); nested {
+		// Nested messages are qualified with their top-level parent
+		if b.messageNames[first] && b.pkg != "" {
+			return "." + b.pkg + "." + typeName
+		}
+		return "." + typeName
+	}
+	if b.pkg == "" {
+		return "." + typeName
+	}
+	return "." + b.pkg + "." + typeName
+}
+
+// applyFieldOptions maps collected field options to FieldOptions
+func (b *descriptorBuilder) applyFieldOptions(field *descriptorpb.FieldDescriptorProto, options []FieldOption) {
+	for _, opt := range options {
+		switch opt.Name {
+		case "json_name":
+			if name, err := strconv.Unquote(opt.Value); err == nil {
+				field.JsonName = proto.String(name)
+			}
+		case "default":
+			// Descriptors hold the unquoted default text
+			if value, err := strconv.Unquote(opt.Value); err == nil {
+				field.DefaultValue = proto.String(value)
+			} else {
+				field.DefaultValue = proto.String(opt.Value)
+			}
+		case "packed":
+			if field.Options == nil {
+				field.Options = &descriptorpb.FieldOptions{}
+			}
+			field.Options.Packed = proto.Bool(opt.Value == "true")
+		case "deprecated":
+			if field.Options == nil {
+				field.Options = &descriptorpb.FieldOptions{}
+			}
+			field.Options.Deprecated = proto.Bool(opt.Value == "true")
+		case featureFieldPresence, featureRepeatedFieldEncoding:
+			if field.Options == nil {
+				field.Options = &descriptorpb.FieldOptions{}
+			}
+			if field.Options.Features == nil {
+				field.Options.Features = &descriptorpb.FeatureSet{}
+			}
+			applyFeature(field.Options.Features, opt.Name, opt.Value)
+		default:
+			if field.Options == nil {
+				field.Options = &descriptorpb.FieldOptions{}
+			}
+			field.Options.UninterpretedOption = append(field.Options.UninterpretedOption, uninterpretedOption(opt.Name, opt.Value))
+		}
+	}
+}
+
+// applyEnumOptions maps collected enum options to EnumOptions
+func (b *descriptorBuilder) applyEnumOptions(enum *descriptorpb.EnumDescriptorProto, options []FieldOption) {
+	for _, opt := range options {
+		if enum.Options == nil {
+			enum.Options = &descriptorpb.EnumOptions{}
+		}
+		switch opt.Name {
+		case "allow_alias":
+			enum.Options.AllowAlias = proto.Bool(opt.Value == "true")
+		case "deprecated":
+			enum.Options.Deprecated = proto.Bool(opt.Value == "true")
+		default:
+			enum.Options.UninterpretedOption = append(enum.Options.UninterpretedOption, uninterpretedOption(opt.Name, opt.Value))
+		}
+	}
+}
+
+// applyEnumValueOptions maps collected enum value options to EnumValueOptions
+func (b *descriptorBuilder) applyEnumValueOptions(value *descriptorpb.EnumValueDescriptorProto, options []FieldOption) {
+	for _, opt := range options {
+		if value.Options == nil {
+			value.Options = &descriptorpb.EnumValueOptions{}
+		}
+		switch opt.Name {
+		case "deprecated":
+			value.Options.Deprecated = proto.Bool(opt.Value == "true")
+		default:
+			value.Options.UninterpretedOption = append(value.Options.UninterpretedOption, uninterpretedOption(opt.Name, opt.Value))
+		}
+	}
+}
+
+// addLocation records a source location with its leading comment
+func (b *descriptorBuilder) addLocation(path []int32, span []int32, comment string) {
+	if span == nil {
+		return
+	}
+	loc := &descriptorpb.SourceCodeInfo_Location{
+		Path: append([]int32(nil), path...),
+		Span: span,
+	}
+	if comment != "" {
+		loc.LeadingComments = proto.String(" " + comment + "\n")
+	}
+	b.locations = append(b.locations, loc)
+}
+
+// uninterpretedOption represents a custom option the way protoc stores it before resolution
+func uninterpretedOption(name, value string) *descriptorpb.UninterpretedOption {
+	opt := &descriptorpb.UninterpretedOption{}
+
+	// Split "(my.ext).field" into the extension part "my.ext" and the plain part "field"
+	for rest := name; rest != ""; {
+		part, isExtension := rest, false
+		if strings.HasPrefix(rest, "(") {
+			if end := strings.Index(rest, ")"); end > 0 {
+				part, isExtension = rest[1:end], true
+				rest = strings.TrimPrefix(rest[end+1:], ".")
+			} else {
+				rest = ""
+			}
+		} else if dot := strings.Index(rest, "."); dot >= 0 {
+			part, rest = rest[:dot], rest[dot+1:]
+		} else {
+			rest = ""
+		}
+		opt.Name = append(opt.Name, &descriptorpb.UninterpretedOption_NamePart{
+			NamePart:    proto.String(part),
+			IsExtension: proto.Bool(isExtension),
+		})
+	}
+
+	if unquoted, err := strconv.Unquote(value); err == nil {
+		opt.StringValue = []byte(unquoted)
+	} else if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if n < 0 {
+			opt.NegativeIntValue = proto.Int64(n)
+		} else {
+			opt.PositiveIntValue = proto.Uint64(uint64(n))
+		}
+	} else if f, err := strconv.ParseFloat(value, 64); err == nil {
+		opt.DoubleValue = proto.Float64(f)
+	} else {
+		opt.IdentifierValue = proto.String(value)
+	}
+	return opt
+}
+
+// protoJSONName converts a field name to its JSON name the same way protoc does
+func protoJSONName(name string) string {
+	var out strings.Builder
+	upperNext := false
+	for _, r := range name {
+		if r == '_' {
+			upperNext = true
+			continue
+		}
+		if upperNext && r >= 'a' && r <= 'z' {
+			r -= 'a' - 'A'
+		}
+		upperNext = false
+		out.WriteRune(r)
+	}
+	return out.String()
+}
+
+// mapEntryName returns the name protoc gives to the entry message of a map field
+func mapEntryName(fieldName string) string {
+	var out strings.Builder
+	upperNext := true
+	for _, r := range fieldName {
+		if r == '_' {
+			upperNext = true
+			continue
+		}
+		if upperNext && r >= 'a' && r <= 'z' {
+			r -= 'a' - 'A'
+		}
+		upperNext = false
+		out.WriteRune(r)
+	}
+	return out.String() + "Entry"
+}
+
+// compactNumberRanges groups a sorted list of numbers into inclusive [start, end] ranges
+func compactNumberRanges(numbers []int) [][2]int {
+	var ranges [][2]int
+	for _, n := range numbers {
+		if len(ranges) > 0 && ranges[len(ranges)-1][1] >= n-1 {
+			if n > ranges[len(ranges)-1][1] {
+				ranges[len(ranges)-1][1] = n
+			}
+			continue
+		}
+		ranges = append(ranges, [2]int{n, n})
+	}
+	return ranges
+}
+
+func appendPath(path []int32, elems ...int32) []int32 {
+	result := make([]int32, 0, len(path)+len(elems))
+	result = append(result, path...)
+	return append(result, elems...)
+}
+
+// sourceLocator finds declaration spans in the generated .proto text.
+// Declarations are looked up in the order they are written, so a forward scan is enough.
+type sourceLocator struct {
+	lines  []string
+	next   int
+	blocks []int // start lines of the open blocks
+}
+
+func newSourceLocator(content string) *sourceLocator {
+	return &sourceLocator{lines: strings.Split(content, "\n")}
+}
+
+// findLine returns the span of the next line starting with prefix (ignoring indentation)
+func (l *sourceLocator) findLine(prefix string) []int32 {
+	for i := l.next; i < len(l.lines); i++ {
+		trimmed := strings.TrimLeft(l.lines[i], " ")
+		if strings.HasPrefix(trimmed, prefix) {
+			l.next = i + 1
+			col := len(l.lines[i]) - len(trimmed)
+			return []int32{int32(i), int32(col), int32(len(l.lines[i]))}
+		}
+	}
+	return nil
+}
+
+// findField returns the span of the next field declaration with the given name and number
+func (l *sourceLocator) findField(name string, number int) []int32 {
+	needle := fmt.Sprintf(" %s = %d", name, number)
+	for i := l.next; i < len(l.lines); i++ {
+		trimmed := strings.TrimLeft(l.lines[i], " ")
+		if strings.HasPrefix(trimmed, "//") {
+			continue
+		}
+		if strings.Contains(trimmed, needle+";") || strings.Contains(trimmed, needle+" [") {
+			l.next = i + 1
+			col := len(l.lines[i]) - len(trimmed)
+			return []int32{int32(i), int32(col), int32(len(l.lines[i]))}
+		}
+	}
+	return nil
+}
+
+// findBlock returns the span of the next block declaration, ending at its closing brace
+func (l *sourceLocator) findBlock(header string) []int32 {
+	span := l.findLine(header)
+	if span == nil {
+		return nil
+	}
+
+	// Find the matching closing brace at the same indentation
+	indent := strings.Repeat(" ", int(span[1]))
+	for i := int(span[0]) + 1; i < len(l.lines); i++ {
+		if l.lines[i] == indent+"}" {
+			l.blocks = append(l.blocks, i)
+			return []int32{span[0], span[1], int32(i), int32(len(l.lines[i]))}
+		}
+	}
+	return span
+}
+
+// endBlock moves the scan position past the last opened top-level block
+func (l *sourceLocator) endBlock() {
+	l.endBlocksFrom(0)
+}
+
+// openBlocks returns the number of open blocks, to be passed to endBlocksFrom
+func (l *sourceLocator) openBlocks() int {
+	return len(l.blocks)
+}
+
+// endBlocksFrom moves the scan position past the first block opened after the given count.
+// Blocks opened inside it (oneofs, nested messages) are closed with it.
+func (l *sourceLocator) endBlocksFrom(open int) {
+	if len(l.blocks) <= open {
+		return
+	}
+	end := l.blocks[open]
+	l.blocks = l.blocks[:open]
+	if end+1 > l.next {
+		l.next = end + 1
+	}
+}
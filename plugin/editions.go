@@ -0,0 +1,217 @@
+package plugin
+
+import (
+	"strings"
+
+	"github.com/pablor21/gonnotation/parser"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+const (
+	// SyntaxEditions selects Protobuf Editions output (edition = "...") instead of proto2/proto3
+	SyntaxEditions = "editions"
+	// DefaultEdition is the edition written when none is configured
+	DefaultEdition = "2023"
+)
+
+// Feature names as written in editions options
+const (
+	featureFieldPresence         = "features.field_presence"
+	featureEnumType              = "features.enum_type"
+	featureRepeatedFieldEncoding = "features.repeated_field_encoding"
+)
+
+// Feature values
+const (
+	presenceExplicit = "EXPLICIT"
+	presenceImplicit = "IMPLICIT"
+	presenceRequired = "LEGACY_REQUIRED"
+	enumTypeOpen     = "OPEN"
+	encodingPacked   = "PACKED"
+	encodingExpanded = "EXPANDED"
+)
+
+// EditionFeatures configures the file-level feature defaults written in editions mode
+type EditionFeatures struct {
+	FieldPresence         string `yaml:"field_presence"`          // EXPLICIT or IMPLICIT
+	EnumType              string `yaml:"enum_type"`               // OPEN or CLOSED
+	RepeatedFieldEncoding string `yaml:"repeated_field_encoding"` // PACKED or EXPANDED
+}
+
+// editionDefaults are the feature defaults of the supported editions (2023 and 2024 share them)
+var editionDefaults = EditionFeatures{
+	FieldPresence:         presenceExplicit,
+	EnumType:              enumTypeOpen,
+	RepeatedFieldEncoding: encodingPacked,
+}
+
+// descriptorEditions maps edition names to descriptor editions
+var descriptorEditions = map[string]descriptorpb.Edition{
+	"2023": descriptorpb.Edition_EDITION_2023,
+	"2024": descriptorpb.Edition_EDITION_2024,
+}
+
+// isEditions checks if the schema is written as a Protobuf Editions file
+func (g *Generator) isEditions() bool {
+	return strings.EqualFold(g.formatGen.config.Syntax, SyntaxEditions)
+}
+
+// getEdition returns the configured edition
+func (g *Generator) getEdition() string {
+	if edition := strings.TrimSpace(g.formatGen.config.Edition); edition != "" {
+		return edition
+	}
+	return DefaultEdition
+}
+
+// fileFeature returns the file-level value of a feature: the configured one, or the edition default
+func fileFeature(configured, editionDefault string) string {
+	if value := strings.ToUpper(strings.TrimSpace(configured)); value != "" {
+		return value
+	}
+	return editionDefault
+}
+
+func (g *Generator) fileFieldPresence() string {
+	return fileFeature(g.formatGen.config.Features.FieldPresence, editionDefaults.FieldPresence)
+}
+
+func (g *Generator) fileEnumType() string {
+	return fileFeature(g.formatGen.config.Features.EnumType, editionDefaults.EnumType)
+}
+
+func (g *Generator) fileRepeatedFieldEncoding() string {
+	return fileFeature(g.formatGen.config.Features.RepeatedFieldEncoding, editionDefaults.RepeatedFieldEncoding)
+}
+
+// collectFileFeatures returns the file-level features that differ from the edition defaults
+func (g *Generator) collectFileFeatures() []FieldOption {
+	if !g.isEditions() {
+		return nil
+	}
+
+	var features []FieldOption
+	if presence := g.fileFieldPresence(); presence != editionDefaults.FieldPresence {
+		features = append(features, FieldOption{Name: featureFieldPresence, Value: presence})
+	}
+	if enumType := g.fileEnumType(); enumType != editionDefaults.EnumType {
+		features = append(features, FieldOption{Name: featureEnumType, Value: enumType})
+	}
+	if encoding := g.fileRepeatedFieldEncoding(); encoding != editionDefaults.RepeatedFieldEncoding {
+		features = append(features, FieldOption{Name: featureRepeatedFieldEncoding, Value: encoding})
+	}
+	return features
+}
+
+// editionFieldOptions rewrites field options for editions: "packed" becomes the
+// repeated_field_encoding feature, legacy_required fields get legacy required presence and
+// pointer/optional fields get explicit presence when the file default is implicit.
+// Oneof and repeated fields can't set presence, message fields are always explicit.
+func (g *Generator) editionFieldOptions(f *parser.FieldInfo, options []FieldOption, inOneof bool) []FieldOption {
+	var result []FieldOption
+	for _, opt := range options {
+		if opt.Name != "packed" {
+			result = append(result, opt)
+			continue
+		}
+		encoding := encodingExpanded
+		if opt.Value == "true" {
+			encoding = encodingPacked
+		}
+		if encoding != g.fileRepeatedFieldEncoding() {
+			result = append(result, FieldOption{Name: featureRepeatedFieldEncoding, Value: encoding})
+		}
+	}
+
+	if inOneof || g.isRepeated(f) || g.isMapField(f) {
+		return result
+	}
+	if g.isLegacyRequired(f) {
+		return append(result, FieldOption{Name: featureFieldPresence, Value: presenceRequired})
+	}
+	if !g.isScalarOrEnumType(g.getProtoType(f)) {
+		return result
+	}
+	if g.isOptional(f) && g.fileFieldPresence() == presenceImplicit {
+		result = append(result, FieldOption{Name: featureFieldPresence, Value: presenceExplicit})
+	}
+	return result
+}
+
+// isMapField checks if a field is written as a map
+func (g *Generator) isMapField(f *parser.FieldInfo) bool {
+	_, _, ok := g.resolveMapTypes(f)
+	return ok
+}
+
+// isScalarOrEnumType checks if a proto type is a scalar or an enum of the schema
+func (g *Generator) isScalarOrEnumType(protoType string) bool {
+	if _, ok := scalarFieldTypes[protoType]; ok {
+		return true
+	}
+	for _, e := range g.ctx.Enums {
+		if !g.isEnumIgnored(e) && g.getEnumName(e) == protoType {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPointerPresence checks if protoc-gen-go declares the Go field of a singular field as a
+// pointer to track its presence: scalars and enums outside of oneofs with the proto3 optional
+// label, every proto2 field (optional or required), or with explicit or legacy required presence
+// in editions. Bytes and messages don't need a pointer.
+func (g *Generator) hasPointerPresence(f *parser.FieldInfo) bool {
+	if g.isRepeated(f) || g.isMapField(f) {
+		return false
+	}
+	if protoType := g.getProtoType(f); protoType == "bytes" || !g.isScalarOrEnumType(protoType) {
+		return false
+	}
+	if g.isProto2() {
+		return true
+	}
+	if g.isEditions() {
+		return g.isLegacyRequired(f) || g.isOptional(f) || g.fileFieldPresence() != presenceImplicit
+	}
+	return g.isOptional(f)
+}
+
+// getEnumFeatures returns the enum features that differ from the file defaults,
+// taken from @enum(enum_type="open"|"closed")
+func (g *Generator) getEnumFeatures(e *parser.EnumInfo) []FieldOption {
+	if !g.isEditions() {
+		return nil
+	}
+
+	for _, ann := range e.Annotations {
+		name := strings.ToLower(ann.Name)
+		if name == "enum" || strings.HasSuffix(name, ".enum") {
+			if enumType, ok := ann.GetParamValue("enum_type"); ok && enumType != "" {
+				enumType = strings.ToUpper(enumType)
+				if enumType != g.fileEnumType() {
+					return []FieldOption{{Name: featureEnumType, Value: enumType}}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// applyFeature sets a feature on a descriptor FeatureSet, unknown values are ignored
+func applyFeature(features *descriptorpb.FeatureSet, name, value string) {
+	switch name {
+	case featureFieldPresence:
+		if v, ok := descriptorpb.FeatureSet_FieldPresence_value[value]; ok {
+			features.FieldPresence = descriptorpb.FeatureSet_FieldPresence(v).Enum()
+		}
+	case featureEnumType:
+		if v, ok := descriptorpb.FeatureSet_EnumType_value[value]; ok {
+			features.EnumType = descriptorpb.FeatureSet_EnumType(v).Enum()
+		}
+	case featureRepeatedFieldEncoding:
+		if v, ok := descriptorpb.FeatureSet_RepeatedFieldEncoding_value[value]; ok {
+			features.RepeatedFieldEncoding = descriptorpb.FeatureSet_RepeatedFieldEncoding(v).Enum()
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package plugin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pablor21/gonnotation/annotations"
+	"github.com/pablor21/gonnotation/parser"
+)
+
+// isEnumAliasAllowed checks if an enum allows values sharing a number, from @enum(allow_alias=true)
+func (g *Generator) isEnumAliasAllowed(e *parser.EnumInfo) bool {
+	for _, ann := range e.Annotations {
+		name := strings.ToLower(ann.Name)
+		if name == "enum" || strings.HasSuffix(name, ".enum") {
+			if allowAlias, ok := ann.GetParamBool("allow_alias"); ok {
+				return allowAlias
+			}
+		}
+	}
+	return false
+}
+
+// hasEnumAliases checks if values of an enum share a number
+func (g *Generator) hasEnumAliases(e *parser.EnumInfo) bool {
+	enumName := g.getEnumName(e)
+	numbers := make(map[int]bool)
+	for i := range e.Values {
+		number := g.getEnumValueNumber(e, i, enumName)
+		if numbers[number] {
+			return true
+		}
+		numbers[number] = true
+	}
+	return false
+}
+
+// getEnumOptions returns the options written inside an enum: allow_alias, only when values
+// share a number since protoc rejects it otherwise, deprecated and the custom options set
+// with @option
+func (g *Generator) getEnumOptions(e *parser.EnumInfo) []FieldOption {
+	var options []FieldOption
+	if g.isEnumAliasAllowed(e) && g.hasEnumAliases(e) {
+		options = append(options, FieldOption{Name: "allow_alias", Value: "true"})
+	}
+	return append(options, collectAnnotationOptions(e.Annotations, "enum")...)
+}
+
+// getEnumValueOptions returns the options written after an enum value: deprecated and
+// the custom options set with @option
+func (g *Generator) getEnumValueOptions(v *parser.EnumValue) []FieldOption {
+	return collectAnnotationOptions(v.Annotations, "enumvalue")
+}
+
+// collectAnnotationOptions collects the deprecated option, from @deprecated or the deprecated
+// param of the element annotation (@enum(deprecated=true)), and the custom @option options
+func collectAnnotationOptions(anns []annotations.Annotation, elementAnnotation string) []FieldOption {
+	var options []FieldOption
+	deprecated := false
+	for _, ann := range anns {
+		name := strings.ToLower(ann.Name)
+		switch {
+		case name == "deprecated" || strings.HasSuffix(name, ".deprecated"):
+			deprecated = true
+		case name == elementAnnotation || strings.HasSuffix(name, "."+elementAnnotation):
+			if value, ok := ann.GetParamBool("deprecated"); ok && value {
+				deprecated = true
+			}
+		case name == "option" || strings.HasSuffix(name, ".option"):
+			if optName, ok := ann.GetParamValue("name"); ok {
+				if optValue, ok := ann.GetParamValue("value"); ok {
+					options = append(options, FieldOption{Name: optName, Value: optionLiteral(optValue)})
+				}
+			}
+		}
+	}
+	if deprecated {
+		options = append([]FieldOption{{Name: "deprecated", Value: "true"}}, options...)
+	}
+	return options
+}
+
+// optionLiteral writes an option value as a proto literal: bools and numbers as they are,
+// anything else quoted
+func optionLiteral(value string) string {
+	if value == "true" || value == "false" {
+		return value
+	}
+	if _, err := strconv.Atoi(value); err == nil {
+		return value
+	}
+	return fmt.Sprintf("\"%s\"", value)
+}
+
+// getEnumReserved returns the numbers and value names reserved with @reserved on an enum,
+// e.g. @reserved(numbers="2, 15, 9 to 11", names="OLD_VALUE")
+func (g *Generator) getEnumReserved(e *parser.EnumInfo) ([]int, []string) {
+	var numbers []int
+	var names []string
+	for _, ann := range e.Annotations {
+		name := strings.ToLower(ann.Name)
+		if name != "reserved" && !strings.HasSuffix(name, ".reserved") {
+			continue
+		}
+
+		if numbersList, ok := ann.GetParamValue("numbers"); ok && numbersList != "" {
+			for _, part := range strings.Split(strings.Trim(numbersList, "[]"), ",") {
+				numbers = append(numbers, parseEnumNumberRange(part)...)
+			}
+		}
+
+		if namesParam, ok := ann.GetParamValue("names"); ok && namesParam != "" {
+			for _, reserved := range strings.Split(strings.Trim(namesParam, "[]"), ",") {
+				if reserved = strings.Trim(strings.TrimSpace(reserved), "\""); reserved != "" {
+					names = append(names, reserved)
+				}
+			}
+		} else if namesList, ok := ann.GetParamStringList("names"); ok {
+			names = append(names, namesList...)
+		}
+	}
+	return uniqueSortedInts(numbers), names
+}
+
+// parseEnumNumberRange expands "N" or "N to M" into the numbers it covers. Unlike field
+// numbers, enum numbers may be zero or negative; open ranges ("N to max") aren't supported.
+func parseEnumNumberRange(spec string) []int {
+	start, end, isRange := strings.Cut(strings.TrimSpace(spec), " to ")
+	from, err := strconv.Atoi(strings.TrimSpace(start))
+	if err != nil {
+		return nil
+	}
+	to := from
+	if isRange {
+		if to, err = strconv.Atoi(strings.TrimSpace(end)); err != nil || to < from {
+			return nil
+		}
+	}
+
+	numbers := make([]int, 0, to-from+1)
+	for n := from; n <= to; n++ {
+		numbers = append(numbers, n)
+	}
+	return numbers
+}
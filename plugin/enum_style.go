@@ -0,0 +1,74 @@
+package plugin
+
+import (
+	"strings"
+
+	"github.com/pablor21/gonnotation/parser"
+	"github.com/pablor21/goschemagen"
+)
+
+// EnumStyleConfig configures how enum values are named and numbered
+type EnumStyleConfig struct {
+	PrefixValues         bool `yaml:"prefix_values"`          // Prefix value names with the enum name (ACTIVE -> STATUS_ACTIVE)
+	UnspecifiedZeroValue bool `yaml:"unspecified_zero_value"` // Add a <ENUM>_UNSPECIFIED = 0 value when no value is numbered 0
+	Offset               int  `yaml:"offset"`                 // Added to the index of auto-numbered values
+}
+
+// enumValuePrefix returns the prefix of the value names of an enum (Status -> STATUS_)
+func enumValuePrefix(enumName string) string {
+	return goschemagen.TransformFieldName(enumName, goschemagen.FieldCaseScreamingSnake) + "_"
+}
+
+// prefixEnumValueName prefixes a value name with the enum name when prefix_values is set,
+// unless the Go name already carries it (StatusActive -> STATUS_ACTIVE)
+func (g *Generator) prefixEnumValueName(valueName, enumName string) string {
+	if !g.formatGen.config.EnumStyle.PrefixValues || enumName == "" {
+		return valueName
+	}
+	prefix := enumValuePrefix(enumName)
+	if strings.HasPrefix(valueName, prefix) {
+		return valueName
+	}
+	return prefix + valueName
+}
+
+// unspecifiedEnumValueName returns the name of the zero value added to an enum
+func unspecifiedEnumValueName(enumName string) string {
+	return enumValuePrefix(enumName) + "UNSPECIFIED"
+}
+
+// enumValueOffset returns the number added to the index of auto-numbered values.
+// Zero is left to the UNSPECIFIED value when unspecified_zero_value is set.
+func (g *Generator) enumValueOffset() int {
+	offset := g.formatGen.config.EnumStyle.Offset
+	if g.formatGen.config.EnumStyle.UnspecifiedZeroValue && offset < 1 {
+		offset = 1
+	}
+	return offset
+}
+
+// addUnspecifiedEnumValue prepends the <ENUM>_UNSPECIFIED = 0 value when unspecified_zero_value
+// is set and no value of the enum is numbered 0
+func (g *Generator) addUnspecifiedEnumValue(values []enumValue, enumName string) []enumValue {
+	if !g.formatGen.config.EnumStyle.UnspecifiedZeroValue {
+		return values
+	}
+	for _, ev := range values {
+		if ev.Number == 0 {
+			return values
+		}
+	}
+
+	name := unspecifiedEnumValueName(enumName)
+	unspecified := enumValue{Value: &parser.EnumValue{Name: name}, Name: name, Number: 0}
+	return append([]enumValue{unspecified}, values...)
+}
+
+// enumsStartAtZero checks if the first value of an enum must be numbered 0,
+// which is the case of open enums (proto3, editions by default)
+func (g *Generator) enumsStartAtZero() bool {
+	if g.isProto2() {
+		return false
+	}
+	return !g.isEditions() || g.fileEnumType() == enumTypeOpen
+}
@@ -0,0 +1,282 @@
+package plugin
+
+import (
+	"fmt"
+	"go/ast"
+	"sort"
+	"strings"
+)
+
+// protoFileGraph records the proto file declaring each message and enum, and the imports
+// between files, for the strategies that split the schema into several files
+type protoFileGraph struct {
+	typeFiles map[string]string   // Go type key (package path and name) -> proto file declaring it
+	typeNames map[string]string   // Go type key -> proto name (qualified for nested_in messages)
+	packages  map[string]string   // Proto file -> proto package
+	imports   map[string][]string // Proto file -> proto files it imports, sorted
+	groups    []*fileGroup        // Groups of types of the files, in generation order
+}
+
+// buildFileGraph indexes the types declared by each group and the files each group depends on.
+// Import cycles can't be compiled by protoc and are reported as errors.
+func (g *Generator) buildFileGraph(groups []*fileGroup) (*protoFileGraph, error) {
+	graph := &protoFileGraph{
+		typeFiles: make(map[string]string),
+		typeNames: make(map[string]string),
+		packages:  make(map[string]string),
+		imports:   make(map[string][]string),
+		groups:    groups,
+	}
+
+	for _, group := range groups {
+		graph.packages[group.fileName] = g.groupGenerator(group).getPackageName()
+		for _, s := range group.structs {
+			key := goTypeKey(s.PackagePath, s.Name)
+			if g.getNestedIn(s) != "" {
+				graph.typeFiles[key] = group.fileName
+				graph.typeNames[key] = g.qualifiedNestedName(s)
+			} else if len(g.resolveMessageNames(s)) > 0 {
+				graph.typeFiles[key] = group.fileName
+				graph.typeNames[key] = s.Name
+			}
+		}
+		for _, e := range group.enums {
+			key := goTypeKey(e.PackagePath, e.Name)
+			graph.typeFiles[key] = group.fileName
+			graph.typeNames[key] = e.Name
+		}
+	}
+
+	for _, group := range groups {
+		deps := make(map[string]bool)
+		for _, key := range g.groupReferences(group) {
+			if file, ok := graph.typeFiles[key]; ok && file != group.fileName {
+				deps[file] = true
+			}
+		}
+		graph.imports[group.fileName] = sortedKeys(deps)
+	}
+
+	if cycle := graph.findCycle(); cycle != nil {
+		return nil, fmt.Errorf("import cycle between proto files: %s", strings.Join(cycle, " -> "))
+	}
+	return graph, nil
+}
+
+// findCycle returns the files of an import cycle, the first one repeated at the end, or nil
+func (graph *protoFileGraph) findCycle() []string {
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int)
+	var path []string
+
+	var visit func(file string) []string
+	visit = func(file string) []string {
+		state[file] = visiting
+		path = append(path, file)
+		for _, dep := range graph.imports[file] {
+			switch state[dep] {
+			case visiting:
+				for i, f := range path {
+					if f == dep {
+						return append(append([]string{}, path[i:]...), dep)
+					}
+				}
+			case 0:
+				if cycle := visit(dep); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		state[file] = done
+		return nil
+	}
+
+	for _, file := range sortedKeys(graph.imports) {
+		if state[file] == 0 {
+			if cycle := visit(file); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// groupReferences returns the keys of the Go types referenced by the messages and services of a
+// group, see goTypeKey
+func (g *Generator) groupReferences(group *fileGroup) []string {
+	var refs []string
+	addRefs := func(pkgPath string, t ast.Expr) {
+		for _, ref := range typeReferences(t) {
+			refs = append(refs, g.typeKey(pkgPath, ref))
+		}
+	}
+
+	for _, s := range group.structs {
+		if g.hasServiceAnnotationForStruct(s) {
+			for _, fn := range g.ctx.Functions {
+				if fn.Receiver == nil || fn.Receiver.TypeName != s.Name || !g.hasRPCAnnotation(fn) {
+					continue
+				}
+				for _, p := range fn.Params {
+					addRefs(s.PackagePath, p.Type)
+				}
+				for _, r := range fn.Results {
+					addRefs(s.PackagePath, r.Type)
+				}
+			}
+			continue
+		}
+		for _, f := range s.Fields {
+			if iface := g.findUnion(f.Type); iface != nil {
+				for _, variant := range g.getUnionVariants(iface) {
+					refs = append(refs, goTypeKey(variant.PackagePath, variant.Name))
+				}
+				continue
+			}
+			addRefs(s.PackagePath, f.Type)
+		}
+	}
+
+	for _, iface := range group.interfaces {
+		if !g.hasServiceAnnotation(iface) {
+			continue
+		}
+		for _, m := range iface.Methods {
+			for _, p := range m.Params {
+				addRefs(iface.PackagePath, p.Type)
+			}
+			for _, r := range m.Results {
+				addRefs(iface.PackagePath, r.Type)
+			}
+		}
+	}
+
+	sort.Strings(refs)
+	return refs
+}
+
+// goTypeKey identifies a Go type by the import path of its package and its name, types of
+// different packages can share a name
+func goTypeKey(pkgPath, name string) string {
+	return pkgPath + "." + name
+}
+
+// typeKey returns the key of a type name as written in a package: Name for the types of the
+// package itself, pkg.Name for the types of the package it imports with that name
+func (g *Generator) typeKey(pkgPath, typeName string) string {
+	if pkgName, name, qualified := strings.Cut(typeName, "."); qualified {
+		if path := g.resolvePackagePath(pkgName, name); path != "" {
+			return goTypeKey(path, name)
+		}
+		return typeName
+	}
+	return goTypeKey(pkgPath, typeName)
+}
+
+// typeReferences returns the names of the types a type expression refers to, behind pointers,
+// slices, maps, streams and the fields of anonymous structs. Qualified types keep their
+// package (pkg.Name).
+func typeReferences(t ast.Expr) []string {
+	switch v := t.(type) {
+	case *ast.Ident:
+		return []string{v.Name}
+	case *ast.SelectorExpr:
+		if pkg, ok := v.X.(*ast.Ident); ok {
+			return []string{pkg.Name + "." + v.Sel.Name}
+		}
+		return []string{v.Sel.Name}
+	case *ast.StarExpr:
+		return typeReferences(v.X)
+	case *ast.ArrayType:
+		return typeReferences(v.Elt)
+	case *ast.MapType:
+		return append(typeReferences(v.Key), typeReferences(v.Value)...)
+	case *ast.ChanType:
+		return typeReferences(v.Value)
+	case *ast.IndexExpr:
+		// Streams of messages: iter.Seq[T]
+		return typeReferences(v.Index)
+	case *ast.FuncType:
+		// Streams of messages: func(T) error
+		var refs []string
+		if v.Params != nil {
+			for _, param := range v.Params.List {
+				refs = append(refs, typeReferences(param.Type)...)
+			}
+		}
+		return refs
+	case *ast.StructType:
+		var refs []string
+		if v.Fields != nil {
+			for _, field := range v.Fields.List {
+				refs = append(refs, typeReferences(field.Type)...)
+			}
+		}
+		return refs
+	}
+	return nil
+}
+
+// crossFileTypeName returns the name a message or enum declared in another proto file is
+// referenced by: qualified with its proto package when it differs from the current file's.
+// Unqualified Go names are types of the package of the message or service being generated.
+func (g *Generator) crossFileTypeName(goType string) (string, bool) {
+	if g.files == nil || g.currentFile == "" {
+		return "", false
+	}
+	key := g.typeKey(g.goPackage, goType)
+	file, ok := g.files.typeFiles[key]
+	if !ok || file == g.currentFile {
+		return "", false
+	}
+
+	name := g.files.typeNames[key]
+	if pkg := g.files.packages[file]; pkg != "" && pkg != g.files.packages[g.currentFile] {
+		return pkg + "." + name, true
+	}
+	return name, true
+}
+
+// sameFileTypeName returns the name of a message or enum of another Go package written to the
+// same file as the current type, e.g. billing.Invoice -> Invoice in the single strategy
+func (g *Generator) sameFileTypeName(goType string) (string, bool) {
+	pkgName, name, qualified := strings.Cut(goType, ".")
+	if !qualified || g.ctx == nil {
+		return "", false
+	}
+	for _, s := range g.ctx.Structs {
+		if s.Name == name && s.Package == pkgName {
+			return g.getMessageName(s, ""), true
+		}
+	}
+	for _, e := range g.ctx.Enums {
+		if e.Name == name && e.Package == pkgName {
+			return g.getEnumName(e), true
+		}
+	}
+	return "", false
+}
+
+// rpcTypeName returns the request or response type of an RPC as written in the service
+func (g *Generator) rpcTypeName(typeName string) string {
+	if strings.HasPrefix(typeName, "google.protobuf.") {
+		return typeName
+	}
+	if name, ok := g.crossFileTypeName(typeName); ok {
+		return name
+	}
+	if name, ok := g.sameFileTypeName(typeName); ok {
+		return name
+	}
+	return typeName
+}
+
+// protoImportPath converts the path of a generated file into its import path. The "schema/"
+// prefix is removed since protoc is run with "-I schema".
+func protoImportPath(file string) string {
+	return strings.TrimPrefix(file, "schema/")
+}
@@ -3,6 +3,7 @@ package plugin
 import (
 	"fmt"
 	"go/ast"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -19,21 +20,28 @@ type ProtoRPCMethod struct {
 	OutputType         string // Protobuf type (e.g., "User")
 	OriginalInputType  string // Original Go type (e.g., "int64")
 	OriginalOutputType string // Original Go type (e.g., "*User")
+	InputPackage       string // Import path of the package declaring the original input type
+	OutputPackage      string // Import path of the package declaring the original output type
 	Comment            string
 	ClientStream       bool        // Client streaming
 	ServerStream       bool        // Server streaming
 	IsStreaming        bool        // Any streaming (client or server)
+	InputStream        *StreamForm // Go form of the streamed requests, nil when not streamed
+	OutputStream       *StreamForm // Go form of the streamed responses, nil when not streamed
 	HasContext         bool        // Whether original method has context.Context parameter
 	Original           interface{} // Can be *parser.MethodInfo or *parser.FunctionInfo
 }
 
 // ProtoService represents a parsed service
 type ProtoService struct {
-	Name     string
-	Comment  string
-	Methods  []ProtoRPCMethod
-	IsStruct bool
-	Original interface{} // *parser.InterfaceInfo or *parser.StructInfo
+	Name        string
+	Comment     string
+	Methods     []ProtoRPCMethod
+	IsStruct    bool
+	GoName      string      // Name of the Go interface or struct
+	PackagePath string      // Import path of the package declaring the Go interface or struct
+	Namespace   string      // Namespace of the Go interface or struct
+	Original    interface{} // *parser.InterfaceInfo or *parser.StructInfo
 }
 
 // ProtoMessage represents a parsed message
@@ -60,6 +68,26 @@ type Generator struct {
 	fieldNumbers  map[string]int // Track field numbers per message
 	currentNumber int            // Current field number counter
 	currentFile   string         // Current file being generated (for imports)
+	namespace     string         // Namespace of the file being generated (namespace strategy)
+
+	// Field number lockfile (nil when lock_file is not configured)
+	lock *LockFile
+
+	// go/types information of the parsed packages (nil when it can't be loaded)
+	types *typeResolver
+
+	// Files of the multi-file strategies and their imports (nil for a single file)
+	files *protoFileGraph
+
+	// Import path of the Go package of the message or service being generated, the package of
+	// the unqualified type names of its fields and methods
+	goPackage string
+
+	// Every parsed type, for the lookups of the generators restricted to a file (nil otherwise)
+	all *goschemagen.GenerationContext
+
+	// Types written to the file being generated, to import the files declaring them
+	usedTypes map[string]bool
 
 	// Parsed structured data - computed once, used everywhere
 	services []ProtoService
@@ -68,24 +96,6 @@ type Generator struct {
 	// fieldProcessor *goschemagen.FieldProcessor
 }
 
-// getMergedKnownTypes merges core known types with format-specific known types
-// Format-specific types override core types with the same schema name
-func (g *Generator) getMergedKnownTypes() map[string]goschemagen.KnownTypeMapping {
-	merged := make(map[string]goschemagen.KnownTypeMapping)
-
-	// Start with core known types
-	if g.formatGen.config.KnownTypes != nil {
-		for name, mapping := range g.formatGen.config.KnownTypes {
-			merged[name] = mapping
-		}
-	}
-
-	// Protobuf doesn't have its own KnownTypes config yet
-	// Just return core known types for now
-
-	return merged
-}
-
 // parseAllData parses all services and messages once
 func (g *Generator) parseAllData() error {
 	if g.parsed {
@@ -96,15 +106,18 @@ func (g *Generator) parseAllData() error {
 	for _, iface := range g.ctx.Interfaces {
 		if g.hasServiceAnnotation(iface) {
 			service := ProtoService{
-				Name:     g.getServiceName(iface),
-				Comment:  g.getInterfaceDescription(iface),
-				IsStruct: false,
-				Original: iface,
+				Name:        g.getServiceName(iface),
+				Comment:     g.getInterfaceDescription(iface),
+				IsStruct:    false,
+				GoName:      iface.Name,
+				PackagePath: iface.PackagePath,
+				Namespace:   iface.Namespace,
+				Original:    iface,
 			}
 
 			// Parse all methods
 			for _, method := range iface.Methods {
-				rpcMethod := g.parseRPCMethod(method)
+				rpcMethod := g.parseRPCMethod(method, iface.Package, iface.PackagePath)
 				service.Methods = append(service.Methods, rpcMethod)
 			}
 
@@ -116,16 +129,19 @@ func (g *Generator) parseAllData() error {
 	for _, structInfo := range g.ctx.Structs {
 		if g.hasServiceAnnotationForStruct(structInfo) {
 			service := ProtoService{
-				Name:     g.getServiceNameFromStruct(structInfo),
-				Comment:  g.getStructDescription(structInfo),
-				IsStruct: true,
-				Original: structInfo,
+				Name:        g.getServiceNameFromStruct(structInfo),
+				Comment:     g.getStructDescription(structInfo),
+				IsStruct:    true,
+				GoName:      structInfo.Name,
+				PackagePath: structInfo.PackagePath,
+				Namespace:   structInfo.Namespace,
+				Original:    structInfo,
 			}
 
 			// Parse methods from functions with this struct as receiver
 			for _, fn := range g.ctx.Functions {
 				if fn.Receiver != nil && fn.Receiver.TypeName == structInfo.Name && g.hasRPCAnnotation(fn) {
-					rpcMethod := g.parseRPCMethodFromFunction(fn)
+					rpcMethod := g.parseRPCMethodFromFunction(fn, structInfo.Package, structInfo.PackagePath)
 					service.Methods = append(service.Methods, rpcMethod)
 				}
 			}
@@ -136,25 +152,28 @@ func (g *Generator) parseAllData() error {
 
 	// Parse messages from structs
 	for _, structInfo := range g.ctx.Structs {
-		if g.shouldIncludeStruct(structInfo) {
+		if !g.shouldIncludeStruct(structInfo) {
+			continue
+		}
+		g.goPackage = structInfo.PackagePath
+		for _, messageName := range g.resolveMessageNames(structInfo) {
 			message := ProtoMessage{
-				Name:     structInfo.Name,
-				Comment:  g.getStructDescription(structInfo),
+				Name:     messageName,
+				Comment:  g.getMessageDescription(structInfo, messageName),
 				Original: structInfo,
 			}
 
-			// Parse fields
-			fieldNum := g.formatGen.config.StartFieldNumber
-			for _, field := range structInfo.Fields {
+			// Parse fields with the same numbers used in the generated file
+			fields, _ := g.resolveMessageFields(structInfo, messageName)
+			for _, mf := range fields {
 				protoField := ProtoField{
-					Name:     g.getFieldName(field),
-					Type:     g.mapGoTypeToProto(field.Type),
-					Number:   fieldNum,
-					Comment:  g.getFieldDescription(field),
-					Original: field,
+					Name:     g.getFieldName(mf.Field),
+					Type:     g.mapGoTypeToProto(mf.Field.Type),
+					Number:   mf.Number,
+					Comment:  g.getFieldDescription(mf.Field),
+					Original: mf.Field,
 				}
 				message.Fields = append(message.Fields, protoField)
-				fieldNum++
 			}
 
 			g.messages = append(g.messages, message)
@@ -191,69 +210,122 @@ func (g *Generator) Generate() ([]byte, error) {
 		}
 	}
 
-	// Write syntax declaration
-	out.WriteString(fmt.Sprintf("syntax = \"%s\";\n\n", g.formatGen.config.Syntax))
-
-	// Write package declaration
-	if pkg := g.getPackageName(); pkg != "" {
-		out.WriteString(fmt.Sprintf("package %s;\n\n", pkg))
-	}
-
-	// Write options
-	if err := g.writeOptions(&out); err != nil {
-		return nil, err
-	}
-
-	// Write imports
-	if err := g.writeImports(&out); err != nil {
-		return nil, err
-	}
+	// Generate the declarations first: the imports depend on the types they use
+	var body strings.Builder
+	g.usedTypes = make(map[string]bool)
 
 	// Generate messages from structs
-	if err := g.generateMessages(&out); err != nil {
+	if err := g.generateMessages(&body); err != nil {
 		return nil, err
 	}
 
 	// Generate enums
-	if err := g.generateEnums(&out); err != nil {
+	if err := g.generateEnums(&body); err != nil {
 		return nil, err
 	}
 
 	// Generate extensions
-	if err := g.generateExtensions(&out); err != nil {
+	if err := g.generateExtensions(&body); err != nil {
 		return nil, err
 	}
 
 	// Generate services (if enabled)
 	if g.formatGen.config.GenerateService {
-		if err := g.generateServices(&out); err != nil {
+		if err := g.generateServices(&body); err != nil {
 			return nil, err
 		}
 	}
 
+	// Write syntax (or edition) declaration
+	if g.isEditions() {
+		out.WriteString(fmt.Sprintf("edition = \"%s\";\n\n", g.getEdition()))
+	} else {
+		out.WriteString(fmt.Sprintf("syntax = \"%s\";\n\n", g.formatGen.config.Syntax))
+	}
+
+	// Write package declaration
+	if pkg := g.getPackageName(); pkg != "" {
+		out.WriteString(fmt.Sprintf("package %s;\n\n", pkg))
+	}
+
+	// Write options
+	if err := g.writeOptions(&out); err != nil {
+		return nil, err
+	}
+
+	// Write imports
+	if err := g.writeImports(&out); err != nil {
+		return nil, err
+	}
+
+	// Write the declarations
+	out.WriteString(body.String())
+
 	return []byte(out.String()), nil
 }
 
 func (g *Generator) getPackageName() string {
+	// Default to first package name
+	goPackage := ""
+	if g.ctx != nil && len(g.ctx.Structs) > 0 {
+		goPackage = g.ctx.Structs[0].Package
+	}
+	return g.packageNameFor(goPackage)
+}
+
+// packageNameFor returns the proto package of the types of a Go package in the current namespace
+func (g *Generator) packageNameFor(goPackage string) string {
 	// First check for @proto.package annotation in file-level comments
 	if fileAnnotations := g.extractFileLevelPackageAnnotation(); fileAnnotations != "" {
 		return fileAnnotations
 	}
 
+	// Then the package of the namespace, from the namespaces config
+	if pkg := g.namespaceConfig().Package; pkg != "" {
+		return g.expandNamespace(pkg)
+	}
+
 	// Fall back to config
 	if g.formatGen.config.Package != "" {
-		return g.formatGen.config.Package
-	}
-	// Default to first package name
-	if g.ctx != nil && len(g.ctx.Structs) > 0 {
-		if pkg := g.ctx.Structs[0].Package; pkg != "" {
-			return pkg
-		}
+		return g.expandNamespace(g.formatGen.config.Package)
 	}
-	return ""
+	return goPackage
 }
 
 func (g *Generator) writeOptions(out *strings.Builder) error {
+	if g.formatGen.config.OptimizeFor != "" {
+		// optimize_for doesn't use quotes
+		fmt.Fprintf(out, "option optimize_for = %s;\n", g.formatGen.config.OptimizeFor)
+	}
+
+	// Editions features are enum values, written without quotes
+	features := g.collectFileFeatures()
+	for _, feature := range features {
+		fmt.Fprintf(out, "option %s = %s;\n", feature.Name, feature.Value)
+	}
+
+	options := g.collectFileOptions()
+
+	// Write all options in sorted order for consistency
+	keys := make([]string, 0, len(options))
+	for k := range options {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		fmt.Fprintf(out, "option %s = \"%s\";\n", k, options[k])
+	}
+
+	if len(options) > 0 || len(features) > 0 {
+		out.WriteString("\n")
+	}
+	return nil
+}
+
+// collectFileOptions collects the quoted file options from config and file-level annotations.
+// optimize_for is handled separately since it is written without quotes.
+func (g *Generator) collectFileOptions() map[string]string {
 	// Collect all options (config + file-level annotations)
 	options := make(map[string]string)
 
@@ -267,10 +339,6 @@ func (g *Generator) writeOptions(out *strings.Builder) error {
 	if g.formatGen.config.JavaOuterClass != "" {
 		options["java_outer_classname"] = g.formatGen.config.JavaOuterClass
 	}
-	if g.formatGen.config.OptimizeFor != "" {
-		// optimize_for doesn't use quotes
-		fmt.Fprintf(out, "option optimize_for = %s;\n", g.formatGen.config.OptimizeFor)
-	}
 
 	// Add additional options from config.Options
 	if g.formatGen.config.Options != nil {
@@ -279,27 +347,21 @@ func (g *Generator) writeOptions(out *strings.Builder) error {
 		}
 	}
 
+	// The go_package of the namespace overrides the general one
+	if goPackage := g.namespaceConfig().GoPackage; goPackage != "" {
+		options["go_package"] = goPackage
+	}
+	for k, v := range options {
+		options[k] = g.expandNamespace(v)
+	}
+
 	// Extract file-level @proto.option annotations
 	fileOptions := g.extractFileLevelOptions()
 	for k, v := range fileOptions {
 		options[k] = v // File-level overrides config
 	}
 
-	// Write all options in sorted order for consistency
-	keys := make([]string, 0, len(options))
-	for k := range options {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-
-	for _, k := range keys {
-		fmt.Fprintf(out, "option %s = \"%s\";\n", k, options[k])
-	}
-
-	if len(options) > 0 {
-		out.WriteString("\n")
-	}
-	return nil
+	return options
 }
 
 // extractFileLevelOptions extracts @proto.option annotations from file-level comments
@@ -308,10 +370,28 @@ func (g *Generator) extractFileLevelOptions() map[string]string {
 
 	// Check file-level annotations that were extracted during parsing
 	if g.ctx != nil && g.ctx.FileAnnotations != nil {
+		// The parser also reads the comments of enum values and struct fields as file-level
+		// ones, their options aren't options of the file
+		elementOptions := make(map[string]bool)
+		for _, e := range g.ctx.Enums {
+			for _, v := range e.Values {
+				for _, ann := range v.Annotations {
+					elementOptions[ann.RawText] = true
+				}
+			}
+		}
+		for _, st := range g.ctx.Structs {
+			for _, f := range st.Fields {
+				for _, ann := range f.Annotations {
+					elementOptions[ann.RawText] = true
+				}
+			}
+		}
+
 		for _, fileAnns := range g.ctx.FileAnnotations {
 			for _, ann := range fileAnns {
 				name := strings.ToLower(ann.Name)
-				if name == "option" || strings.HasSuffix(name, ".option") {
+				if (name == "option" || strings.HasSuffix(name, ".option")) && !elementOptions[ann.RawText] {
 					// Extract option key and value
 					if key, exists := ann.GetParamValue("name"); exists {
 						if value, exists := ann.GetParamValue("value"); exists {
@@ -399,119 +479,48 @@ func (g *Generator) extractFileLevelImports() []ImportInfo {
 }
 
 func (g *Generator) writeImports(out *strings.Builder) error {
+	imports := g.collectImports()
+	if len(imports) == 0 {
+		return nil
+	}
+
+	for _, imp := range imports {
+		// Check if this import has special modifiers (public/weak)
+		var prefix string
+		if imp.Public {
+			prefix = "public "
+		} else if imp.Weak {
+			prefix = "weak "
+		}
+		fmt.Fprintf(out, "import %s\"%s\";\n", prefix, imp.Path)
+	}
+	out.WriteString("\n")
+
+	return nil
+}
+
+// collectImports resolves every import required by the current file, sorted by path
+func (g *Generator) collectImports() []ImportInfo {
 	imports := make(map[string]bool)
 
 	// Add file imports for multi-file generation
-	if len(g.ctx.FileTypeMappings) > 0 {
+	if g.files != nil {
+		for _, importFile := range g.files.imports[g.getCurrentFileName()] {
+			imports[protoImportPath(importFile)] = true
+		}
+	} else if len(g.ctx.FileTypeMappings) > 0 {
 		currentFile := g.getCurrentFileName()
 		usedTypes := g.getUsedTypes()
 		requiredImports := g.ctx.GetRequiredImports(currentFile, usedTypes)
 
 		for _, importFile := range requiredImports {
-			// Convert full file path to protoc import path
-			// Remove "schema/" prefix since protoc is run with "-I schema"
-			importPath := importFile
-			if len(importFile) > 7 && importFile[:7] == "schema/" {
-				importPath = importFile[7:] // Remove "schema/" prefix
-			}
-			imports[importPath] = true
-		}
-	}
-
-	// Add well-known types if enabled
-	if g.formatGen.config.KnownTypes != nil {
-		// Get merged known types from core and format configs
-		knownTypes := g.getMergedKnownTypes()
-
-		// Scan for types that match known type mappings
-		for _, s := range g.ctx.Structs {
-			for _, f := range s.Fields {
-				goType := g.getGoTypeName(f.Type)
-
-				// Check if this type matches any known type mapping
-				for _, mapping := range knownTypes {
-					for _, model := range mapping.Model {
-						if strings.Contains(goType, model) {
-							// Check if the mapping specifies a protobuf type that needs an import
-							if mapping.Type == "google.protobuf.Timestamp" || strings.Contains(mapping.Type, "Timestamp") {
-								imports["google/protobuf/timestamp.proto"] = true
-							}
-							if mapping.Type == "google.protobuf.Duration" || strings.Contains(mapping.Type, "Duration") {
-								imports["google/protobuf/duration.proto"] = true
-							}
-							if mapping.Type == "google.protobuf.Empty" || strings.Contains(mapping.Type, "Empty") {
-								imports["google/protobuf/empty.proto"] = true
-							}
-						}
-					}
-				}
-
-				// Fallback to simple string matching for backward compatibility
-				if strings.Contains(goType, "time.Time") {
-					imports["google/protobuf/timestamp.proto"] = true
-				}
-				if strings.Contains(goType, "time.Duration") {
-					imports["google/protobuf/duration.proto"] = true
-				}
-
-				// Check for Any type
-				if strings.Contains(goType, "any") || goType == "any" {
-					imports["google/protobuf/any.proto"] = true
-				}
-
-				// Check for wrapper types
-				if strings.Contains(goType, "wrapperspb") {
-					imports["google/protobuf/wrappers.proto"] = true
-				}
-
-				// Check for struct types
-				if strings.Contains(goType, "structpb") {
-					imports["google/protobuf/struct.proto"] = true
-				}
-
-				// Check for empty type
-				if strings.Contains(goType, "emptypb") {
-					imports["google/protobuf/empty.proto"] = true
-				}
-			}
+			imports[protoImportPath(importFile)] = true
 		}
 	}
 
-	// Check services for google.protobuf wrapper types and add necessary imports
-	for _, service := range g.services {
-		for _, method := range service.Methods {
-			// Check input type
-			if strings.Contains(method.InputType, "google.protobuf.") {
-				if strings.Contains(method.InputType, "Value") {
-					imports["google/protobuf/wrappers.proto"] = true
-				}
-				if strings.Contains(method.InputType, "Empty") {
-					imports["google/protobuf/empty.proto"] = true
-				}
-				if strings.Contains(method.InputType, "Timestamp") {
-					imports["google/protobuf/timestamp.proto"] = true
-				}
-				if strings.Contains(method.InputType, "Duration") {
-					imports["google/protobuf/duration.proto"] = true
-				}
-			}
-
-			// Check output type
-			if strings.Contains(method.OutputType, "google.protobuf.") {
-				if strings.Contains(method.OutputType, "Value") {
-					imports["google/protobuf/wrappers.proto"] = true
-				}
-				if strings.Contains(method.OutputType, "Empty") {
-					imports["google/protobuf/empty.proto"] = true
-				}
-				if strings.Contains(method.OutputType, "Timestamp") {
-					imports["google/protobuf/timestamp.proto"] = true
-				}
-				if strings.Contains(method.OutputType, "Duration") {
-					imports["google/protobuf/duration.proto"] = true
-				}
-			}
-		}
+	// Add the files declaring the well-known and mapped types the file uses
+	for _, file := range g.typeImports() {
+		imports[file] = true
 	}
 
 	// Add custom imports
@@ -527,32 +536,26 @@ func (g *Generator) writeImports(out *strings.Builder) error {
 		annotationImports = append(annotationImports, importInfo)
 	}
 
-	// Write imports
-	if len(imports) > 0 {
-		var sortedImports []string
-		for imp := range imports {
-			sortedImports = append(sortedImports, imp)
-		}
-		sort.Strings(sortedImports)
-		for _, imp := range sortedImports {
-			// Check if this import has special modifiers (public/weak)
-			var prefix string
-			for _, annImport := range annotationImports {
-				if annImport.Path == imp {
-					if annImport.Public {
-						prefix = "public "
-					} else if annImport.Weak {
-						prefix = "weak "
-					}
-					break
-				}
+	var sortedImports []string
+	for imp := range imports {
+		sortedImports = append(sortedImports, imp)
+	}
+	sort.Strings(sortedImports)
+
+	result := make([]ImportInfo, 0, len(sortedImports))
+	for _, imp := range sortedImports {
+		info := ImportInfo{Path: imp}
+		// Keep the public/weak modifiers declared via annotations
+		for _, annImport := range annotationImports {
+			if annImport.Path == imp {
+				info = annImport
+				break
 			}
-			fmt.Fprintf(out, "import %s\"%s\";\n", prefix, imp)
 		}
-		out.WriteString("\n")
+		result = append(result, info)
 	}
 
-	return nil
+	return result
 }
 
 // shouldSkipFieldForMessage determines if a field should be skipped for a specific message
@@ -810,6 +813,16 @@ func (g *Generator) generateMessages(out *strings.Builder) error {
 }
 
 func (g *Generator) processStruct(out *strings.Builder, s *parser.StructInfo) error {
+	for _, messageName := range g.resolveMessageNames(s) {
+		if err := g.generateMessage(out, s, messageName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveMessageNames returns the names of the messages generated for a struct
+func (g *Generator) resolveMessageNames(s *parser.StructInfo) []string {
 	// Skip generic structs with type parameters (not concrete instantiations)
 	if s.IsGeneric {
 		return nil
@@ -820,26 +833,21 @@ func (g *Generator) processStruct(out *strings.Builder, s *parser.StructInfo) er
 		return nil
 	}
 
+	// Structs declared with @message(nested_in=...) are generated inside their parent
+	if g.getNestedIn(s) != "" {
+		return nil
+	}
+
 	// Get all message names from annotations (supports multiple @proto.message)
 	messageNames := g.getMessageNames(s)
 
 	if len(messageNames) == 0 {
 		// No explicit @message annotation, check if should generate default
 		if !s.IsEmpty && !g.hasOtherTypeAnnotation(s) {
-			// Generate with default name
-			if err := g.generateMessage(out, s, ""); err != nil {
-				return err
-			}
-		}
-	} else {
-		// Generate a message for each @proto.message annotation
-		for _, messageName := range messageNames {
-			if err := g.generateMessage(out, s, messageName); err != nil {
-				return err
-			}
+			return []string{g.getMessageName(s, "")}
 		}
 	}
-	return nil
+	return messageNames
 }
 
 func (g *Generator) shouldSkipType(s *parser.StructInfo) bool {
@@ -858,7 +866,8 @@ func (g *Generator) hasOtherTypeAnnotation(s *parser.StructInfo) bool {
 	for _, ann := range s.Annotations {
 		name := strings.ToLower(ann.Name)
 		if name == "service" || strings.HasSuffix(name, ".service") ||
-			name == "enum" || strings.HasSuffix(name, ".enum") {
+			name == "enum" || strings.HasSuffix(name, ".enum") ||
+			name == "extend" || strings.HasSuffix(name, ".extend") {
 			return true
 		}
 	}
@@ -890,12 +899,103 @@ func (g *Generator) generateMessage(out *strings.Builder, s *parser.StructInfo,
 	}
 
 	// Get description from the specific @proto.message annotation that matches this messageName
-	desc := g.getMessageDescription(s, messageName)
+	desc := g.getMessageDescription(s, localMessageName(messageName))
 	if desc != "" {
 		fmt.Fprintf(out, "// %s\n", desc)
 	}
 
-	fmt.Fprintf(out, "message %s {\n", messageName)
+	fmt.Fprintf(out, "message %s {\n", localMessageName(messageName))
+
+	// Nested messages can be declared in another Go package than their parent
+	parentPackage := g.goPackage
+	g.goPackage = s.PackagePath
+	defer func() { g.goPackage = parentPackage }()
+
+	// Nested messages are written first, one level deeper
+	for _, nested := range g.collectNestedMessages(s, messageName) {
+		var block strings.Builder
+		if err := g.generateMessage(&block, nested.Struct, nested.Name); err != nil {
+			return err
+		}
+		writeIndented(out, block.String())
+		out.WriteString("\n")
+	}
+
+	fields, reservedNumbers := g.resolveMessageFields(s, messageName)
+
+	// Collections nested in lists and maps are wrapped in messages of their own
+	for _, wrapper := range g.collectionWrappers(fields) {
+		var block strings.Builder
+		g.generateCollectionWrapper(&block, wrapper)
+		writeIndented(out, block.String())
+		out.WriteString("\n")
+	}
+
+	currentOneof := ""
+	for _, mf := range fields {
+		// Open/close oneof blocks as the group changes
+		if mf.Oneof != currentOneof {
+			if currentOneof != "" {
+				fmt.Fprintf(out, "  }\n")
+			}
+			if mf.Oneof != "" {
+				fmt.Fprintf(out, "  oneof %s {\n", mf.Oneof)
+			}
+			currentOneof = mf.Oneof
+		}
+
+		fieldLine := g.generateField(mf.Field, mf.Number, mf.Oneof != "")
+		if fieldLine == "" {
+			continue
+		}
+		if mf.Oneof != "" {
+			// Remove the leading spaces since we're inside a oneof block
+			fmt.Fprintf(out, "    %s\n", strings.TrimSpace(fieldLine))
+		} else {
+			fmt.Fprintf(out, "  %s\n", fieldLine)
+		}
+	}
+	if currentOneof != "" {
+		fmt.Fprintf(out, "  }\n")
+	}
+
+	// Output reserved field numbers if any
+	if len(reservedNumbers) > 0 {
+		sort.Ints(reservedNumbers)
+		reservedRanges := g.compactReservedRanges(reservedNumbers)
+		fmt.Fprintf(out, "  reserved %s;\n", strings.Join(reservedRanges, ", "))
+	}
+
+	// Output reserved field names from @proto.reserved annotation
+	reservedNames := g.getReservedNames(s, messageName)
+	if len(reservedNames) > 0 {
+		quotedNames := make([]string, len(reservedNames))
+		for i, name := range reservedNames {
+			quotedNames[i] = fmt.Sprintf("\"%s\"", name)
+		}
+		fmt.Fprintf(out, "  reserved %s;\n", strings.Join(quotedNames, ", "))
+	}
+
+	// Output extension ranges from @proto.extensions (not available in proto3)
+	if ranges := g.getExtensionRanges(s); len(ranges) > 0 && g.formatGen.config.Syntax != "proto3" {
+		fmt.Fprintf(out, "  extensions %s;\n", formatNumberRanges(ranges))
+	}
+
+	out.WriteString("}\n\n")
+	return nil
+}
+
+// messageField is a field resolved for a specific message
+type messageField struct {
+	Field  *parser.FieldInfo // Field with generic types substituted
+	Number int
+	Oneof  string // Oneof group name, empty for regular fields
+}
+
+// resolveMessageFields resolves the fields of a message in output order (oneof groups first)
+// and assigns their numbers. It also returns the field numbers that must be reserved.
+func (g *Generator) resolveMessageFields(s *parser.StructInfo, messageName string) ([]messageField, []int) {
+	var fields []messageField
 
 	// Build type substitutions for generic types
 	typeSubstitutions := g.buildTypeSubstitutions(s)
@@ -909,13 +1009,27 @@ func (g *Generator) generateMessage(out *strings.Builder, s *parser.StructInfo,
 	// Reset field number for this message
 	fieldNum := g.formatGen.config.StartFieldNumber
 
-	// Group fields by oneof annotations
+	// With a lockfile, auto-assigned numbers must not collide with explicit ones
+	explicitNumbers := make(map[int]bool)
+	if g.lock != nil {
+		for _, f := range s.Fields {
+			if num := g.getExplicitFieldNumber(f); num > 0 {
+				explicitNumbers[num] = true
+			}
+		}
+	}
+
+	// Group fields by oneof annotations (sorted so numbering is stable)
 	oneofGroups := g.groupFieldsByOneof(s.Fields, messageName)
+	groupNames := make([]string, 0, len(oneofGroups))
+	for groupName := range oneofGroups {
+		groupNames = append(groupNames, groupName)
+	}
+	sort.Strings(groupNames)
 
-	// Generate oneof groups first
-	for groupName, fields := range oneofGroups {
-		fmt.Fprintf(out, "  oneof %s {\n", groupName)
-		for _, f := range fields {
+	// Resolve oneof groups first
+	for _, groupName := range groupNames {
+		for _, f := range oneofGroups[groupName] {
 			if f.GoName == "" || len(f.GoName) == 0 || f.GoName[0] < 'A' || f.GoName[0] > 'Z' {
 				continue // skip unexported fields
 			}
@@ -926,33 +1040,15 @@ func (g *Generator) generateMessage(out *strings.Builder, s *parser.StructInfo,
 				continue
 			}
 
-			// Substitute generic types if needed
-			modifiedField := f
-			if len(typeSubstitutions) > 0 {
-				substitutedType := g.substituteGenericType(f.Type, typeSubstitutions)
-				modifiedField = &parser.FieldInfo{
-					Name:        f.Name,
-					GoName:      f.GoName,
-					Type:        substitutedType,
-					Tag:         f.Tag,
-					IsEmbedded:  f.IsEmbedded,
-					Annotations: f.Annotations,
-				}
-			}
+			modifiedField := g.substituteAnonymousStruct(g.substituteFieldType(f, typeSubstitutions), messageName)
 
 			// Get field number from annotation or auto-assign
-			num := g.getFieldNumber(modifiedField, &fieldNum)
-			fieldLine := g.generateField(modifiedField, num)
-			if fieldLine != "" {
-				// Remove the leading spaces since we're inside a oneof block
-				fieldLine = strings.TrimSpace(fieldLine)
-				fmt.Fprintf(out, "    %s\n", fieldLine)
-			}
+			num := g.assignFieldNumber(messageName, modifiedField, &fieldNum, explicitNumbers)
+			fields = append(fields, messageField{Field: modifiedField, Number: num, Oneof: groupName})
 		}
-		fmt.Fprintf(out, "  }\n")
 	}
 
-	// Generate fields
+	// Resolve regular fields
 	for _, f := range s.Fields {
 		if f.GoName == "" || len(f.GoName) == 0 || f.GoName[0] < 'A' || f.GoName[0] > 'Z' {
 			// skip unexported fields
@@ -987,47 +1083,47 @@ func (g *Generator) generateMessage(out *strings.Builder, s *parser.StructInfo,
 			continue
 		}
 
-		// Substitute generic types if needed
-		modifiedField := f
-		if len(typeSubstitutions) > 0 {
-			substitutedType := g.substituteGenericType(f.Type, typeSubstitutions)
-			modifiedField = &parser.FieldInfo{
-				Name:        f.Name,
-				GoName:      f.GoName,
-				Type:        substitutedType,
-				Tag:         f.Tag,
-				IsEmbedded:  f.IsEmbedded,
-				Annotations: f.Annotations,
+		modifiedField := g.substituteAnonymousStruct(g.substituteFieldType(f, typeSubstitutions), messageName)
+
+		// @union interface fields become a oneof with one case per variant
+		if oneofName, cases := g.unionCases(modifiedField); len(cases) > 0 {
+			for _, c := range cases {
+				num := g.assignFieldNumber(messageName, c.Field, &fieldNum, explicitNumbers)
+				fields = append(fields, messageField{Field: c.Field, Number: num, Oneof: oneofName})
 			}
+			continue
 		}
 
 		// Get field number from annotation or auto-assign
-		num := g.getFieldNumber(modifiedField, &fieldNum)
-		fieldLine := g.generateField(modifiedField, num)
-		if fieldLine != "" {
-			fmt.Fprintf(out, "  %s\n", fieldLine)
-		}
+		num := g.assignFieldNumber(messageName, modifiedField, &fieldNum, explicitNumbers)
+		fields = append(fields, messageField{Field: modifiedField, Number: num})
 	}
 
-	// Output reserved field numbers if any
-	if len(reservedNumbers) > 0 {
-		sort.Ints(reservedNumbers)
-		reservedRanges := g.compactReservedRanges(reservedNumbers)
-		fmt.Fprintf(out, "  reserved %s;\n", strings.Join(reservedRanges, ", "))
+	// Fields removed since the lockfile was written keep their numbers reserved
+	if g.lock != nil {
+		present := make(map[string]int, len(fields))
+		for _, mf := range fields {
+			present[g.getFieldName(mf.Field)] = mf.Number
+		}
+		reservedNumbers = uniqueSortedInts(append(reservedNumbers, g.lock.ReconcileMessage(messageName, present)...))
 	}
 
-	// Output reserved field names from @proto.reserved annotation
-	reservedNames := g.getReservedNames(s, messageName)
-	if len(reservedNames) > 0 {
-		quotedNames := make([]string, len(reservedNames))
-		for i, name := range reservedNames {
-			quotedNames[i] = fmt.Sprintf("\"%s\"", name)
-		}
-		fmt.Fprintf(out, "  reserved %s;\n", strings.Join(quotedNames, ", "))
+	return fields, reservedNumbers
+}
+
+// substituteFieldType returns a copy of the field with generic types substituted, if needed
+func (g *Generator) substituteFieldType(f *parser.FieldInfo, typeSubstitutions map[string]ast.Expr) *parser.FieldInfo {
+	if len(typeSubstitutions) == 0 {
+		return f
+	}
+	return &parser.FieldInfo{
+		Name:        f.Name,
+		GoName:      f.GoName,
+		Type:        g.substituteGenericType(f.Type, typeSubstitutions),
+		Tag:         f.Tag,
+		IsEmbedded:  f.IsEmbedded,
+		Annotations: f.Annotations,
 	}
-
-	out.WriteString("}\n\n")
-	return nil
 }
 
 // compactReservedRanges converts a sorted list of numbers into compact ranges
@@ -1094,6 +1190,15 @@ func (g *Generator) getReservedNames(s *parser.StructInfo, messageName string) [
 		}
 	}
 
+	// Names of fields removed since the lockfile was written
+	if g.lock != nil {
+		for _, reserved := range g.lock.ReservedFieldNames(messageName) {
+			if !slices.Contains(names, reserved) {
+				names = append(names, reserved)
+			}
+		}
+	}
+
 	return names
 }
 
@@ -1255,6 +1360,18 @@ func (g *Generator) getMessageDescription(s *parser.StructInfo, messageName stri
 
 func (g *Generator) getFieldNumber(f *parser.FieldInfo, counter *int) int {
 	// Check for explicit number in annotation or struct tag
+	if num := g.getExplicitFieldNumber(f); num > 0 {
+		return num
+	}
+
+	// Auto-assign
+	num := *counter
+	*counter++
+	return num
+}
+
+// getExplicitFieldNumber returns the number set via annotation or struct tag, or 0
+func (g *Generator) getExplicitFieldNumber(f *parser.FieldInfo) int {
 	for _, ann := range f.Annotations {
 		name := strings.ToLower(ann.Name)
 		if name == "field" || strings.HasSuffix(name, ".field") || name == "map" || strings.HasSuffix(name, ".map") {
@@ -1284,13 +1401,31 @@ func (g *Generator) getFieldNumber(f *parser.FieldInfo, counter *int) int {
 		}
 	}
 
-	// Auto-assign
-	num := *counter
-	*counter++
+	return 0
+}
+
+// assignFieldNumber returns the number of a field in a message. Explicit numbers win,
+// then the number recorded in the lockfile, then a fresh auto-assigned number.
+func (g *Generator) assignFieldNumber(messageName string, f *parser.FieldInfo, counter *int, explicit map[int]bool) int {
+	if g.lock == nil {
+		return g.getFieldNumber(f, counter)
+	}
+
+	fieldName := g.getFieldName(f)
+	if num := g.getExplicitFieldNumber(f); num > 0 {
+		g.lock.RecordField(messageName, fieldName, num)
+		return num
+	}
+	if num, ok := g.lock.FieldNumber(messageName, fieldName); ok && !explicit[num] {
+		return num
+	}
+
+	num := g.lock.NextFieldNumber(messageName, counter, explicit)
+	g.lock.RecordField(messageName, fieldName, num)
 	return num
 }
 
-func (g *Generator) generateField(f *parser.FieldInfo, number int) string {
+func (g *Generator) generateField(f *parser.FieldInfo, number int, inOneof bool) string {
 	fieldName := g.getFieldName(f)
 
 	// Check if this is a map field
@@ -1303,26 +1438,31 @@ func (g *Generator) generateField(f *parser.FieldInfo, number int) string {
 	// Check for repeated (array/slice)
 	isRepeated := g.isRepeated(f)
 
-	// Check for optional (pointer in proto3)
-	isOptional := g.isOptional(f)
-
 	var parts []string
 
-	// Add repeated keyword
+	// Add the label. Oneof fields take no label, proto2 singular fields are always
+	// labeled, and editions express presence with features instead.
 	if isRepeated {
 		parts = append(parts, "repeated")
-	} else if isOptional && g.formatGen.config.Syntax == "proto3" {
+	} else if g.isProto2() && !inOneof {
+		if g.isRequired(f) {
+			parts = append(parts, "required")
+		} else {
+			parts = append(parts, "optional")
+		}
+	} else if g.isProto3Optional(f, inOneof) {
 		parts = append(parts, "optional")
 	}
 
 	// Add type and name
+	g.useType(protoType)
 	parts = append(parts, protoType, fieldName)
 
 	// Add field number
 	fieldDef := fmt.Sprintf("%s = %d", strings.Join(parts, " "), number)
 
 	// Add options (packed, deprecated, json_name, etc.)
-	options := g.getFieldOptions(f)
+	options := g.getFieldOptions(f, inOneof)
 	if len(options) > 0 {
 		fieldDef += fmt.Sprintf(" [%s]", strings.Join(options, ", "))
 	}
@@ -1339,6 +1479,31 @@ func (g *Generator) generateField(f *parser.FieldInfo, number int) string {
 
 // generateMapField generates a map field definition if the field is a map
 func (g *Generator) generateMapField(f *parser.FieldInfo, fieldName string, number int) string {
+	keyType, valueType, ok := g.resolveMapTypes(f)
+	if !ok {
+		return "" // Not a map field
+	}
+
+	g.useType(valueType)
+	mapDef := fmt.Sprintf("map<%s, %s> %s = %d", keyType, valueType, fieldName, number)
+
+	// Add options if any
+	options := g.getFieldOptions(f, false)
+	if len(options) > 0 {
+		mapDef += fmt.Sprintf(" [%s]", strings.Join(options, ", "))
+	}
+	mapDef += ";"
+
+	// Add comment
+	if desc := g.getFieldDescription(f); desc != "" {
+		mapDef = fmt.Sprintf("// %s\n  %s", desc, mapDef)
+	}
+	return mapDef
+}
+
+// resolveMapTypes returns the proto key and value types if the field is a map,
+// either declared with @proto.map or detected from a Go map type
+func (g *Generator) resolveMapTypes(f *parser.FieldInfo) (string, string, bool) {
 	// Check for @proto.map annotation
 	for _, ann := range f.Annotations {
 		name := strings.ToLower(ann.Name)
@@ -1346,25 +1511,7 @@ func (g *Generator) generateMapField(f *parser.FieldInfo, fieldName string, numb
 			keyType, _ := ann.GetParamValue("key")
 			valueType, _ := ann.GetParamValue("value")
 			if keyType != "" && valueType != "" {
-				// Check for custom number in @proto.map annotation
-				if numStr := ann.Params["number"]; numStr != "" {
-					_, _ = fmt.Sscanf(numStr, "%d", &number) // Ignore error, keep default if parse fails
-				}
-
-				mapDef := fmt.Sprintf("map<%s, %s> %s = %d", keyType, valueType, fieldName, number)
-
-				// Add options if any
-				options := g.getFieldOptions(f)
-				if len(options) > 0 {
-					mapDef += fmt.Sprintf(" [%s]", strings.Join(options, ", "))
-				}
-				mapDef += ";"
-
-				// Add comment
-				if desc := g.getFieldDescription(f); desc != "" {
-					mapDef = fmt.Sprintf("// %s\n  %s", desc, mapDef)
-				}
-				return mapDef
+				return keyType, valueType, true
 			}
 		}
 	}
@@ -1372,28 +1519,15 @@ func (g *Generator) generateMapField(f *parser.FieldInfo, fieldName string, numb
 	// Auto-detect Go map types
 	if mapType, ok := f.Type.(*ast.MapType); ok {
 		keyType := g.mapGoTypeToProto(mapType.Key)
-		valueType := g.mapGoTypeToProto(mapType.Value)
+		valueType := g.collectionValueType(mapType.Value)
 
 		// Validate key type (protobuf only allows specific types as map keys)
 		if g.isValidMapKey(keyType) {
-			mapDef := fmt.Sprintf("map<%s, %s> %s = %d", keyType, valueType, fieldName, number)
-
-			// Add options if any
-			options := g.getFieldOptions(f)
-			if len(options) > 0 {
-				mapDef += fmt.Sprintf(" [%s]", strings.Join(options, ", "))
-			}
-			mapDef += ";"
-
-			// Add comment
-			if desc := g.getFieldDescription(f); desc != "" {
-				mapDef = fmt.Sprintf("// %s\n  %s", desc, mapDef)
-			}
-			return mapDef
+			return keyType, valueType, true
 		}
 	}
 
-	return "" // Not a map field
+	return "", "", false
 }
 
 // isValidMapKey checks if a type is valid as a protobuf map key
@@ -1461,6 +1595,11 @@ func (g *Generator) getProtoType(f *parser.FieldInfo) string {
 		}
 	}
 
+	// Optional scalars are written as well-known wrappers with optional_style: wrappers
+	if wrapper, ok := g.optionalWrapper(f); ok {
+		return wrapper
+	}
+
 	// Map Go type to protobuf type
 	return g.mapGoTypeToProto(f.Type)
 }
@@ -1469,6 +1608,13 @@ func (g *Generator) mapGoTypeToProto(t ast.Expr) string {
 	// Handle composite types structurally to avoid recursion issues
 	switch v := t.(type) {
 	case *ast.ArrayType:
+		if isByteArray(v) {
+			return "bytes"
+		}
+		// Lists of collections are lists of wrapper messages
+		if isCollection(v.Elt) {
+			return g.wrapperName(v.Elt)
+		}
 		// Repeated is handled separately; map the element type
 		return g.mapGoTypeToProto(v.Elt)
 	case *ast.StarExpr:
@@ -1479,72 +1625,100 @@ func (g *Generator) mapGoTypeToProto(t ast.Expr) string {
 	goType := g.getGoTypeName(t)
 
 	// Check custom mappings
-	if mapped, ok := g.formatGen.config.TypeMappings[goType]; ok {
-		return mapped
+	if mapping, ok := g.customTypeMapping(goType); ok {
+		return mapping.ProtoType
+	}
+	if builtin, ok := g.builtinType(goType); ok {
+		return builtin.proto
+	}
+	if protoType, ok := g.timeProtoType(goType); ok {
+		return protoType
+	}
+
+	// Types resolve through go/types, by name when the packages couldn't be loaded
+	if g.types != nil {
+		if protoType, ok := g.resolveProtoType(goType); ok {
+			return protoType
+		}
+	} else if protoType, ok := standardProtoType(goType); ok {
+		return protoType
+	}
+
+	// Messages and enums of other files are referenced through their package
+	if name, ok := g.crossFileTypeName(goType); ok {
+		return name
+	}
+	if name, ok := g.sameFileTypeName(goType); ok {
+		return name
+	}
+
+	// Aliases map to the type they stand for
+	if resolved, _, ok := g.resolveNamedType(goType); ok && resolved != goType {
+		return g.mapGoTypeToProto(&ast.Ident{Name: resolved})
+	}
+
+	// Types declared with @message(nested_in=...) are referenced by their qualified name
+	if nested, ok := g.nestedTypeName(goType); ok {
+		return nested
 	}
 
-	// Standard mappings
+	// Assume it's a message type
+	return goType
+}
+
+// standardProtoType maps the Go types known by name when no type information is available
+func standardProtoType(goType string) (string, bool) {
 	switch goType {
 	case "string", "*string":
-		return "string"
+		return "string", true
 	case "int", "int32", "*int32":
-		return "int32"
+		return "int32", true
 	case "int64", "*int64":
-		return "int64"
+		return "int64", true
 	case "uint", "uint32", "*uint32":
-		return "uint32"
+		return "uint32", true
 	case "uint64", "*uint64":
-		return "uint64"
+		return "uint64", true
 	case "bool", "*bool":
-		return "bool"
+		return "bool", true
 	case "float32", "*float32":
-		return "float"
+		return "float", true
 	case "float64", "*float64":
-		return "double"
-	case "[]byte":
-		return "bytes"
-	case "time.Time", "*time.Time":
-		return "google.protobuf.Timestamp"
-	case "time.Duration", "*time.Duration":
-		return "google.protobuf.Duration"
+		return "double", true
 	case "any", "*any":
-		return "google.protobuf.Any"
+		return "google.protobuf.Any", true
 	case "interface{}", "*interface{}":
-		return "google.protobuf.Any"
+		return "google.protobuf.Any", true
 
 	// Well-known wrapper types
 	case "wrapperspb.StringValue", "*wrapperspb.StringValue":
-		return "google.protobuf.StringValue"
+		return "google.protobuf.StringValue", true
 	case "wrapperspb.Int32Value", "*wrapperspb.Int32Value":
-		return "google.protobuf.Int32Value"
+		return "google.protobuf.Int32Value", true
 	case "wrapperspb.Int64Value", "*wrapperspb.Int64Value":
-		return "google.protobuf.Int64Value"
+		return "google.protobuf.Int64Value", true
 	case "wrapperspb.UInt32Value", "*wrapperspb.UInt32Value":
-		return "google.protobuf.UInt32Value"
+		return "google.protobuf.UInt32Value", true
 	case "wrapperspb.UInt64Value", "*wrapperspb.UInt64Value":
-		return "google.protobuf.UInt64Value"
+		return "google.protobuf.UInt64Value", true
 	case "wrapperspb.FloatValue", "*wrapperspb.FloatValue":
-		return "google.protobuf.FloatValue"
+		return "google.protobuf.FloatValue", true
 	case "wrapperspb.DoubleValue", "*wrapperspb.DoubleValue":
-		return "google.protobuf.DoubleValue"
+		return "google.protobuf.DoubleValue", true
 	case "wrapperspb.BoolValue", "*wrapperspb.BoolValue":
-		return "google.protobuf.BoolValue"
+		return "google.protobuf.BoolValue", true
 	case "wrapperspb.BytesValue", "*wrapperspb.BytesValue":
-		return "google.protobuf.BytesValue"
+		return "google.protobuf.BytesValue", true
 	case "structpb.Struct", "*structpb.Struct":
-		return "google.protobuf.Struct"
+		return "google.protobuf.Struct", true
 	case "structpb.Value", "*structpb.Value":
-		return "google.protobuf.Value"
+		return "google.protobuf.Value", true
 	case "structpb.ListValue", "*structpb.ListValue":
-		return "google.protobuf.ListValue"
+		return "google.protobuf.ListValue", true
 	case "emptypb.Empty", "*emptypb.Empty":
-		return "google.protobuf.Empty"
+		return "google.protobuf.Empty", true
 	}
-
-	// Basic string prefixes already handled above for structured types
-
-	// Assume it's a message type
-	return goType
+	return "", false
 }
 
 func (g *Generator) getGoTypeName(t ast.Expr) string {
@@ -1554,6 +1728,9 @@ func (g *Generator) getGoTypeName(t ast.Expr) string {
 	case *ast.StarExpr:
 		return "*" + g.getGoTypeName(v.X)
 	case *ast.ArrayType:
+		if length, ok := fixedByteArrayLen(v); ok {
+			return "[" + length + "]byte"
+		}
 		return "[]" + g.getGoTypeName(v.Elt)
 	case *ast.SelectorExpr:
 		return g.getGoTypeName(v.X) + "." + v.Sel.Name
@@ -1563,18 +1740,16 @@ func (g *Generator) getGoTypeName(t ast.Expr) string {
 	return "unknown"
 }
 
-// getQualifiedTypeName returns the fully qualified type name with package prefix
-func (g *Generator) getQualifiedTypeName(typeName string) string {
-	// Handle pointer types
-	if strings.HasPrefix(typeName, "*") {
-		baseType := strings.TrimPrefix(typeName, "*")
-		return "*" + g.getQualifiedTypeName(baseType)
-	}
-
-	// Handle slice types
-	if strings.HasPrefix(typeName, "[]") {
-		elementType := strings.TrimPrefix(typeName, "[]")
-		return "[]" + g.getQualifiedTypeName(elementType)
+// getQualifiedTypeName returns a Go type as written outside of its package, along with the import
+// path of that package. Unqualified types are declared in the package of the service (pkg, pkgPath),
+// qualified ones are resolved through the parsed types.
+func (g *Generator) getQualifiedTypeName(typeName, pkg, pkgPath string) (string, string) {
+	// Handle pointer and slice types
+	for _, prefix := range []string{"*", "[]"} {
+		if strings.HasPrefix(typeName, prefix) {
+			qualified, path := g.getQualifiedTypeName(strings.TrimPrefix(typeName, prefix), pkg, pkgPath)
+			return prefix + qualified, path
+		}
 	}
 
 	// Handle primitive types and error - return as is
@@ -1582,25 +1757,61 @@ func (g *Generator) getQualifiedTypeName(typeName string) string {
 	case "string", "int", "int8", "int16", "int32", "int64",
 		"uint", "uint8", "uint16", "uint32", "uint64",
 		"float32", "float64", "bool", "byte", "rune", "error":
-		return typeName
+		return typeName, ""
+	}
+	if strings.HasPrefix(typeName, "map[") {
+		return typeName, ""
 	}
 
-	// If already qualified (contains a dot), return as is
-	if strings.Contains(typeName, ".") {
-		return typeName
+	// Already qualified: find the package it refers to
+	if pkgName, name, qualified := strings.Cut(typeName, "."); qualified {
+		return typeName, g.resolvePackagePath(pkgName, name)
 	}
 
-	// For user-defined types, look up the package from parsed context
-	if g.ctx != nil && g.ctx.Structs != nil {
-		for _, structInfo := range g.ctx.Structs {
-			if structInfo.Name == typeName {
-				return structInfo.Package + "." + typeName
-			}
+	if pkg != "" {
+		return pkg + "." + typeName, pkgPath
+	}
+
+	// Without the service package, look the type up among the parsed types
+	for _, s := range g.ctx.Structs {
+		if s.Name == typeName {
+			return s.Package + "." + typeName, s.PackagePath
+		}
+	}
+	for _, e := range g.ctx.Enums {
+		if e.Name == typeName {
+			return e.Package + "." + typeName, e.PackagePath
+		}
+	}
+	return typeName, ""
+}
+
+// resolvePackagePath returns the import path of the package a qualified type (pkgName.name)
+// refers to, from the parsed types or, for other packages, from go/types
+func (g *Generator) resolvePackagePath(pkgName, name string) string {
+	ctx := g.lookupContext()
+	for _, s := range ctx.Structs {
+		if s.Package == pkgName && s.Name == name {
+			return s.PackagePath
+		}
+	}
+	for _, e := range ctx.Enums {
+		if e.Package == pkgName && e.Name == name {
+			return e.PackagePath
+		}
+	}
+	for _, iface := range ctx.Interfaces {
+		if iface.Package == pkgName && iface.Name == name {
+			return iface.PackagePath
 		}
 	}
 
-	// Fallback: assume it's from models package (this is the common case)
-	return "models." + typeName
+	if g.types != nil {
+		if obj := g.types.lookup(pkgName + "." + name); obj != nil && obj.Pkg() != nil {
+			return obj.Pkg().Path()
+		}
+	}
+	return ""
 }
 
 func (g *Generator) isRepeated(f *parser.FieldInfo) bool {
@@ -1614,9 +1825,11 @@ func (g *Generator) isRepeated(f *parser.FieldInfo) bool {
 		}
 	}
 
-	// Check if it's a slice/array
-	goType := g.getGoTypeName(f.Type)
-	return strings.HasPrefix(goType, "[]") && !strings.HasPrefix(goType, "[]byte")
+	// Check if it's a slice/array, byte slices and arrays are bytes
+	if array, ok := f.Type.(*ast.ArrayType); ok {
+		return !isByteArray(array)
+	}
+	return false
 }
 
 func (g *Generator) isOptional(f *parser.FieldInfo) bool {
@@ -1635,38 +1848,70 @@ func (g *Generator) isOptional(f *parser.FieldInfo) bool {
 	return strings.HasPrefix(goType, "*")
 }
 
-func (g *Generator) getFieldOptions(f *parser.FieldInfo) []string {
+// FieldOption is a field option as written in the proto file; Value is the proto literal
+type FieldOption struct {
+	Name  string
+	Value string
+}
+
+func (g *Generator) getFieldOptions(f *parser.FieldInfo, inOneof bool) []string {
 	var options []string
+	for _, opt := range g.resolveFieldOptions(f, inOneof) {
+		options = append(options, fmt.Sprintf("%s = %s", opt.Name, opt.Value))
+	}
+	return options
+}
+
+// resolveFieldOptions returns the options written for a field, translated to features in editions mode
+func (g *Generator) resolveFieldOptions(f *parser.FieldInfo, inOneof bool) []FieldOption {
+	options := g.collectFieldOptions(f)
+	if g.isEditions() {
+		return g.editionFieldOptions(f, options, inOneof)
+	}
+
+	// packed=false becomes the expanded encoding feature in editions, proto2 and proto3 files
+	// only write packed = true
+	options = slices.DeleteFunc(options, func(opt FieldOption) bool {
+		return opt.Name == "packed" && opt.Value != "true"
+	})
+
+	// Default values only exist in proto2, invalid ones are reported by the validator
+	if g.isProto2() {
+		if raw, ok := g.getDefaultValue(f); ok {
+			if value, err := g.formatDefaultValue(f, raw); err == nil {
+				options = append([]FieldOption{{Name: "default", Value: value}}, options...)
+			}
+		}
+	}
+	return options
+}
+
+// collectFieldOptions collects field options (packed, deprecated, json_name, custom) from annotations and tags
+func (g *Generator) collectFieldOptions(f *parser.FieldInfo) []FieldOption {
+	var options []FieldOption
 
 	// Check for options via annotations
 	for _, ann := range f.Annotations {
 		name := strings.ToLower(ann.Name)
 		if name == "field" || strings.HasSuffix(name, ".field") {
-			// packed option
-			if packedBool, ok := ann.GetParamBool("packed"); ok && packedBool {
-				options = append(options, "packed = true")
+			// packed option (packed=false matters too: repeated scalars are packed by default)
+			if packedBool, ok := ann.GetParamBool("packed"); ok {
+				options = append(options, FieldOption{Name: "packed", Value: strconv.FormatBool(packedBool)})
 			}
 			// deprecated option
 			if deprecatedBool, ok := ann.GetParamBool("deprecated"); ok && deprecatedBool {
-				options = append(options, "deprecated = true")
+				options = append(options, FieldOption{Name: "deprecated", Value: "true"})
 			}
 			// json_name option
 			if jsonName, ok := ann.GetParamValue("json_name"); ok && jsonName != "" {
-				options = append(options, fmt.Sprintf("json_name = \"%s\"", jsonName))
+				options = append(options, FieldOption{Name: "json_name", Value: fmt.Sprintf("\"%s\"", jsonName)})
 			}
 		}
 		// Check @proto.option annotation for custom options
 		if name == "option" || strings.HasSuffix(name, ".option") {
 			if optName, ok := ann.GetParamValue("name"); ok {
 				if optValue, ok := ann.GetParamValue("value"); ok {
-					// Try to parse as bool or number, otherwise treat as string
-					if optValue == "true" || optValue == "false" {
-						options = append(options, fmt.Sprintf("%s = %s", optName, optValue))
-					} else if _, err := strconv.Atoi(optValue); err == nil {
-						options = append(options, fmt.Sprintf("%s = %s", optName, optValue))
-					} else {
-						options = append(options, fmt.Sprintf("%s = \"%s\"", optName, optValue))
-					}
+					options = append(options, FieldOption{Name: optName, Value: optionLiteral(optValue)})
 				}
 			}
 		}
@@ -1681,17 +1926,16 @@ func (g *Generator) getFieldOptions(f *parser.FieldInfo) []string {
 				if strings.HasPrefix(part, "json_name=") {
 					jsonName := strings.TrimPrefix(part, "json_name=")
 					// Check if not already added
-					optStr := fmt.Sprintf("json_name = \"%s\"", jsonName)
 					if !g.containsOption(options, "json_name") {
-						options = append(options, optStr)
+						options = append(options, FieldOption{Name: "json_name", Value: fmt.Sprintf("\"%s\"", jsonName)})
 					}
 				} else if strings.HasPrefix(part, "packed=") {
-					if strings.TrimPrefix(part, "packed=") == "true" && !g.containsOption(options, "packed") {
-						options = append(options, "packed = true")
+					if packed := strings.TrimPrefix(part, "packed="); (packed == "true" || packed == "false") && !g.containsOption(options, "packed") {
+						options = append(options, FieldOption{Name: "packed", Value: packed})
 					}
 				} else if strings.HasPrefix(part, "deprecated=") {
 					if strings.TrimPrefix(part, "deprecated=") == "true" && !g.containsOption(options, "deprecated") {
-						options = append(options, "deprecated = true")
+						options = append(options, FieldOption{Name: "deprecated", Value: "true"})
 					}
 				}
 			}
@@ -1700,11 +1944,10 @@ func (g *Generator) getFieldOptions(f *parser.FieldInfo) []string {
 	return options
 }
 
-// containsOption checks if an option with the given name prefix already exists
-func (g *Generator) containsOption(options []string, optionName string) bool {
-	prefix := optionName + " ="
+// containsOption checks if an option with the given name already exists
+func (g *Generator) containsOption(options []FieldOption, optionName string) bool {
 	for _, opt := range options {
-		if strings.HasPrefix(opt, prefix) {
+		if opt.Name == optionName {
 			return true
 		}
 	}
@@ -1781,15 +2024,7 @@ func (g *Generator) getInterfaceDescription(interfaceInfo *parser.InterfaceInfo)
 func (g *Generator) generateEnums(out *strings.Builder) error {
 	for _, e := range g.ctx.Enums {
 		// Skip if marked as ignored
-		shouldSkip := false
-		for _, ann := range e.Annotations {
-			name := strings.ToLower(ann.Name)
-			if name == "ignore" || name == "skip" || strings.HasSuffix(name, ".ignore") || strings.HasSuffix(name, ".skip") {
-				shouldSkip = true
-				break
-			}
-		}
-		if shouldSkip {
+		if g.isEnumIgnored(e) {
 			continue
 		}
 
@@ -1800,6 +2035,17 @@ func (g *Generator) generateEnums(out *strings.Builder) error {
 	return nil
 }
 
+// isEnumIgnored checks for @ignore/@skip annotations on an enum
+func (g *Generator) isEnumIgnored(e *parser.EnumInfo) bool {
+	for _, ann := range e.Annotations {
+		name := strings.ToLower(ann.Name)
+		if name == "ignore" || name == "skip" || strings.HasSuffix(name, ".ignore") || strings.HasSuffix(name, ".skip") {
+			return true
+		}
+	}
+	return false
+}
+
 func (g *Generator) generateEnum(out *strings.Builder, e *parser.EnumInfo) error {
 	enumName := g.getEnumName(e)
 
@@ -1810,35 +2056,171 @@ func (g *Generator) generateEnum(out *strings.Builder, e *parser.EnumInfo) error
 
 	fmt.Fprintf(out, "enum %s {\n", enumName)
 
-	// Generate enum values
-	for i, v := range e.Values {
-		valueNum := i // Default to index
+	for _, opt := range append(g.getEnumOptions(e), g.getEnumFeatures(e)...) {
+		fmt.Fprintf(out, "  option %s = %s;\n", opt.Name, opt.Value)
+	}
 
-		// Check for custom number in annotation
-		for _, ann := range v.Annotations {
-			name := strings.ToLower(ann.Name)
-			if name == "enumvalue" || strings.HasSuffix(name, ".enumvalue") {
-				if numStr := ann.Params["number"]; numStr != "" {
-					_, _ = fmt.Sscanf(numStr, "%d", &valueNum) // Ignore error, keep default if parse fails
-				}
+	values, reservedNumbers, reservedNames := g.resolveEnumValues(e)
+
+	// Generate enum values
+	for _, ev := range values {
+		valueLine := fmt.Sprintf("  %s = %d;", ev.Name, ev.Number)
+		if options := g.getEnumValueOptions(ev.Value); len(options) > 0 {
+			formatted := make([]string, len(options))
+			for i, opt := range options {
+				formatted[i] = fmt.Sprintf("%s = %s", opt.Name, opt.Value)
 			}
+			valueLine = fmt.Sprintf("  %s = %d [%s];", ev.Name, ev.Number, strings.Join(formatted, ", "))
 		}
 
-		valueName := g.getEnumValueName(v, enumName)
-		valueLine := fmt.Sprintf("  %s = %d;", valueName, valueNum)
-
 		// Add comment
-		if desc := g.getEnumValueDescription(v); desc != "" {
+		if desc := g.getEnumValueDescription(ev.Value); desc != "" {
 			valueLine = fmt.Sprintf("  // %s\n%s", desc, valueLine)
 		}
 
 		out.WriteString(valueLine + "\n")
 	}
 
+	// Output reserved values (declared with @reserved or removed since the lockfile was written)
+	if len(reservedNumbers) > 0 {
+		fmt.Fprintf(out, "  reserved %s;\n", strings.Join(g.compactReservedRanges(reservedNumbers), ", "))
+	}
+	if len(reservedNames) > 0 {
+		quotedNames := make([]string, len(reservedNames))
+		for i, name := range reservedNames {
+			quotedNames[i] = fmt.Sprintf("\"%s\"", name)
+		}
+		fmt.Fprintf(out, "  reserved %s;\n", strings.Join(quotedNames, ", "))
+	}
+
 	out.WriteString("}\n\n")
 	return nil
 }
 
+// enumValue is an enum value resolved with its proto name and number
+type enumValue struct {
+	Value  *parser.EnumValue
+	Name   string
+	Number int
+}
+
+// resolveEnumValues resolves the values of an enum in declaration order,
+// along with the numbers and names that must be reserved
+func (g *Generator) resolveEnumValues(e *parser.EnumInfo) ([]enumValue, []int, []string) {
+	enumName := g.getEnumName(e)
+	declaredNumbers, declaredNames := g.getEnumReserved(e)
+
+	values := make([]enumValue, 0, len(e.Values))
+	for i, v := range e.Values {
+		values = append(values, enumValue{
+			Value:  v,
+			Name:   g.getEnumValueName(v, enumName),
+			Number: g.getEnumValueNumber(e, i, enumName),
+		})
+	}
+
+	if g.lock == nil {
+		return g.addUnspecifiedEnumValue(values, enumName), declaredNumbers, declaredNames
+	}
+
+	// Numbers taken by explicit annotations, kept from the lockfile or reserved
+	taken := make(map[int]bool)
+	for _, num := range declaredNumbers {
+		taken[num] = true
+	}
+	for i := range values {
+		if num, ok := g.getExplicitEnumValueNumber(values[i].Value); ok {
+			taken[num] = true
+		} else if num, ok := g.lock.EnumValueNumber(enumName, values[i].Name); ok {
+			taken[num] = true
+		}
+	}
+
+	present := make(map[string]int, len(values))
+	for i := range values {
+		ev := &values[i]
+		if num, ok := g.getExplicitEnumValueNumber(ev.Value); ok {
+			ev.Number = num
+		} else if num, ok := g.lock.EnumValueNumber(enumName, ev.Name); ok {
+			ev.Number = num
+		} else {
+			// New value: keep its index unless that number was ever used
+			for taken[ev.Number] || g.lock.IsEnumNumberUsed(enumName, ev.Number) {
+				ev.Number++
+			}
+			taken[ev.Number] = true
+		}
+		g.lock.RecordEnumValue(enumName, ev.Name, ev.Number)
+		present[ev.Name] = ev.Number
+	}
+
+	// The UNSPECIFIED value is added once the other values are numbered, and only
+	// when none of them kept 0 from the lockfile
+	values = g.addUnspecifiedEnumValue(values, enumName)
+	if len(values) > len(e.Values) {
+		unspecified := values[0]
+		g.lock.RecordEnumValue(enumName, unspecified.Name, unspecified.Number)
+		present[unspecified.Name] = unspecified.Number
+	}
+
+	reservedNumbers, reservedNames := g.lock.ReconcileEnum(enumName, present)
+	for _, name := range reservedNames {
+		if !slices.Contains(declaredNames, name) {
+			declaredNames = append(declaredNames, name)
+		}
+	}
+	return values, uniqueSortedInts(append(declaredNumbers, reservedNumbers...)), declaredNames
+}
+
+// getEnumValueNumber returns the number of the value of an enum at an index, defaulting to the
+// index plus the configured offset. A Go value named like the UNSPECIFIED value takes 0 and
+// isn't counted in the index of the values after it.
+func (g *Generator) getEnumValueNumber(e *parser.EnumInfo, index int, enumName string) int {
+	v := e.Values[index]
+	if num, ok := g.getExplicitEnumValueNumber(v); ok {
+		return num
+	}
+	if !g.formatGen.config.EnumStyle.UnspecifiedZeroValue {
+		return index + g.enumValueOffset()
+	}
+
+	if g.isUnspecifiedEnumValue(v, enumName) {
+		return 0
+	}
+	for _, previous := range e.Values[:index] {
+		if g.isUnspecifiedEnumValue(previous, enumName) {
+			index--
+			break
+		}
+	}
+	return index + g.enumValueOffset()
+}
+
+// isUnspecifiedEnumValue checks if a Go value is named like the UNSPECIFIED value of its enum
+// and takes 0
+func (g *Generator) isUnspecifiedEnumValue(v *parser.EnumValue, enumName string) bool {
+	if _, ok := g.getExplicitEnumValueNumber(v); ok {
+		return false
+	}
+	return g.getEnumValueName(v, enumName) == unspecifiedEnumValueName(enumName)
+}
+
+// getExplicitEnumValueNumber returns the number set via @proto.enumvalue, if any
+func (g *Generator) getExplicitEnumValueNumber(v *parser.EnumValue) (int, bool) {
+	for _, ann := range v.Annotations {
+		name := strings.ToLower(ann.Name)
+		if name == "enumvalue" || strings.HasSuffix(name, ".enumvalue") {
+			if numStr := ann.Params["number"]; numStr != "" {
+				var num int
+				if _, err := fmt.Sscanf(numStr, "%d", &num); err == nil {
+					return num, true
+				}
+			}
+		}
+	}
+	return 0, false
+}
+
 func (g *Generator) getEnumName(e *parser.EnumInfo) string {
 	for _, ann := range e.Annotations {
 		name := strings.ToLower(ann.Name)
@@ -1851,7 +2233,9 @@ func (g *Generator) getEnumName(e *parser.EnumInfo) string {
 	return e.Name
 }
 
-func (g *Generator) getEnumValueName(v *parser.EnumValue, _ string) string {
+// getEnumValueName returns the proto name of an enum value: the @enumvalue name as written,
+// or the SCREAMING_SNAKE Go name, prefixed with the enum name when prefix_values is set
+func (g *Generator) getEnumValueName(v *parser.EnumValue, enumName string) string {
 	for _, ann := range v.Annotations {
 		name := strings.ToLower(ann.Name)
 		if name == "enumvalue" || strings.HasSuffix(name, ".enumvalue") {
@@ -1861,7 +2245,7 @@ func (g *Generator) getEnumValueName(v *parser.EnumValue, _ string) string {
 		}
 	}
 	// Protobuf convention: SCREAMING_SNAKE value names
-	return goschemagen.TransformFieldName(v.Name, goschemagen.FieldCaseScreamingSnake)
+	return g.prefixEnumValueName(goschemagen.TransformFieldName(v.Name, goschemagen.FieldCaseScreamingSnake), enumName)
 }
 
 func (g *Generator) generateServices(out *strings.Builder) error {
@@ -1882,6 +2266,7 @@ func (g *Generator) generateServiceFromParsedData(out *strings.Builder, service
 	}
 
 	fmt.Fprintf(out, "service %s {\n", service.Name)
+	g.goPackage = service.PackagePath
 
 	// Generate RPC methods from pre-parsed data
 	for _, method := range service.Methods {
@@ -1895,8 +2280,10 @@ func (g *Generator) generateServiceFromParsedData(out *strings.Builder, service
 			outputStream = "stream "
 		}
 
+		g.useType(method.InputType)
+		g.useType(method.OutputType)
 		fmt.Fprintf(out, "  rpc %s(%s%s) returns (%s%s);\n",
-			method.Name, inputStream, method.InputType, outputStream, method.OutputType)
+			method.Name, inputStream, g.rpcTypeName(method.InputType), outputStream, g.rpcTypeName(method.OutputType))
 	}
 
 	out.WriteString("}\n\n")
@@ -2250,59 +2637,119 @@ func (g *Generator) isFieldInOneof(field *parser.FieldInfo, messageName string)
 	return false
 }
 
+// ProtoExtension represents a field added to another message via @proto.extend
+type ProtoExtension struct {
+	Extendee string
+	Name     string
+	Type     string
+	Number   int
+	Repeated bool
+	Comment  string
+	Original *parser.FieldInfo
+}
+
 // generateExtensions generates protobuf extensions from @proto.extend annotations
 func (g *Generator) generateExtensions(out *strings.Builder) error {
-	// Check for extension annotations in struct fields
+	for _, group := range groupExtensions(g.collectExtensions()) {
+		if err := g.generateExtension(out, group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// groupExtensions groups consecutive extensions of the same message into one extend block
+func groupExtensions(extensions []ProtoExtension) [][]ProtoExtension {
+	var groups [][]ProtoExtension
+	for _, ext := range extensions {
+		if n := len(groups); n > 0 && groups[n-1][0].Extendee == ext.Extendee {
+			groups[n-1] = append(groups[n-1], ext)
+			continue
+		}
+		groups = append(groups, []ProtoExtension{ext})
+	}
+	return groups
+}
+
+// collectExtensions resolves all @proto.extend annotations found on structs and struct fields
+func (g *Generator) collectExtensions() []ProtoExtension {
+	var extensions []ProtoExtension
+
 	for _, structInfo := range g.ctx.Structs {
+		// A struct-level @extend turns all the struct fields into extensions
+		if extendee, ok := getStructExtendee(structInfo); ok {
+			extensions = append(extensions, g.resolveStructExtensions(structInfo, extendee)...)
+			continue
+		}
+
+		// Check for extension annotations in struct fields
 		for _, field := range structInfo.Fields {
 			for _, ann := range field.Annotations {
 				name := strings.ToLower(ann.Name)
 				if name == "extend" || strings.HasSuffix(name, ".extend") {
-					if err := g.generateExtension(out, &ann, field); err != nil {
-						return err
+					if ext, ok := g.resolveExtension(&ann, field); ok {
+						extensions = append(extensions, ext)
 					}
 				}
 			}
 		}
 	}
-	return nil
+	return extensions
 }
 
-// generateExtension generates a single protobuf extension
-func (g *Generator) generateExtension(out *strings.Builder, ann *annotations.Annotation, field *parser.FieldInfo) error {
+// resolveExtension resolves a single @proto.extend annotation
+func (g *Generator) resolveExtension(ann *annotations.Annotation, field *parser.FieldInfo) (ProtoExtension, bool) {
 	// Get the target message to extend
 	targetMessage, exists := ann.GetParamValue("message")
 	if !exists {
 		// Skip if no target message specified
-		return nil
+		return ProtoExtension{}, false
+	}
+
+	ext := ProtoExtension{
+		Extendee: targetMessage,
+		Name:     field.Name, // Default to struct field name
+		Type:     g.getProtoType(field),
+		Number:   g.formatGen.config.StartFieldNumber,
+		Repeated: g.isRepeated(field),
+		Original: field,
 	}
 
 	// Get extension field number
-	fieldNumber := g.formatGen.config.StartFieldNumber
 	if numStr, exists := ann.GetParamValue("number"); exists {
 		if num, err := strconv.Atoi(numStr); err == nil && num > 0 {
-			fieldNumber = num
+			ext.Number = num
 		}
 	}
 
-	// Get field name (default to struct field name)
-	fieldName := field.Name
+	// Get field name
 	if name, exists := ann.GetParamValue("name"); exists {
-		fieldName = name
+		ext.Name = name
 	}
 
 	// Get field type
-	fieldType := g.getProtoType(field)
 	if typeOverride, exists := ann.GetParamValue("type"); exists {
-		fieldType = typeOverride
+		ext.Type = typeOverride
 	}
 
-	// Write extension
-	if desc, exists := ann.GetParamValue("description"); exists && desc != "" {
-		fmt.Fprintf(out, "// %s\n", desc)
+	if desc, exists := ann.GetParamValue("description"); exists {
+		ext.Comment = desc
+	}
+
+	return ext, true
+}
+
+// generateExtension generates an extend block for extensions of the same message
+func (g *Generator) generateExtension(out *strings.Builder, group []ProtoExtension) error {
+	g.useType(group[0].Extendee)
+	fmt.Fprintf(out, "extend %s {\n", group[0].Extendee)
+	for _, ext := range group {
+		g.useType(ext.Type)
+		if ext.Comment != "" {
+			fmt.Fprintf(out, "  // %s\n", ext.Comment)
+		}
+		fmt.Fprintf(out, "  %s%s %s = %d;\n", g.getExtensionLabel(ext), ext.Type, ext.Name, ext.Number)
 	}
-	fmt.Fprintf(out, "extend %s {\n", targetMessage)
-	fmt.Fprintf(out, "  %s %s = %d;\n", fieldType, fieldName, fieldNumber)
 	fmt.Fprintf(out, "}\n\n")
 
 	return nil
@@ -2318,7 +2765,10 @@ func (g *Generator) shouldSkipStruct(structInfo *parser.StructInfo) bool {
 			return true
 		}
 	}
-	return false
+
+	// Struct-level extensions add fields to another message instead of defining one
+	_, isExtension := getStructExtendee(structInfo)
+	return isExtension
 }
 
 // shouldSkipEnum checks if an enum should be skipped from generation
@@ -2390,13 +2840,16 @@ func (g *Generator) toSnakeCase(s string) string {
 	return strings.ToLower(result.String())
 }
 
-// parseRPCMethod parses a method from interface into ProtoRPCMethod
-func (g *Generator) parseRPCMethod(method *parser.MethodInfo) ProtoRPCMethod {
+// parseRPCMethod parses a method from interface into ProtoRPCMethod.
+// pkg and pkgPath are the package of the interface, where unqualified types are declared.
+func (g *Generator) parseRPCMethod(method *parser.MethodInfo, pkg, pkgPath string) ProtoRPCMethod {
 	// Get input/output types from function signature
 	inputType := "google.protobuf.Empty"
 	outputType := "google.protobuf.Empty"
 	originalInputType := ""
 	originalOutputType := ""
+	inputPackage := ""
+	outputPackage := ""
 
 	// Check if method has context.Context parameter
 	hasContext := false
@@ -2415,7 +2868,7 @@ func (g *Generator) parseRPCMethod(method *parser.MethodInfo) ProtoRPCMethod {
 		if paramType == "context.Context" || paramType == "*context.Context" {
 			continue
 		}
-		originalInputType = g.getQualifiedTypeName(paramType)
+		originalInputType, inputPackage = g.getQualifiedTypeName(paramType, pkg, pkgPath)
 		inputType = g.wrapPrimitiveType(paramType, method.Name, "Request")
 		break
 	}
@@ -2427,7 +2880,7 @@ func (g *Generator) parseRPCMethod(method *parser.MethodInfo) ProtoRPCMethod {
 		if resultType == "error" {
 			continue
 		}
-		originalOutputType = g.getQualifiedTypeName(resultType)
+		originalOutputType, outputPackage = g.getQualifiedTypeName(resultType, pkg, pkgPath)
 		outputType = g.wrapPrimitiveType(resultType, method.Name, "Response")
 		break
 	}
@@ -2479,12 +2932,14 @@ func (g *Generator) parseRPCMethod(method *parser.MethodInfo) ProtoRPCMethod {
 		}
 	}
 
-	return ProtoRPCMethod{
+	rpcMethod := ProtoRPCMethod{
 		Name:               method.Name,
 		InputType:          inputType,
 		OutputType:         outputType,
 		OriginalInputType:  originalInputType,
 		OriginalOutputType: originalOutputType,
+		InputPackage:       inputPackage,
+		OutputPackage:      outputPackage,
 		Comment:            g.getMethodDescription(method),
 		ClientStream:       clientStream,
 		ServerStream:       serverStream,
@@ -2492,6 +2947,17 @@ func (g *Generator) parseRPCMethod(method *parser.MethodInfo) ProtoRPCMethod {
 		HasContext:         hasContext,
 		Original:           method,
 	}
+
+	params := make([]ast.Expr, 0, len(method.Params))
+	for _, param := range method.Params {
+		params = append(params, param.Type)
+	}
+	results := make([]ast.Expr, 0, len(method.Results))
+	for _, result := range method.Results {
+		results = append(results, result.Type)
+	}
+	g.applyStreamForms(&rpcMethod, params, results, pkg, pkgPath)
+	return rpcMethod
 }
 
 // wrapPrimitiveType wraps primitive types in protobuf wrapper types or generates proper message names
@@ -2529,13 +2995,16 @@ func (g *Generator) wrapPrimitiveType(goType, methodName, suffix string) string
 	return goType
 }
 
-// parseRPCMethodFromFunction parses a method from function into ProtoRPCMethod
-func (g *Generator) parseRPCMethodFromFunction(fn *parser.FunctionInfo) ProtoRPCMethod {
+// parseRPCMethodFromFunction parses a method from function into ProtoRPCMethod.
+// pkg and pkgPath are the package of the receiver, where unqualified types are declared.
+func (g *Generator) parseRPCMethodFromFunction(fn *parser.FunctionInfo, pkg, pkgPath string) ProtoRPCMethod {
 	// Convert function to method-like structure
 	inputType := "google.protobuf.Empty"
 	outputType := "google.protobuf.Empty"
 	originalInputType := ""
 	originalOutputType := ""
+	inputPackage := ""
+	outputPackage := ""
 
 	// Check if function has context.Context parameter
 	hasContext := false
@@ -2554,7 +3023,7 @@ func (g *Generator) parseRPCMethodFromFunction(fn *parser.FunctionInfo) ProtoRPC
 		if paramType == "context.Context" || paramType == "*context.Context" {
 			continue
 		}
-		originalInputType = g.getQualifiedTypeName(paramType)
+		originalInputType, inputPackage = g.getQualifiedTypeName(paramType, pkg, pkgPath)
 		inputType = g.wrapPrimitiveType(paramType, fn.Name, "Request")
 		break
 	}
@@ -2566,7 +3035,7 @@ func (g *Generator) parseRPCMethodFromFunction(fn *parser.FunctionInfo) ProtoRPC
 		if resultType == "error" {
 			continue
 		}
-		originalOutputType = g.getQualifiedTypeName(resultType)
+		originalOutputType, outputPackage = g.getQualifiedTypeName(resultType, pkg, pkgPath)
 		outputType = g.wrapPrimitiveType(resultType, fn.Name, "Response")
 		break
 	}
@@ -2618,12 +3087,14 @@ func (g *Generator) parseRPCMethodFromFunction(fn *parser.FunctionInfo) ProtoRPC
 		}
 	}
 
-	return ProtoRPCMethod{
+	rpcMethod := ProtoRPCMethod{
 		Name:               fn.Name,
 		InputType:          inputType,
 		OutputType:         outputType,
 		OriginalInputType:  originalInputType,
 		OriginalOutputType: originalOutputType,
+		InputPackage:       inputPackage,
+		OutputPackage:      outputPackage,
 		Comment:            g.getFunctionDescription(fn),
 		ClientStream:       clientStream,
 		ServerStream:       serverStream,
@@ -2631,6 +3102,17 @@ func (g *Generator) parseRPCMethodFromFunction(fn *parser.FunctionInfo) ProtoRPC
 		HasContext:         hasContext,
 		Original:           fn,
 	}
+
+	params := make([]ast.Expr, 0, len(fn.Params))
+	for _, param := range fn.Params {
+		params = append(params, param.Type)
+	}
+	results := make([]ast.Expr, 0, len(fn.Results))
+	for _, result := range fn.Results {
+		results = append(results, result.Type)
+	}
+	g.applyStreamForms(&rpcMethod, params, results, pkg, pkgPath)
+	return rpcMethod
 }
 
 // hasServiceAnnotation checks if interface has service annotation
@@ -2728,3 +3210,13 @@ func (g *Generator) GetParsedMessages() []ProtoMessage {
 	}
 	return g.messages
 }
+
+// sortedKeys returns the keys of a map in order, so output built from maps is deterministic
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
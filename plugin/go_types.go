@@ -0,0 +1,222 @@
+package plugin
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+)
+
+// builtinType is the representation of a Go type that isn't parsed, from the standard library
+// or a common module, in protobuf and in the JSON Schema and TypeScript of its JSON encoding
+type builtinType struct {
+	proto      string                 // Protobuf type
+	jsonSchema map[string]interface{} // JSON Schema type
+	typeScript string                 // TypeScript type
+	path       string                 // Import path of the Go package declaring it
+
+	// Adapters convert the type with a type conversion to the Go type of the protobuf scalar,
+	// or without one with the <Name>ToProto and <Name>FromProto helpers of the types template
+	scalar    string
+	protoPath string // Import path of the Go type of the protobuf field, for the helpers
+	pointer   bool   // The helpers convert *T, the way the type is used, <Name>Value* helpers convert T
+}
+
+const (
+	wrappersGoPackage  = "google.golang.org/protobuf/types/known/wrapperspb"
+	timestampGoPackage = "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var (
+	jsonInteger = map[string]interface{}{"type": "integer"}
+	jsonBytes   = map[string]interface{}{"type": "string", "contentEncoding": "base64"}
+)
+
+// jsonNullable returns the JSON Schema of a JSON type that can also be null
+func jsonNullable(jsonType string) map[string]interface{} {
+	return map[string]interface{}{"type": []string{jsonType, "null"}}
+}
+
+// builtinTypes maps the Go types the schema is generated for without declaring them
+var builtinTypes = map[string]builtinType{
+	"int8":   {proto: "int32", jsonSchema: jsonInteger, typeScript: "number", scalar: "int32"},
+	"int16":  {proto: "int32", jsonSchema: jsonInteger, typeScript: "number", scalar: "int32"},
+	"rune":   {proto: "int32", jsonSchema: jsonInteger, typeScript: "number", scalar: "int32"},
+	"uint8":  {proto: "uint32", jsonSchema: jsonInteger, typeScript: "number", scalar: "uint32"},
+	"uint16": {proto: "uint32", jsonSchema: jsonInteger, typeScript: "number", scalar: "uint32"},
+	"byte":   {proto: "uint32", jsonSchema: jsonInteger, typeScript: "number", scalar: "uint32"},
+
+	"json.RawMessage": {proto: "bytes", jsonSchema: jsonBytes, typeScript: "string", path: "encoding/json", scalar: "[]byte"},
+	"net.IP":          {proto: "bytes", jsonSchema: jsonBytes, typeScript: "string", path: "net", scalar: "[]byte"},
+
+	"url.URL": {
+		proto:      "string",
+		jsonSchema: map[string]interface{}{"type": "string", "format": "uri"},
+		typeScript: "string",
+		path:       "net/url",
+		pointer:    true,
+	},
+	"big.Int": {
+		proto:      "string",
+		jsonSchema: map[string]interface{}{"type": "string", "pattern": "^-?[0-9]+$"},
+		typeScript: "string",
+		path:       "math/big",
+		pointer:    true,
+	},
+	"uuid.UUID": {
+		proto:      "string",
+		jsonSchema: map[string]interface{}{"type": "string", "format": "uuid"},
+		typeScript: "string",
+		path:       "github.com/google/uuid",
+	},
+
+	// Nullable database types are wrapped, absent when they aren't valid
+	"sql.NullString":  {proto: "google.protobuf.StringValue", jsonSchema: jsonNullable("string"), typeScript: "string | null", path: "database/sql", protoPath: wrappersGoPackage},
+	"sql.NullInt64":   {proto: "google.protobuf.Int64Value", jsonSchema: jsonNullable("integer"), typeScript: "number | null", path: "database/sql", protoPath: wrappersGoPackage},
+	"sql.NullInt32":   {proto: "google.protobuf.Int32Value", jsonSchema: jsonNullable("integer"), typeScript: "number | null", path: "database/sql", protoPath: wrappersGoPackage},
+	"sql.NullInt16":   {proto: "google.protobuf.Int32Value", jsonSchema: jsonNullable("integer"), typeScript: "number | null", path: "database/sql", protoPath: wrappersGoPackage},
+	"sql.NullByte":    {proto: "google.protobuf.UInt32Value", jsonSchema: jsonNullable("integer"), typeScript: "number | null", path: "database/sql", protoPath: wrappersGoPackage},
+	"sql.NullFloat64": {proto: "google.protobuf.DoubleValue", jsonSchema: jsonNullable("number"), typeScript: "number | null", path: "database/sql", protoPath: wrappersGoPackage},
+	"sql.NullBool":    {proto: "google.protobuf.BoolValue", jsonSchema: jsonNullable("boolean"), typeScript: "boolean | null", path: "database/sql", protoPath: wrappersGoPackage},
+	"sql.NullTime": {
+		proto:      "google.protobuf.Timestamp",
+		jsonSchema: map[string]interface{}{"type": []string{"string", "null"}, "format": "date-time"},
+		typeScript: "string | null",
+		path:       "database/sql",
+		protoPath:  timestampGoPackage,
+	},
+}
+
+// builtinType returns the representation of a Go type from builtinTypes, unless type_mappings
+// or a custom mapping map it otherwise
+func (g *Generator) builtinType(goType string) (builtinType, bool) {
+	if _, ok := g.customTypeMapping(goType); ok {
+		return builtinType{}, false
+	}
+	builtin, ok := builtinTypes[goType]
+	return builtin, ok
+}
+
+// isByteArray checks if a type is a byte slice or a fixed-size byte array ([16]byte), both
+// declared as bytes
+func isByteArray(t *ast.ArrayType) bool {
+	elt, ok := t.Elt.(*ast.Ident)
+	return ok && (elt.Name == "byte" || elt.Name == "uint8")
+}
+
+// fixedByteArrayLen returns the length of a fixed-size byte array type
+func fixedByteArrayLen(t ast.Expr) (string, bool) {
+	array, ok := t.(*ast.ArrayType)
+	if !ok || !isByteArray(array) {
+		return "", false
+	}
+	length, ok := array.Len.(*ast.BasicLit)
+	if !ok {
+		return "", false
+	}
+	return length.Value, true
+}
+
+// builtinHelpers returns the builtin type a field of T or *T converts with the helpers of the
+// types template
+func (g *StubGenerator) builtinHelpers(fieldType string) (string, builtinType, bool) {
+	goType := strings.TrimPrefix(fieldType, "*")
+	builtin, ok := g.mainGenerator.builtinType(goType)
+	if !ok || builtin.scalar != "" {
+		return "", builtinType{}, false
+	}
+	return goType, builtin, true
+}
+
+// builtinValueHelper returns the helper converting a value of a builtin type in a direction:
+// <Name>ToProto or <Name>FromProto, <Name>ValueToProto or <Name>ValueFromProto for the types
+// whose helpers convert *T
+func (g *StubGenerator) builtinValueHelper(goType string, builtin builtinType, direction string) string {
+	if builtin.pointer {
+		return g.sanitizeTypeName(goType) + "Value" + direction
+	}
+	return g.sanitizeTypeName(goType) + direction
+}
+
+// builtinConversion returns the conversion of a field of a builtin type in a direction (ToProto
+// or FromProto). Types converted with a type conversion are handled like named primitives.
+// Pointers to scalars are optional fields, nil when unset, like pointers to wrapper messages.
+func (g *StubGenerator) builtinConversion(fieldType, direction, value string) (string, bool) {
+	// Fixed-size byte arrays ([16]byte) are copied, the protobuf value may have another length
+	if length, ok := strings.CutSuffix(strings.TrimPrefix(fieldType, "["), "]byte"); ok && length != "" && strings.HasPrefix(fieldType, "[") {
+		if direction == "ToProto" {
+			return value + "[:]", true
+		}
+		return fmt.Sprintf("func() (a [%s]byte) { copy(a[:], %s); return a }()", length, value), true
+	}
+	goType, builtin, ok := g.builtinHelpers(fieldType)
+	if !ok {
+		return "", false
+	}
+	helper := g.builtinValueHelper(goType, builtin, direction)
+	if !strings.HasPrefix(fieldType, "*") {
+		return fmt.Sprintf("%s(%s)", helper, value), true
+	}
+	if _, scalar := protoScalarGoTypes[builtin.proto]; scalar {
+		return fmt.Sprintf("builtinOptional%s(%s, %s)", direction, value, helper), true
+	}
+	return fmt.Sprintf("builtinPointer%s(%s, %s)", direction, value, helper), true
+}
+
+// builtinElementType returns the type of the values of a field converted one by one: the
+// elements of slices and the values of maps
+func (g *StubGenerator) builtinElementType(fieldType string) string {
+	if values := g.parseMapType(fieldType); values != nil {
+		fieldType = values[1]
+	}
+	return strings.TrimPrefix(fieldType, "[]")
+}
+
+// collectBuiltinTypes returns the alias of the package of each builtin or time type converted
+// with the helpers of the types template, by Go type
+func (g *StubGenerator) collectBuiltinTypes() map[string]string {
+	types := make(map[string]string)
+	for _, typeInfo := range g.originalTypes {
+		for _, field := range typeInfo.Fields {
+			if goType, builtin, ok := g.builtinHelpers(g.builtinElementType(field.Type)); ok {
+				types[goType] = g.getPackageAlias(builtin.path)
+			}
+		}
+	}
+	for goType := range g.usedTimeTypes() {
+		types[goType] = g.getPackageAlias("time")
+	}
+	return types
+}
+
+// builtinTypeImports returns the packages the conversions of builtin types reference
+func (g *StubGenerator) builtinTypeImports() []string {
+	var paths []string
+	for _, typeInfo := range g.originalTypes {
+		for _, field := range typeInfo.Fields {
+			elementType := g.builtinElementType(field.Type)
+			if _, builtin, ok := g.builtinHelpers(elementType); ok {
+				paths = append(paths, builtin.path, builtin.protoPath)
+			} else if builtin, ok := g.mainGenerator.builtinType(elementType); ok && builtin.scalar != "" {
+				paths = append(paths, builtin.path)
+			}
+		}
+	}
+	return paths
+}
+
+// builtinPackagePaths returns the packages of the builtin types used by the parsed structs,
+// which get an alias like the packages of the parsed types
+func (g *StubGenerator) builtinPackagePaths() []string {
+	paths := make(map[string]bool)
+	for _, s := range g.ctx.Structs {
+		for _, f := range s.Fields {
+			goType := strings.TrimLeft(g.getGoTypeName(f.Type), "*[]")
+			if builtin, ok := g.mainGenerator.builtinType(goType); ok && builtin.path != "" {
+				paths[builtin.path] = true
+			} else if _, ok := g.mainGenerator.timeType(goType); ok {
+				paths["time"] = true
+			}
+		}
+	}
+	return sortedKeys(paths)
+}
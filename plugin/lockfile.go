@@ -0,0 +1,359 @@
+package plugin
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// LockFileVersion is the current version of the lockfile format
+const LockFileVersion = 1
+
+// LockFile records the numbers assigned to message fields and enum values so that
+// auto-numbering stays stable when Go fields are reordered, added or removed
+type LockFile struct {
+	Version  int                     `json:"version"`
+	Messages map[string]*MessageLock `json:"messages"`
+	Enums    map[string]*EnumLock    `json:"enums"`
+
+	path string // Resolved path the lockfile is read from and written to
+}
+
+// MessageLock records the field numbers of a message
+type MessageLock struct {
+	Fields        map[string]int `json:"fields"`                   // proto field name -> number
+	Reserved      []int          `json:"reserved,omitempty"`       // numbers of removed fields
+	ReservedNames []string       `json:"reserved_names,omitempty"` // names of removed fields
+
+	moved []int // old numbers of the fields renumbered in this run
+}
+
+// EnumLock records the value numbers of an enum
+type EnumLock struct {
+	Values        map[string]int `json:"values"`                   // proto value name -> number
+	Reserved      []int          `json:"reserved,omitempty"`       // numbers of removed values
+	ReservedNames []string       `json:"reserved_names,omitempty"` // names of removed values
+
+	moved []int // old numbers of the values renumbered in this run
+}
+
+// NewLockFile creates an empty lockfile
+func NewLockFile() *LockFile {
+	return &LockFile{
+		Version:  LockFileVersion,
+		Messages: make(map[string]*MessageLock),
+		Enums:    make(map[string]*EnumLock),
+	}
+}
+
+// LoadLockFile reads a lockfile, returning an empty one if it doesn't exist yet
+func LoadLockFile(path string) (*LockFile, error) {
+	lock := NewLockFile()
+	lock.path = path
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return lock, nil
+		}
+		return nil, fmt.Errorf("failed to read lockfile %s: %w", path, err)
+	}
+
+	if err := json.Unmarshal(data, lock); err != nil {
+		return nil, fmt.Errorf("failed to parse lockfile %s: %w", path, err)
+	}
+	if lock.Version > LockFileVersion {
+		return nil, fmt.Errorf("lockfile %s has unsupported version %d", path, lock.Version)
+	}
+	lock.Version = LockFileVersion
+	if lock.Messages == nil {
+		lock.Messages = make(map[string]*MessageLock)
+	}
+	if lock.Enums == nil {
+		lock.Enums = make(map[string]*EnumLock)
+	}
+	return lock, nil
+}
+
+// Save writes the lockfile back to the path it was loaded from
+func (l *LockFile) Save() error {
+	if l.path == "" {
+		return fmt.Errorf("lockfile has no path")
+	}
+
+	data, err := json.MarshalIndent(l, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode lockfile: %w", err)
+	}
+	data = append(data, '\n')
+
+	if dir := filepath.Dir(l.path); dir != "" {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create lockfile directory %s: %w", dir, err)
+		}
+	}
+	if err := os.WriteFile(l.path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write lockfile %s: %w", l.path, err)
+	}
+	return nil
+}
+
+func (l *LockFile) message(name string) *MessageLock {
+	m := l.Messages[name]
+	if m == nil {
+		m = &MessageLock{Fields: make(map[string]int)}
+		l.Messages[name] = m
+	}
+	if m.Fields == nil {
+		m.Fields = make(map[string]int)
+	}
+	return m
+}
+
+func (l *LockFile) enum(name string) *EnumLock {
+	e := l.Enums[name]
+	if e == nil {
+		e = &EnumLock{Values: make(map[string]int)}
+		l.Enums[name] = e
+	}
+	if e.Values == nil {
+		e.Values = make(map[string]int)
+	}
+	return e
+}
+
+// FieldNumber returns the number recorded for a field
+func (l *LockFile) FieldNumber(messageName, fieldName string) (int, bool) {
+	if m := l.Messages[messageName]; m != nil {
+		num, ok := m.Fields[fieldName]
+		return num, ok
+	}
+	return 0, false
+}
+
+// RecordField records the number of a field. If the field was locked to a different
+// number, the old number is reserved by ReconcileMessage since it may still be used by
+// deployed clients, unless another field uses it by then.
+func (l *LockFile) RecordField(messageName, fieldName string, number int) {
+	m := l.message(messageName)
+	m.moved = recordLocked(m.Fields, m.moved, fieldName, number)
+}
+
+// NextFieldNumber returns the first number from counter on that was never used in the message,
+// skipping the given explicit numbers. The counter is advanced past the returned number.
+func (l *LockFile) NextFieldNumber(messageName string, counter *int, explicit map[int]bool) int {
+	used := make(map[int]bool)
+	if m := l.Messages[messageName]; m != nil {
+		for _, num := range m.Fields {
+			used[num] = true
+		}
+		for _, num := range m.Reserved {
+			used[num] = true
+		}
+		for _, num := range m.moved {
+			used[num] = true
+		}
+	}
+
+	num := *counter
+	for used[num] || explicit[num] || (num >= 19000 && num <= 19999) {
+		num++
+	}
+	*counter = num + 1
+	return num
+}
+
+// ReconcileMessage reserves the locked fields that are no longer present in the message
+// (present maps field names to numbers) and returns every number reserved for it.
+// A renamed field that kept its number only gets its old name reserved.
+func (l *LockFile) ReconcileMessage(messageName string, present map[string]int) []int {
+	m := l.message(messageName)
+	m.Reserved, m.ReservedNames = reconcileLocked(m.Fields, m.Reserved, m.ReservedNames, m.moved, present)
+	m.moved = nil
+	return append([]int(nil), m.Reserved...)
+}
+
+// recordLocked locks a name to a number and returns the moved numbers, with the old number of
+// the name if it changed. Moved numbers are only reserved once the run is reconciled, so names
+// can swap their numbers; numbers reserved before are never released.
+func recordLocked(locked map[string]int, moved []int, name string, number int) []int {
+	if old, ok := locked[name]; ok && old != number {
+		moved = appendUniqueInt(moved, old)
+	}
+	locked[name] = number
+	return moved
+}
+
+// reconcileLocked moves the locked entries missing from present to the reserved lists, and
+// reserves the moved numbers that no present entry uses
+func reconcileLocked(locked map[string]int, reserved []int, reservedNames []string, moved []int, present map[string]int) ([]int, []string) {
+	inUse := make(map[int]bool, len(present))
+	for _, num := range present {
+		inUse[num] = true
+	}
+	for _, num := range moved {
+		if !inUse[num] {
+			reserved = appendUniqueInt(reserved, num)
+		}
+	}
+
+	for _, name := range sortedKeys(locked) {
+		if _, ok := present[name]; ok {
+			continue
+		}
+		if !inUse[locked[name]] {
+			reserved = appendUniqueInt(reserved, locked[name])
+		}
+		reservedNames = appendUniqueString(reservedNames, name)
+		delete(locked, name)
+	}
+
+	// A name that came back is in use again and can't stay reserved
+	var names []string
+	for _, name := range reservedNames {
+		if _, ok := present[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	return reserved, names
+}
+
+// ReservedFieldNames returns the names of the removed fields of a message
+func (l *LockFile) ReservedFieldNames(messageName string) []string {
+	if m := l.Messages[messageName]; m != nil {
+		return m.ReservedNames
+	}
+	return nil
+}
+
+// IsReservedFieldNumber checks if a number belonged to a removed field of the message
+func (l *LockFile) IsReservedFieldNumber(messageName string, number int) bool {
+	if m := l.Messages[messageName]; m != nil {
+		for _, num := range m.Reserved {
+			if num == number {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// EnumValueNumber returns the number recorded for an enum value
+func (l *LockFile) EnumValueNumber(enumName, valueName string) (int, bool) {
+	if e := l.Enums[enumName]; e != nil {
+		num, ok := e.Values[valueName]
+		return num, ok
+	}
+	return 0, false
+}
+
+// RecordEnumValue records the number of an enum value, reserving its old number like RecordField
+func (l *LockFile) RecordEnumValue(enumName, valueName string, number int) {
+	e := l.enum(enumName)
+	e.moved = recordLocked(e.Values, e.moved, valueName, number)
+}
+
+// IsReservedEnumNumber checks if a number belonged to a removed value of the enum
+func (l *LockFile) IsReservedEnumNumber(enumName string, number int) bool {
+	if e := l.Enums[enumName]; e != nil {
+		for _, num := range e.Reserved {
+			if num == number {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsEnumNumberUsed checks if a number is taken by a locked, renumbered or removed value of the enum
+func (l *LockFile) IsEnumNumberUsed(enumName string, number int) bool {
+	if e := l.Enums[enumName]; e != nil {
+		for _, num := range e.Values {
+			if num == number {
+				return true
+			}
+		}
+		for _, num := range e.Reserved {
+			if num == number {
+				return true
+			}
+		}
+		for _, num := range e.moved {
+			if num == number {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReconcileEnum reserves the locked values that are no longer present in the enum
+// (present maps value names to numbers) and returns the reserved numbers and names
+func (l *LockFile) ReconcileEnum(enumName string, present map[string]int) ([]int, []string) {
+	e := l.enum(enumName)
+	e.Reserved, e.ReservedNames = reconcileLocked(e.Values, e.Reserved, e.ReservedNames, e.moved, present)
+	e.moved = nil
+	return append([]int(nil), e.Reserved...), append([]string(nil), e.ReservedNames...)
+}
+
+// resolveLockFilePath resolves the configured lockfile path relative to the config directory
+func (g *Generator) resolveLockFilePath() string {
+	path := g.formatGen.config.LockFile
+	if path == "" {
+		return ""
+	}
+	if !filepath.IsAbs(path) && g.ctx.CoreConfig != nil && g.ctx.CoreConfig.ConfigDir != "" {
+		path = filepath.Join(g.ctx.CoreConfig.ConfigDir, path)
+	}
+	return path
+}
+
+// loadLockFile loads the configured lockfile, if any
+func (g *Generator) loadLockFile() error {
+	path := g.resolveLockFilePath()
+	if path == "" {
+		return nil
+	}
+
+	lock, err := LoadLockFile(path)
+	if err != nil {
+		return err
+	}
+	g.lock = lock
+	return nil
+}
+
+// uniqueSortedInts sorts numbers and removes duplicates
+func uniqueSortedInts(numbers []int) []int {
+	sort.Ints(numbers)
+	var result []int
+	for i, n := range numbers {
+		if i == 0 || n != numbers[i-1] {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+func appendUniqueInt(values []int, value int) []int {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	values = append(values, value)
+	sort.Ints(values)
+	return values
+}
+
+func appendUniqueString(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(values, value)
+}
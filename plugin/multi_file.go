@@ -9,10 +9,21 @@ import (
 	"github.com/pablor21/goschemagen"
 )
 
+// GenStrategyMultiple generates one proto file per message, enum and service
+const GenStrategyMultiple parser.GenStrategy = "multiple"
+
 // GenerateMulti generates multiple proto files based on generation strategy
 func (g *Generator) GenerateMulti() (*parser.GeneratedOutput, error) {
 	strategy := g.formatGen.config.GenerationStrategy
 
+	// Load the field number lockfile before any number is assigned
+	if err := g.loadLockFile(); err != nil {
+		return nil, err
+	}
+
+	// Resolve named types and string-backed enums through go/types
+	g.loadTypeInfo()
+
 	var output *parser.GeneratedOutput
 	var err error
 
@@ -25,6 +36,8 @@ func (g *Generator) GenerateMulti() (*parser.GeneratedOutput, error) {
 		output, err = g.generatePackage()
 	case parser.GenStrategyNamespace:
 		output, err = g.generateNamespace()
+	case GenStrategyMultiple:
+		output, err = g.generateMultiple()
 	default:
 		output, err = g.generateSingle()
 	}
@@ -33,6 +46,21 @@ func (g *Generator) GenerateMulti() (*parser.GeneratedOutput, error) {
 		return nil, err
 	}
 
+	// Compare against the baseline schema before anything is persisted
+	if err := g.checkBreakingChanges(); err != nil {
+		return nil, err
+	}
+	if g.formatGen.breakingOnly {
+		return &parser.GeneratedOutput{}, nil
+	}
+
+	// Persist the numbers assigned in this run
+	if g.lock != nil {
+		if err := g.lock.Save(); err != nil {
+			return nil, err
+		}
+	}
+
 	// Generate additional output formats if specified
 	if err := g.generateAdditionalFormats(output); err != nil {
 		g.ctx.Logger.Info(fmt.Sprintf("Failed to generate additional formats: %v", err))
@@ -46,10 +74,9 @@ func (g *Generator) GenerateMulti() (*parser.GeneratedOutput, error) {
 		} else {
 			// Generate only the adapter files, skip protoc (will be done later)
 			if err := stubGen.generateAdapterFilesOnly(); err != nil {
-				g.ctx.Logger.Info(fmt.Sprintf("Failed to generate stubs: %v", err))
-			} else {
-				g.ctx.Logger.Info("Generated type-preserving stubs successfully")
+				return nil, fmt.Errorf("failed to generate stubs: %w", err)
 			}
+			g.ctx.Logger.Info("Generated type-preserving stubs successfully")
 		}
 	}
 
@@ -216,12 +243,13 @@ func (g *Generator) generateFollow() (*parser.GeneratedOutput, error) {
 
 	// First pass: populate file type mappings
 	for sourceFile, group := range fileGroups {
+		// Use the base name of the source file without extension
 		baseName := filepath.Base(sourceFile)
 		baseName = strings.TrimSuffix(baseName, filepath.Ext(baseName))
-		filename := g.resolveFileName(baseName, baseName)
+		group.fileName = g.resolveFileName(baseName, baseName)
 
 		// Add file mapping to context
-		mapping := g.ctx.AddFileTypeMapping(filename)
+		mapping := g.ctx.AddFileTypeMapping(group.fileName)
 
 		// Add types to mapping
 		for _, s := range group.structs {
@@ -235,17 +263,15 @@ func (g *Generator) generateFollow() (*parser.GeneratedOutput, error) {
 		}
 	}
 
+	if err := g.loadFileGraph(fileGroups); err != nil {
+		return nil, err
+	}
+
 	// Second pass: generate files with proper imports
 	var files []*parser.GeneratedFile
 
-	for sourceFile, group := range fileGroups {
-		// Use the base name of the source file without extension
-		baseName := filepath.Base(sourceFile)
-		baseName = strings.TrimSuffix(baseName, filepath.Ext(baseName))
-		filename := g.resolveFileName(baseName, baseName)
-
-		// Set current file context for imports
-		g.currentFile = filename
+	for _, sourceFile := range sortedKeys(fileGroups) {
+		group := fileGroups[sourceFile]
 
 		content, err := g.generateForGroup(group)
 		if err != nil {
@@ -257,7 +283,7 @@ func (g *Generator) generateFollow() (*parser.GeneratedOutput, error) {
 		}
 
 		files = append(files, &parser.GeneratedFile{
-			Path:    filename,
+			Path:    group.fileName,
 			Content: content,
 			Metadata: map[string]any{
 				"source_file": sourceFile,
@@ -330,10 +356,23 @@ func (g *Generator) generatePackage() (*parser.GeneratedOutput, error) {
 		group.interfaces = append(group.interfaces, i)
 	}
 
+	for pkgName, group := range packageGroups {
+		// Use the last part of the package path as the filename
+		parts := strings.Split(pkgName, "/")
+		baseName := parts[len(parts)-1]
+		group.fileName = g.resolveFileName(baseName, baseName)
+	}
+
+	if err := g.loadFileGraph(packageGroups); err != nil {
+		return nil, err
+	}
+
 	// Generate a file for each package
 	var files []*parser.GeneratedFile
 
-	for pkgName, group := range packageGroups {
+	for _, pkgName := range sortedKeys(packageGroups) {
+		group := packageGroups[pkgName]
+
 		content, err := g.generateForGroup(group)
 		if err != nil {
 			return nil, fmt.Errorf("error generating proto for package %s: %w", pkgName, err)
@@ -343,14 +382,8 @@ func (g *Generator) generatePackage() (*parser.GeneratedOutput, error) {
 			continue
 		}
 
-		// Use the last part of the package path as the filename
-		parts := strings.Split(pkgName, "/")
-		baseName := parts[len(parts)-1]
-
-		filename := g.resolveFileName(baseName, baseName)
-
 		files = append(files, &parser.GeneratedFile{
-			Path:    filename,
+			Path:    group.fileName,
 			Content: content,
 			Metadata: map[string]any{
 				"package": pkgName,
@@ -427,10 +460,20 @@ func (g *Generator) generateNamespace() (*parser.GeneratedOutput, error) {
 		group.interfaces = append(group.interfaces, i)
 	}
 
+	for namespaceName, group := range namespaceGroups {
+		group.fileName = g.resolveFileName(namespaceName, namespaceName)
+	}
+
+	if err := g.loadFileGraph(namespaceGroups); err != nil {
+		return nil, err
+	}
+
 	// Generate a file for each namespace
 	var files []*parser.GeneratedFile
 
-	for namespaceName, group := range namespaceGroups {
+	for _, namespaceName := range sortedKeys(namespaceGroups) {
+		group := namespaceGroups[namespaceName]
+
 		content, err := g.generateForGroup(group)
 		if err != nil {
 			return nil, fmt.Errorf("error generating proto for namespace %s: %w", namespaceName, err)
@@ -440,10 +483,8 @@ func (g *Generator) generateNamespace() (*parser.GeneratedOutput, error) {
 			continue
 		}
 
-		filename := g.resolveFileName(namespaceName, namespaceName)
-
 		files = append(files, &parser.GeneratedFile{
-			Path:    filename,
+			Path:    group.fileName,
 			Content: content,
 			Metadata: map[string]any{
 				"namespace": namespaceName,
@@ -457,24 +498,161 @@ func (g *Generator) generateNamespace() (*parser.GeneratedOutput, error) {
 	}, nil
 }
 
+// generateMultiple generates one proto file per message, enum and service. Messages declared
+// with nested_in are written in the file of their outermost parent.
+func (g *Generator) generateMultiple() (*parser.GeneratedOutput, error) {
+	typeGroups := make(map[string]*fileGroup)
+	groupFor := func(typeName string) *fileGroup {
+		group := typeGroups[typeName]
+		if group == nil {
+			group = &fileGroup{
+				typeName:   typeName,
+				structs:    []*parser.StructInfo{},
+				enums:      []*parser.EnumInfo{},
+				interfaces: []*parser.InterfaceInfo{},
+			}
+			typeGroups[typeName] = group
+		}
+		return group
+	}
+
+	for _, s := range g.ctx.Structs {
+		owner := s
+		if g.getNestedIn(s) != "" {
+			owner = g.outermostParent(s)
+		}
+		if owner == nil || !g.declaresFileType(owner) {
+			continue
+		}
+		group := groupFor(owner.Name)
+		group.structs = append(group.structs, s)
+	}
+
+	for _, e := range g.ctx.Enums {
+		if !g.isEnumIgnored(e) {
+			group := groupFor(e.Name)
+			group.enums = append(group.enums, e)
+		}
+	}
+
+	for _, i := range g.ctx.Interfaces {
+		if g.formatGen.config.GenerateService && g.hasServiceAnnotation(i) {
+			group := groupFor(i.Name)
+			group.interfaces = append(group.interfaces, i)
+		}
+	}
+
+	fileTypes := make(map[string]string)
+	for _, typeName := range sortedKeys(typeGroups) {
+		group := typeGroups[typeName]
+		baseName := g.toSnakeCase(typeName)
+		group.fileName = g.resolveFileName(baseName, baseName)
+
+		if other, exists := fileTypes[group.fileName]; exists {
+			return nil, fmt.Errorf("types %s and %s are both generated in %s", other, typeName, group.fileName)
+		}
+		fileTypes[group.fileName] = typeName
+	}
+
+	if err := g.loadFileGraph(typeGroups); err != nil {
+		return nil, err
+	}
+
+	// Generate a file for each type
+	var files []*parser.GeneratedFile
+
+	for _, typeName := range sortedKeys(typeGroups) {
+		group := typeGroups[typeName]
+
+		content, err := g.generateForGroup(group)
+		if err != nil {
+			return nil, fmt.Errorf("error generating proto for type %s: %w", typeName, err)
+		}
+
+		if len(content) == 0 {
+			continue
+		}
+
+		files = append(files, &parser.GeneratedFile{
+			Path:    group.fileName,
+			Content: content,
+			Metadata: map[string]any{
+				"type": typeName,
+			},
+		})
+	}
+
+	return &parser.GeneratedOutput{
+		Files:        files,
+		IsSingleFile: false,
+	}, nil
+}
+
+// declaresFileType checks if a struct declares something in its own file with the multiple
+// strategy: a message, a service or an extension
+func (g *Generator) declaresFileType(s *parser.StructInfo) bool {
+	if g.hasServiceAnnotationForStruct(s) {
+		return g.formatGen.config.GenerateService
+	}
+	if _, isExtension := getStructExtendee(s); isExtension {
+		return !g.shouldSkipType(s)
+	}
+	return len(g.resolveMessageNames(s)) > 0
+}
+
+// outermostParent returns the struct of the top-level message a nested_in struct is declared in
+func (g *Generator) outermostParent(s *parser.StructInfo) *parser.StructInfo {
+	root, _, _ := strings.Cut(g.qualifiedNestedName(s), ".")
+	for _, candidate := range g.ctx.Structs {
+		if g.getNestedIn(candidate) == "" && g.getMessageName(candidate, "") == root {
+			return candidate
+		}
+	}
+	return nil
+}
+
 // fileGroup represents a group of types to be generated together
 type fileGroup struct {
 	sourceFile  string
 	packageName string
 	namespace   string
+	typeName    string // Type of the file with the multiple strategy
+	fileName    string // Generated proto file
 	structs     []*parser.StructInfo
 	enums       []*parser.EnumInfo
 	interfaces  []*parser.InterfaceInfo
 }
 
+// loadFileGraph builds the imports between the files of the groups, see buildFileGraph
+func (g *Generator) loadFileGraph(groups map[string]*fileGroup) error {
+	ordered := make([]*fileGroup, 0, len(groups))
+	for _, key := range sortedKeys(groups) {
+		ordered = append(ordered, groups[key])
+	}
+
+	graph, err := g.buildFileGraph(ordered)
+	if err != nil {
+		return err
+	}
+	g.files = graph
+	return nil
+}
+
 // generateForGroup generates protobuf schema for a specific group of types
 func (g *Generator) generateForGroup(group *fileGroup) ([]byte, error) {
+	// Generate the schema
+	return g.groupGenerator(group).Generate()
+}
+
+// groupGenerator returns a generator restricted to the types of a group
+func (g *Generator) groupGenerator(group *fileGroup) *Generator {
 	// Create a temporary context with only the types in this group
 	tempCtx := &goschemagen.GenerationContext{
 		GenerationContext: parser.GenerationContext{
 			Structs:          group.structs,
 			Enums:            group.enums,
 			Interfaces:       group.interfaces,
+			AllStructs:       g.ctx.AllStructs,   // Include generic definitions for instantiations
 			Functions:        g.ctx.Functions,    // Include functions for struct services
 			AllFunctions:     g.ctx.AllFunctions, // Include all functions for reference
 			CoreConfig:       g.ctx.CoreConfig,
@@ -489,11 +667,23 @@ func (g *Generator) generateForGroup(group *fileGroup) ([]byte, error) {
 		ctx:           tempCtx,
 		fieldNumbers:  make(map[string]int),
 		currentNumber: g.formatGen.config.StartFieldNumber,
-		currentFile:   g.currentFile, // Pass current file context
+		currentFile:   group.fileName,  // Pass current file context
+		namespace:     group.namespace, // Namespace files get their own package
+		lock:          g.lock,          // Share the lockfile across files
+		types:         g.types,         // Share the type information too
+		files:         g.files,         // And the files other types are declared in
+		all:           g.ctx,           // Unions and their variants may be in other files
 	}
+	return tempGen
+}
 
-	// Generate the schema
-	return tempGen.Generate()
+// lookupContext returns the context types are looked up in: every parsed type, also from the
+// generators restricted to a file
+func (g *Generator) lookupContext() *goschemagen.GenerationContext {
+	if g.all != nil {
+		return g.all
+	}
+	return g.ctx
 }
 
 // resolveFileName resolves the output filename from the pattern
@@ -510,6 +700,7 @@ func (g *Generator) resolveFileName(schemaName, name string) string {
 	// Replace placeholders
 	result := strings.ReplaceAll(pattern, "{schema_name}", schemaName)
 	result = strings.ReplaceAll(result, "{name}", name)
+	result = strings.ReplaceAll(result, "{type_name}", name)
 	// Replace format placeholder with "proto" for protobuf files
 	result = strings.ReplaceAll(result, "{format}", "proto")
 
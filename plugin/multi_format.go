@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/pablor21/gonnotation/parser"
+	"google.golang.org/protobuf/proto"
 )
 
 // MultiFormatGenerator handles generation of multiple output formats
@@ -156,8 +157,8 @@ func (mfg *MultiFormatGenerator) generateFieldJSONSchema(field *parser.FieldInfo
 
 	fieldTypeStr := mfg.typeExprToString(field.Type)
 
-	// Handle array types
-	if strings.HasPrefix(fieldTypeStr, "[]") {
+	// Handle array types, byte slices are base64 strings
+	if strings.HasPrefix(fieldTypeStr, "[]") && fieldTypeStr != "[]byte" {
 		schema["type"] = "array"
 		itemType := strings.TrimPrefix(fieldTypeStr, "[]")
 		schema["items"] = mfg.getJSONSchemaType(itemType)
@@ -216,7 +217,15 @@ func (mfg *MultiFormatGenerator) getJSONSchemaType(goType string) map[string]int
 			"type":   "string",
 			"format": "date-time",
 		}
+	case "time.Duration":
+		// Durations are encoded as nanoseconds
+		return jsonInteger
+	case "[]byte":
+		return jsonBytes
 	default:
+		if builtin, ok := mfg.generator.builtinType(goType); ok {
+			return builtin.jsonSchema
+		}
 		// Custom type - reference to definition
 		if mfg.isCustomType(goType) {
 			return map[string]interface{}{
@@ -498,10 +507,14 @@ func (mfg *MultiFormatGenerator) generateInterfaceTypeScript(out *strings.Builde
 	out.WriteString("}\n\n")
 }
 
+// generateDescriptor serializes a google.protobuf.FileDescriptorSet for the schema,
+// equivalent to protoc --descriptor_set_out with --include_imports and --include_source_info
 func (mfg *MultiFormatGenerator) generateDescriptor() ([]byte, error) {
-	// This would generate a binary protobuf descriptor
-	// For now, return a placeholder
-	return []byte("Binary protobuf descriptor - not implemented yet"), nil
+	set, err := mfg.generator.BuildFileDescriptorSet()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build descriptor set: %w", err)
+	}
+	return proto.Marshal(set)
 }
 
 // Helper methods for type conversions
@@ -519,6 +532,14 @@ func (mfg *MultiFormatGenerator) getMarkdownType(goType string) string {
 func (mfg *MultiFormatGenerator) getTypeScriptType(goType string) string {
 	goType = strings.TrimPrefix(goType, "*")
 
+	// Byte slices and the builtin types are encoded as their protobuf type
+	if goType == "[]byte" {
+		return "string"
+	}
+	if builtin, ok := mfg.generator.builtinType(goType); ok {
+		return builtin.typeScript
+	}
+
 	if strings.HasPrefix(goType, "[]") {
 		itemType := mfg.getTypeScriptType(strings.TrimPrefix(goType, "[]"))
 		return fmt.Sprintf("%s[]", itemType)
@@ -573,6 +594,8 @@ func (mfg *MultiFormatGenerator) getTypeScriptType(goType string) string {
 		return "boolean"
 	case "time.Time":
 		return "string | Date"
+	case "time.Duration":
+		return "number"
 	case "interface{}", "any":
 		return "any"
 	default:
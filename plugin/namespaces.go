@@ -0,0 +1,89 @@
+package plugin
+
+import (
+	"strings"
+
+	"github.com/pablor21/gonnotation/parser"
+)
+
+// NamespaceConfig sets the proto package and go_package of the files of a namespace
+type NamespaceConfig struct {
+	Package   string `yaml:"package"`    // Protobuf package name, e.g. acme.billing.v1
+	GoPackage string `yaml:"go_package"` // Go package import path
+}
+
+// defaultNamespace names the file of the types without @namespace
+const defaultNamespace = "default"
+
+// currentNamespace returns the namespace of the file being generated. Outside of the
+// namespace strategy every type goes to the default one.
+func (g *Generator) currentNamespace() string {
+	if g.namespace == "" {
+		return defaultNamespace
+	}
+	return g.namespace
+}
+
+// namespaceConfig returns the package settings of the current namespace
+func (g *Generator) namespaceConfig() NamespaceConfig {
+	return g.formatGen.config.Namespaces[g.currentNamespace()]
+}
+
+// expandNamespace replaces the {namespace} placeholder of a package or option value with the
+// current namespace. Proto packages are dotted, so nested namespaces (billing/invoices) are
+// converted when the value has no slashes.
+func (g *Generator) expandNamespace(value string) string {
+	if !strings.Contains(value, "{namespace}") {
+		return value
+	}
+	namespace := g.currentNamespace()
+	if !strings.Contains(value, "/") {
+		namespace = strings.ReplaceAll(namespace, "/", ".")
+	}
+	return strings.ReplaceAll(value, "{namespace}", namespace)
+}
+
+// typeProtoPackage returns the proto package the types of a Go package and namespace are declared
+// in: the configured package, unless the namespace strategy gives the namespace its own
+func (g *Generator) typeProtoPackage(goPackage, namespace string) string {
+	nsGen := *g
+	if g.formatGen.config.GenerationStrategy == parser.GenStrategyNamespace {
+		nsGen.namespace = namespace
+	}
+	return nsGen.packageNameFor(goPackage)
+}
+
+// namespaceGoPackages returns the go_package of each namespace whose files are generated in
+// a Go package other than the default one, with the namespace strategy
+func (g *Generator) namespaceGoPackages() map[string]string {
+	packages := make(map[string]string)
+	if g.formatGen.config.GenerationStrategy != parser.GenStrategyNamespace {
+		return packages
+	}
+
+	defaultGoPackage := g.collectFileOptions()["go_package"]
+	for _, namespace := range g.typeNamespaces() {
+		nsGen := *g
+		nsGen.namespace = namespace
+		if goPackage := nsGen.collectFileOptions()["go_package"]; goPackage != "" && goPackage != defaultGoPackage {
+			packages[namespace] = goPackage
+		}
+	}
+	return packages
+}
+
+// typeNamespaces returns the namespaces the parsed types are declared in
+func (g *Generator) typeNamespaces() []string {
+	namespaces := make(map[string]bool)
+	for _, s := range g.ctx.Structs {
+		namespaces[s.Namespace] = true
+	}
+	for _, e := range g.ctx.Enums {
+		namespaces[e.Namespace] = true
+	}
+	for _, i := range g.ctx.Interfaces {
+		namespaces[i.Namespace] = true
+	}
+	delete(namespaces, "")
+	return sortedKeys(namespaces)
+}
@@ -0,0 +1,209 @@
+package plugin
+
+import (
+	"go/ast"
+	"strings"
+
+	"github.com/pablor21/gonnotation/parser"
+)
+
+// nestedMessage is a message declared inside another message
+type nestedMessage struct {
+	Name   string             // Qualified name, e.g. "User.Address"
+	Struct *parser.StructInfo // Struct the message is generated from
+}
+
+// getNestedIn returns the message a struct is declared in, from @message(nested_in="Parent")
+func (g *Generator) getNestedIn(s *parser.StructInfo) string {
+	for _, ann := range s.Annotations {
+		name := strings.ToLower(ann.Name)
+		if name == "message" || strings.HasSuffix(name, ".message") {
+			if parent, ok := ann.GetParamValue("nested_in"); ok && parent != "" {
+				return parent
+			}
+		}
+	}
+	return ""
+}
+
+// qualifiedNestedName returns the message name of a nested_in struct qualified with
+// its parents, e.g. "User.Address"
+func (g *Generator) qualifiedNestedName(s *parser.StructInfo) string {
+	return g.qualifyNestedParent(g.getNestedIn(s), map[string]bool{}) + "." + g.getMessageName(s, "")
+}
+
+// qualifyNestedParent expands a nested_in parent whose message is nested in another one
+func (g *Generator) qualifyNestedParent(parent string, seen map[string]bool) string {
+	first, rest, _ := strings.Cut(parent, ".")
+	if seen[first] {
+		return parent // cycle, reported by the validator
+	}
+	seen[first] = true
+
+	for _, s := range g.ctx.Structs {
+		if g.getMessageName(s, "") != first {
+			continue
+		}
+		if grandParent := g.getNestedIn(s); grandParent != "" {
+			qualified := g.qualifyNestedParent(grandParent, seen) + "." + first
+			if rest != "" {
+				qualified += "." + rest
+			}
+			return qualified
+		}
+		break
+	}
+	return parent
+}
+
+// nestedTypeName returns the qualified message name of a Go type declared with nested_in
+func (g *Generator) nestedTypeName(goType string) (string, bool) {
+	if g.ctx == nil {
+		return "", false
+	}
+	for _, s := range g.ctx.Structs {
+		if s.Name == goType && g.getNestedIn(s) != "" {
+			return g.qualifiedNestedName(s), true
+		}
+	}
+	return "", false
+}
+
+// collectNestedMessages returns the messages declared inside messageName: one per anonymous
+// struct field of s, followed by the structs declared with @message(nested_in=...)
+func (g *Generator) collectNestedMessages(s *parser.StructInfo, messageName string) []nestedMessage {
+	var nested []nestedMessage
+
+	for _, f := range s.Fields {
+		if f.GoName == "" || f.GoName[0] < 'A' || f.GoName[0] > 'Z' {
+			continue // skip unexported fields
+		}
+		if isSkipped, _ := g.shouldSkipFieldForMessage(f, messageName); isSkipped {
+			continue
+		}
+		if st := anonymousStruct(f.Type); st != nil {
+			nested = append(nested, nestedMessage{
+				Name:   messageName + "." + f.GoName,
+				Struct: anonymousStructInfo(f.GoName, s, st),
+			})
+		}
+	}
+
+	for _, candidate := range g.ctx.Structs {
+		if candidate.IsGeneric || g.shouldSkipType(candidate) || g.hasOtherTypeAnnotation(candidate) {
+			continue
+		}
+		if g.getNestedIn(candidate) == "" {
+			continue
+		}
+		if name := g.qualifiedNestedName(candidate); name[:strings.LastIndex(name, ".")] == messageName {
+			nested = append(nested, nestedMessage{Name: name, Struct: candidate})
+		}
+	}
+	return nested
+}
+
+// substituteAnonymousStruct returns a copy of the field whose anonymous struct type is replaced
+// by a reference to the nested message generated for it
+func (g *Generator) substituteAnonymousStruct(f *parser.FieldInfo, messageName string) *parser.FieldInfo {
+	if anonymousStruct(f.Type) == nil {
+		return f
+	}
+	return &parser.FieldInfo{
+		Name:        f.Name,
+		GoName:      f.GoName,
+		Type:        replaceAnonymousStruct(f.Type, messageName+"."+f.GoName),
+		Tag:         f.Tag,
+		IsEmbedded:  f.IsEmbedded,
+		Annotations: f.Annotations,
+	}
+}
+
+// anonymousStruct returns the anonymous struct type of a field, behind pointers and slices
+func anonymousStruct(t ast.Expr) *ast.StructType {
+	switch v := t.(type) {
+	case *ast.StructType:
+		return v
+	case *ast.StarExpr:
+		return anonymousStruct(v.X)
+	case *ast.ArrayType:
+		return anonymousStruct(v.Elt)
+	}
+	return nil
+}
+
+// replaceAnonymousStruct replaces the anonymous struct of a type expression by an identifier,
+// keeping the pointers and slices around it
+func replaceAnonymousStruct(t ast.Expr, name string) ast.Expr {
+	switch v := t.(type) {
+	case *ast.StructType:
+		return &ast.Ident{Name: name}
+	case *ast.StarExpr:
+		return &ast.StarExpr{X: replaceAnonymousStruct(v.X, name)}
+	case *ast.ArrayType:
+		return &ast.ArrayType{Len: v.Len, Elt: replaceAnonymousStruct(v.Elt, name)}
+	}
+	return t
+}
+
+// anonymousStructInfo describes an anonymous struct as a StructInfo of the parent's package
+func anonymousStructInfo(name string, parent *parser.StructInfo, st *ast.StructType) *parser.StructInfo {
+	return &parser.StructInfo{
+		Name:        name,
+		Package:     parent.Package,
+		PackagePath: parent.PackagePath,
+		SourceFile:  parent.SourceFile,
+		Namespace:   parent.Namespace,
+		Fields:      anonymousStructFields(st),
+	}
+}
+
+// anonymousStructFields converts the fields of an anonymous struct
+func anonymousStructFields(st *ast.StructType) []*parser.FieldInfo {
+	var fields []*parser.FieldInfo
+	if st.Fields == nil {
+		return fields
+	}
+	for _, field := range st.Fields.List {
+		if len(field.Names) == 0 {
+			name := embeddedFieldName(field.Type)
+			fields = append(fields, &parser.FieldInfo{Name: name, GoName: name, Type: field.Type, Tag: field.Tag, IsEmbedded: true})
+			continue
+		}
+		for _, ident := range field.Names {
+			fields = append(fields, &parser.FieldInfo{Name: ident.Name, GoName: ident.Name, Type: field.Type, Tag: field.Tag})
+		}
+	}
+	return fields
+}
+
+// embeddedFieldName returns the implicit name of an embedded field
+func embeddedFieldName(t ast.Expr) string {
+	switch v := t.(type) {
+	case *ast.Ident:
+		return v.Name
+	case *ast.StarExpr:
+		return embeddedFieldName(v.X)
+	case *ast.SelectorExpr:
+		return v.Sel.Name
+	}
+	return ""
+}
+
+// localMessageName returns the last segment of a qualified nested message name
+func localMessageName(name string) string {
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		return name[i+1:]
+	}
+	return name
+}
+
+// writeIndented writes a block one level deeper, leaving blank lines empty
+func writeIndented(out *strings.Builder, block string) {
+	for _, line := range strings.Split(strings.TrimRight(block, "\n"), "\n") {
+		if line != "" {
+			out.WriteString("  " + line)
+		}
+		out.WriteString("\n")
+	}
+}
@@ -0,0 +1,140 @@
+package plugin
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+)
+
+// TemplateOneofInfo represents a @proto.oneof group of struct fields, set through the
+// <Message>_<Case> wrapper types protoc generates for its members
+type TemplateOneofInfo struct {
+	Name          string // Oneof name in the schema
+	OneofField    string // Protobuf Go struct field holding the oneof
+	ToProtoFunc   string
+	FromProtoFunc string
+	Members       []*TemplateOneofMember
+}
+
+// TemplateOneofMember represents a struct field member of a oneof
+type TemplateOneofMember struct {
+	GoName              string // Go field name
+	WrapperType         string // Protobuf oneof wrapper type, e.g. Contact_Email
+	CaseField           string // Field of the wrapper type, e.g. Email
+	IsSet               string // Condition checking the Go field isn't its zero value
+	ToProtoConversion   string // Conversion of the Go field to the case field
+	FromProtoConversion string // Conversion of the case field to the Go field
+}
+
+// convertToTemplateOneofs converts the @proto.oneof groups of a type, members in declaration
+// order: when several members are set, the first one is the one converted
+func (g *StubGenerator) convertToTemplateOneofs(typeInfo *TypeInfo, templateFields []*TemplateFieldInfo) []*TemplateOneofInfo {
+	protoName := g.protoTypeName(typeInfo)
+	oneofs := make(map[string]*TemplateOneofInfo)
+	for i, field := range typeInfo.Fields {
+		if field.Oneof == "" || templateFields[i].ProtoFieldName == "" {
+			continue
+		}
+		oneof, ok := oneofs[field.Oneof]
+		if !ok {
+			oneofField := g.protoFieldNameFromTag(field.Oneof)
+			oneof = &TemplateOneofInfo{
+				Name:          field.Oneof,
+				OneofField:    oneofField,
+				ToProtoFunc:   protoName + "_" + oneofField + "ToProto",
+				FromProtoFunc: protoName + "_" + oneofField + "FromProto",
+			}
+			oneofs[field.Oneof] = oneof
+		}
+
+		caseField := templateFields[i].ProtoFieldName
+		toProto, fromProto := g.oneofMemberConversions(field, caseField)
+		oneof.Members = append(oneof.Members, &TemplateOneofMember{
+			GoName:              field.GoName,
+			WrapperType:         protoName + "_" + caseField,
+			CaseField:           caseField,
+			IsSet:               g.oneofMemberSet(field),
+			ToProtoConversion:   toProto,
+			FromProtoConversion: fromProto,
+		})
+	}
+
+	result := make([]*TemplateOneofInfo, 0, len(oneofs))
+	for _, name := range sortedKeys(oneofs) {
+		result = append(result, oneofs[name])
+	}
+	return result
+}
+
+// oneofMemberConversions returns the conversions of a oneof member. Case fields have no
+// presence of their own, so pointers to scalars and enums convert the value they point to.
+func (g *StubGenerator) oneofMemberConversions(field *FieldInfo, caseField string) (string, string) {
+	getter := "Get" + caseField + "()"
+	if pointed, value, ok := g.pointedValue(field); ok && field.wrapper == "" {
+		goType := g.qualifiedGoType(pointed, g.getPackageAlias(field.packagePath))
+		toProto := value.toProto("*orig." + field.GoName)
+		fromProto := fmt.Sprintf("func() *%s { v := %s; return &v }()", goType, value.fromProto("proto."+getter))
+		return toProto, fromProto
+	}
+	return g.getToProtoConversion(field, field.GoName), g.getFromProtoConversion(field, getter)
+}
+
+// oneofMemberSet returns the condition checking a oneof member is set: non-nil pointers,
+// slices and maps, non-zero values otherwise
+func (g *StubGenerator) oneofMemberSet(field *FieldInfo) string {
+	value := "orig." + field.GoName
+	// Structs, arrays and the types converted by helpers are compared with their zero value
+	// through reflect, they may not be comparable
+	isZero := "!reflect.ValueOf(" + value + ").IsZero()"
+
+	switch t := field.goType.(type) {
+	case *ast.StarExpr, *ast.MapType, *ast.InterfaceType, *ast.FuncType, *ast.ChanType:
+		return value + " != nil"
+	case *ast.ArrayType:
+		if t.Len == nil {
+			return "len(" + value + ") > 0"
+		}
+		return isZero
+	}
+
+	if _, ok := g.mainGenerator.timeType(field.Type); ok {
+		if field.Type == "time.Time" {
+			return "!" + value + ".IsZero()"
+		}
+		return value + " != 0"
+	}
+	if _, ok := g.typeMapping(field.Type); ok {
+		return isZero
+	}
+	if _, _, ok := g.builtinHelpers(field.Type); ok {
+		return isZero
+	}
+	conversion, ok := g.elementConversion(field.goType, g.getPackageAlias(field.packagePath))
+	if !ok || strings.HasPrefix(conversion.protoType, "*") {
+		return isZero
+	}
+
+	// Named types and enums are checked through their protobuf value
+	converted := conversion.toProto(value)
+	switch conversion.protoType {
+	case "string":
+		return converted + ` != ""`
+	case "bool":
+		return converted
+	case "[]byte":
+		return "len(" + converted + ") > 0"
+	}
+	return converted + " != 0"
+}
+
+// oneofTypeImports returns the packages the oneof helpers reference
+func (g *StubGenerator) oneofTypeImports() []string {
+	for _, typeInfo := range g.originalTypes {
+		for _, field := range typeInfo.Fields {
+			if field.Oneof != "" && strings.HasPrefix(g.oneofMemberSet(field), "!reflect.") {
+				return []string{"reflect"}
+			}
+		}
+	}
+	return nil
+}
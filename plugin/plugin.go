@@ -13,6 +13,10 @@ import (
 // Plugin implements parser.Plugin for Protobuf schema generation
 type Plugin struct {
 	config *Config
+
+	breakingAgainst string           // Baseline to check against, set by CheckBreakingOnly
+	breakingOnly    bool             // Only check for breaking changes, don't write any file
+	breakingChanges []BreakingChange // Breaking changes found by the last generation
 }
 
 func NewPlugin(config *Config) *Plugin {
@@ -24,6 +28,18 @@ func NewPlugin(config *Config) *Plugin {
 	}
 }
 
+// CheckBreakingOnly makes the plugin compare the schema against a baseline descriptor set
+// (file or directory) instead of writing the generated files
+func (p *Plugin) CheckBreakingOnly(against string) {
+	p.breakingAgainst = against
+	p.breakingOnly = true
+}
+
+// BreakingChanges returns the breaking changes found by the last generation
+func (p *Plugin) BreakingChanges() []BreakingChange {
+	return p.breakingChanges
+}
+
 // GetConfig returns the plugin configuration
 func (p *Plugin) GetConfig() *Config {
 	return p.config
@@ -119,9 +135,10 @@ func (p *Plugin) GenerateMulti(ctx *parser.GenerationContext) (*parser.Generated
 	// Update format generator config
 	p.config = cfg
 
-	// Ensure goschemagen Config is properly initialized
+	// Ensure goschemagen Config is properly initialized, keeping the configured type mappings
 	if cfg.UseCommentsAsDescription == nil {
 		defaultConfig := goschemagen.NewConfig()
+		defaultConfig.TypeMappings = cfg.TypeMappings
 		cfg.Config = *defaultConfig
 	}
 
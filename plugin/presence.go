@@ -0,0 +1,165 @@
+package plugin
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+
+	"github.com/pablor21/gonnotation/parser"
+)
+
+// Optional field styles of proto3 pointer fields
+const (
+	optionalStyleOptional = "optional" // optional string name = 1; (default)
+	optionalStyleWrappers = "wrappers" // google.protobuf.StringValue name = 1;
+)
+
+// optionalWrappers maps the scalars with a well-known wrapper type to its message name
+var optionalWrappers = map[string]string{
+	"double": "DoubleValue",
+	"float":  "FloatValue",
+	"int64":  "Int64Value",
+	"uint64": "UInt64Value",
+	"int32":  "Int32Value",
+	"uint32": "UInt32Value",
+	"bool":   "BoolValue",
+	"string": "StringValue",
+}
+
+// optionalWrapper returns the well-known wrapper a proto3 pointer field is written as with
+// optional_style: wrappers. Enums, scalars without a wrapper (sint32, bytes...) and the time and
+// builtin types converted through helpers of their own stay optional.
+func (g *Generator) optionalWrapper(f *parser.FieldInfo) (string, bool) {
+	config := g.formatGen.config
+	if config.OptionalStyle != optionalStyleWrappers || config.Syntax != "proto3" {
+		return "", false
+	}
+	star, ok := f.Type.(*ast.StarExpr)
+	if !ok {
+		return "", false
+	}
+	goType := g.getGoTypeName(star.X)
+	if builtin, ok := g.builtinType(goType); ok && builtin.scalar == "" {
+		return "", false
+	}
+	if _, ok := g.timeType(goType); ok {
+		return "", false
+	}
+	wrapper, ok := optionalWrappers[g.mapGoTypeToProto(f.Type)]
+	if !ok {
+		return "", false
+	}
+	return "google.protobuf." + wrapper, true
+}
+
+// isProto3Optional checks if a field is written with the proto3 optional label: optional scalars
+// and enums outside of oneofs. Messages track presence without it.
+func (g *Generator) isProto3Optional(f *parser.FieldInfo, inOneof bool) bool {
+	return g.formatGen.config.Syntax == "proto3" && !inOneof && g.isOptional(f) && g.isScalarOrEnumType(g.getProtoType(f))
+}
+
+// OptionalConversionInfo converts a pointer to a scalar or enum, nil when the field is unset,
+// to its proto3 optional field or its well-known wrapper
+type OptionalConversionInfo struct {
+	ToProtoFuncName   string
+	FromProtoFuncName string
+	GoType            string // Type pointed to, e.g. models.Status
+	ProtoType         string // e.g. *pb.Status or *wrapperspb.StringValue
+	ValueToProto      string // Conversion of *orig
+	ValueFromProto    string // Conversion of the protobuf value
+	Wrapper           string // Constructor of the wrapper, e.g. wrapperspb.String
+}
+
+// optionalConversion returns the helpers converting an optional scalar or enum field, unless it's
+// assigned as is (*string to *string)
+func (g *StubGenerator) optionalConversion(field *FieldInfo) (*OptionalConversionInfo, bool) {
+	pointed, value, ok := g.pointedValue(field)
+	if !ok || (field.wrapper == "" && value.toFormat == "%s") {
+		return nil, false
+	}
+
+	name := g.sanitizeTypeName(g.getGoTypeName(pointed))
+	conversion := &OptionalConversionInfo{
+		ToProtoFuncName:   "ConvertPointerToProto_" + name,
+		FromProtoFuncName: "ConvertPointerFromProto_" + name,
+		GoType:            g.qualifiedGoType(pointed, g.getPackageAlias(field.packagePath)),
+		ProtoType:         "*" + value.protoType,
+		ValueToProto:      value.toProto("*orig"),
+		ValueFromProto:    value.fromProto("*proto"),
+	}
+	if field.wrapper != "" {
+		wrapper := strings.TrimPrefix(field.wrapper, "google.protobuf.")
+		conversion.ProtoType = "*wrapperspb." + wrapper
+		conversion.ValueFromProto = value.fromProto("proto.GetValue()")
+		conversion.Wrapper = "wrapperspb." + strings.TrimSuffix(wrapper, "Value")
+	}
+	return conversion, true
+}
+
+// pointedValue returns the scalar or enum a pointer field points to and its conversion
+func (g *StubGenerator) pointedValue(field *FieldInfo) (ast.Expr, valueConversion, bool) {
+	star, ok := field.goType.(*ast.StarExpr)
+	if !ok {
+		return nil, valueConversion{}, false
+	}
+
+	// Mapped, builtin and time types convert through helpers of their own
+	goType := g.getGoTypeName(star.X)
+	if _, ok := g.typeMapping(field.Type); ok {
+		return nil, valueConversion{}, false
+	}
+	if builtin, ok := g.mainGenerator.builtinType(goType); ok && builtin.scalar == "" {
+		return nil, valueConversion{}, false
+	}
+	if _, ok := g.mainGenerator.timeType(goType); ok {
+		return nil, valueConversion{}, false
+	}
+
+	value, ok := g.elementConversion(star.X, g.getPackageAlias(field.packagePath))
+	if !ok || strings.HasPrefix(value.protoType, "*") || value.protoType == "[]byte" {
+		// Messages convert through the pointer helpers of their type, bytes have no pointer
+		return nil, valueConversion{}, false
+	}
+	return star.X, value, true
+}
+
+// getOptionalConversion returns the conversion of an optional field in a direction (ToProto or
+// FromProto)
+func (g *StubGenerator) getOptionalConversion(field *FieldInfo, direction, value string) (string, bool) {
+	conversion, ok := g.optionalConversion(field)
+	if !ok {
+		return "", false
+	}
+	if direction == "FromProto" {
+		return fmt.Sprintf("%s(%s)", conversion.FromProtoFuncName, value), true
+	}
+	return fmt.Sprintf("%s(%s)", conversion.ToProtoFuncName, value), true
+}
+
+// collectOptionalConversions returns the helpers of the optional scalar and enum fields
+func (g *StubGenerator) collectOptionalConversions() []*OptionalConversionInfo {
+	conversions := make(map[string]*OptionalConversionInfo)
+	for _, typeInfo := range g.originalTypes {
+		for _, field := range typeInfo.Fields {
+			if conversion, ok := g.optionalConversion(field); ok {
+				conversions[conversion.ToProtoFuncName] = conversion
+			}
+		}
+	}
+
+	result := make([]*OptionalConversionInfo, 0, len(conversions))
+	for _, name := range sortedKeys(conversions) {
+		result = append(result, conversions[name])
+	}
+	return result
+}
+
+// optionalTypeImports returns the packages the optional conversions reference
+func (g *StubGenerator) optionalTypeImports() []string {
+	for _, conversion := range g.collectOptionalConversions() {
+		if conversion.Wrapper != "" {
+			return []string{wrappersGoPackage}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+package plugin
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pablor21/gonnotation/parser"
+)
+
+// maxFieldNumber is the largest valid field number, written as "max" in ranges
+const maxFieldNumber = 536870911
+
+// isProto2 checks if the schema is written with proto2 syntax
+func (g *Generator) isProto2() bool {
+	return g.formatGen.config.Syntax == "proto2"
+}
+
+// isRequired checks if a proto2 field is required, from @validate(required=true),
+// @field(required=true) or the required tag flag
+func (g *Generator) isRequired(f *parser.FieldInfo) bool {
+	return g.isProto2() && g.fieldFlag(f, "required", true)
+}
+
+// isLegacyRequired checks if an editions field opted in to legacy required presence with
+// @field(legacy_required=true) or the legacy_required tag flag. Validation rules don't
+// make fields required on the wire.
+func (g *Generator) isLegacyRequired(f *parser.FieldInfo) bool {
+	return g.isEditions() && g.fieldFlag(f, "legacy_required", false)
+}
+
+// fieldFlag checks a boolean flag of a field, from @field, @validate when validate is set,
+// or the struct tag
+func (g *Generator) fieldFlag(f *parser.FieldInfo, flag string, validate bool) bool {
+	for _, ann := range f.Annotations {
+		name := strings.ToLower(ann.Name)
+		isValidate := name == "validate" || name == "validation" || name == "constraint" ||
+			strings.HasSuffix(name, ".validate") || strings.HasSuffix(name, ".validation") || strings.HasSuffix(name, ".constraint")
+		if (validate && isValidate) || name == "field" || strings.HasSuffix(name, ".field") {
+			if value, ok := ann.GetParamBool(flag); ok {
+				return value
+			}
+		}
+	}
+
+	if g.ctx != nil && g.ctx.FieldProcessor != nil {
+		pf := g.ctx.FieldProcessor.ProcessField(f)
+		if tag := pf.Tags[parser.DerefPtr(g.ctx.CoreConfig.StructTagName, "")]; tag != "" {
+			for _, part := range strings.Split(tag, ",") {
+				if part == flag || part == flag+"=true" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// getDefaultValue returns the raw default value of a field, from @field(default=...) or the default= tag
+func (g *Generator) getDefaultValue(f *parser.FieldInfo) (string, bool) {
+	for _, ann := range f.Annotations {
+		name := strings.ToLower(ann.Name)
+		if name == "field" || strings.HasSuffix(name, ".field") {
+			if value, ok := ann.GetParamValue("default"); ok {
+				return value, true
+			}
+		}
+	}
+
+	if g.ctx != nil && g.ctx.FieldProcessor != nil {
+		pf := g.ctx.FieldProcessor.ProcessField(f)
+		if tag := pf.Tags[parser.DerefPtr(g.ctx.CoreConfig.StructTagName, "")]; tag != "" {
+			for _, part := range strings.Split(tag, ",") {
+				if strings.HasPrefix(part, "default=") {
+					return strings.TrimPrefix(part, "default="), true
+				}
+			}
+		}
+	}
+	return "", false
+}
+
+// formatDefaultValue converts a raw default value to the proto literal for the field type.
+// Enum defaults accept the proto value name or the Go constant name.
+func (g *Generator) formatDefaultValue(f *parser.FieldInfo, raw string) (string, error) {
+	if g.isRepeated(f) || g.isMapField(f) {
+		return "", fmt.Errorf("repeated and map fields can't have a default value")
+	}
+
+	protoType := g.getProtoType(f)
+	raw = strings.TrimSpace(raw)
+
+	switch protoType {
+	case "string", "bytes":
+		if unquoted, err := strconv.Unquote(raw); err == nil {
+			raw = unquoted
+		}
+		return strconv.Quote(raw), nil
+	case "bool":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return "", fmt.Errorf("invalid bool default %q", raw)
+		}
+		return strconv.FormatBool(b), nil
+	case "int32", "sint32", "sfixed32":
+		return formatIntDefault(raw, 32)
+	case "int64", "sint64", "sfixed64":
+		return formatIntDefault(raw, 64)
+	case "uint32", "fixed32":
+		return formatUintDefault(raw, 32)
+	case "uint64", "fixed64":
+		return formatUintDefault(raw, 64)
+	case "float", "double":
+		switch strings.ToLower(raw) {
+		case "inf", "+inf":
+			return "inf", nil
+		case "-inf":
+			return "-inf", nil
+		case "nan":
+			return "nan", nil
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsInf(v, 0) || math.IsNaN(v) {
+			return "", fmt.Errorf("invalid %s default %q", protoType, raw)
+		}
+		return raw, nil
+	}
+
+	// Enum defaults are written as the value name
+	for _, e := range g.ctx.Enums {
+		enumName := g.getEnumName(e)
+		if g.isEnumIgnored(e) || enumName != protoType {
+			continue
+		}
+		for _, v := range e.Values {
+			if valueName := g.getEnumValueName(v, enumName); raw == valueName || raw == v.Name {
+				return valueName, nil
+			}
+		}
+		return "", fmt.Errorf("%q is not a value of enum %s", raw, enumName)
+	}
+
+	return "", fmt.Errorf("message field of type %s can't have a default value", protoType)
+}
+
+func formatIntDefault(raw string, bitSize int) (string, error) {
+	v, err := strconv.ParseInt(raw, 0, bitSize)
+	if err != nil {
+		return "", fmt.Errorf("invalid int%d default %q", bitSize, raw)
+	}
+	return strconv.FormatInt(v, 10), nil
+}
+
+func formatUintDefault(raw string, bitSize int) (string, error) {
+	v, err := strconv.ParseUint(raw, 0, bitSize)
+	if err != nil {
+		return "", fmt.Errorf("invalid uint%d default %q", bitSize, raw)
+	}
+	return strconv.FormatUint(v, 10), nil
+}
+
+// getExtensionRanges returns the extension ranges (inclusive) declared with @extensions on a struct,
+// either as @extensions(ranges="100 to 199, 1000 to max"), @extensions(ranges="100-199") or
+// @extensions(from=100, to=199)
+func (g *Generator) getExtensionRanges(s *parser.StructInfo) [][2]int {
+	var ranges [][2]int
+	for _, ann := range s.Annotations {
+		name := strings.ToLower(ann.Name)
+		if name != "extensions" && !strings.HasSuffix(name, ".extensions") {
+			continue
+		}
+
+		if value, ok := ann.GetParamValue("ranges"); ok && value != "" {
+			for _, part := range strings.Split(strings.Trim(value, "[]"), ",") {
+				if r, ok := parseNumberRange(part); ok {
+					ranges = append(ranges, r)
+				}
+			}
+			continue
+		}
+
+		from, hasFrom := ann.GetParamValue("from")
+		if !hasFrom {
+			continue
+		}
+		to, hasTo := ann.GetParamValue("to")
+		if !hasTo {
+			to = from
+		}
+		if r, ok := parseNumberRange(from + " to " + to); ok {
+			ranges = append(ranges, r)
+		}
+	}
+	return ranges
+}
+
+// parseNumberRange parses "N", "N to M", "N-M" or "N to max"
+func parseNumberRange(spec string) ([2]int, bool) {
+	parts := strings.SplitN(strings.TrimSpace(spec), " to ", 2)
+	if len(parts) == 1 {
+		parts = strings.SplitN(parts[0], "-", 2)
+	}
+	start, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || start < 1 {
+		return [2]int{}, false
+	}
+	end := start
+	if len(parts) == 2 {
+		endStr := strings.TrimSpace(parts[1])
+		if endStr == "max" {
+			end = maxFieldNumber
+		} else if end, err = strconv.Atoi(endStr); err != nil || end < start {
+			return [2]int{}, false
+		}
+	}
+	return [2]int{start, end}, true
+}
+
+// formatNumberRanges formats ranges the way they are written in extensions statements
+func formatNumberRanges(ranges [][2]int) string {
+	parts := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		switch {
+		case r[0] == r[1]:
+			parts = append(parts, strconv.Itoa(r[0]))
+		case r[1] == maxFieldNumber:
+			parts = append(parts, fmt.Sprintf("%d to max", r[0]))
+		default:
+			parts = append(parts, fmt.Sprintf("%d to %d", r[0], r[1]))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// getStructExtendee returns the message extended by a struct annotated with @extend(message=...)
+func getStructExtendee(s *parser.StructInfo) (string, bool) {
+	for _, ann := range s.Annotations {
+		name := strings.ToLower(ann.Name)
+		if name == "extend" || strings.HasSuffix(name, ".extend") {
+			if message, ok := ann.GetParamValue("message"); ok && message != "" {
+				return message, true
+			}
+		}
+	}
+	return "", false
+}
+
+// resolveStructExtensions turns the fields of a struct-level @extend into extensions of the target
+// message. Fields without an explicit number are numbered from the first extension range of the
+// target when it is declared in the schema, or from the start field number otherwise.
+func (g *Generator) resolveStructExtensions(s *parser.StructInfo, extendee string) []ProtoExtension {
+	counter := g.formatGen.config.StartFieldNumber
+	for _, target := range g.ctx.Structs {
+		if g.getMessageName(target, "") != extendee {
+			continue
+		}
+		if ranges := g.getExtensionRanges(target); len(ranges) > 0 {
+			counter = ranges[0][0]
+		}
+		break
+	}
+
+	explicit := make(map[int]bool)
+	for _, f := range s.Fields {
+		if num := g.getExplicitFieldNumber(f); num > 0 {
+			explicit[num] = true
+		}
+	}
+
+	var extensions []ProtoExtension
+	for _, f := range s.Fields {
+		if f.GoName == "" || f.GoName[0] < 'A' || f.GoName[0] > 'Z' {
+			continue // skip unexported fields
+		}
+		if isSkipped, _ := g.shouldSkipFieldForMessage(f, extendee); isSkipped {
+			continue
+		}
+
+		num := g.getExplicitFieldNumber(f)
+		if num == 0 {
+			for explicit[counter] {
+				counter++
+			}
+			num = counter
+			counter++
+		}
+
+		extensions = append(extensions, ProtoExtension{
+			Extendee: extendee,
+			Name:     g.getFieldName(f),
+			Type:     g.getProtoType(f),
+			Number:   num,
+			Repeated: g.isRepeated(f),
+			Comment:  g.getFieldDescription(f),
+			Original: f,
+		})
+	}
+	return extensions
+}
+
+// getExtensionLabel returns the label written before an extension field
+func (g *Generator) getExtensionLabel(ext ProtoExtension) string {
+	if ext.Repeated {
+		return "repeated "
+	}
+	if g.isProto2() {
+		return "optional "
+	}
+	return ""
+}
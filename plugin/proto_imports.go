@@ -0,0 +1,96 @@
+package plugin
+
+import (
+	"strings"
+)
+
+// wellKnownTypeFiles are the proto files declaring the Google well-known types and the
+// common googleapis types (google.type), by fully qualified type name
+var wellKnownTypeFiles = map[string]string{
+	"google.protobuf.Any":         "google/protobuf/any.proto",
+	"google.protobuf.Duration":    "google/protobuf/duration.proto",
+	"google.protobuf.Empty":       "google/protobuf/empty.proto",
+	"google.protobuf.FieldMask":   "google/protobuf/field_mask.proto",
+	"google.protobuf.Timestamp":   "google/protobuf/timestamp.proto",
+	"google.protobuf.Struct":      "google/protobuf/struct.proto",
+	"google.protobuf.Value":       "google/protobuf/struct.proto",
+	"google.protobuf.ListValue":   "google/protobuf/struct.proto",
+	"google.protobuf.NullValue":   "google/protobuf/struct.proto",
+	"google.protobuf.DoubleValue": "google/protobuf/wrappers.proto",
+	"google.protobuf.FloatValue":  "google/protobuf/wrappers.proto",
+	"google.protobuf.Int64Value":  "google/protobuf/wrappers.proto",
+	"google.protobuf.UInt64Value": "google/protobuf/wrappers.proto",
+	"google.protobuf.Int32Value":  "google/protobuf/wrappers.proto",
+	"google.protobuf.UInt32Value": "google/protobuf/wrappers.proto",
+	"google.protobuf.BoolValue":   "google/protobuf/wrappers.proto",
+	"google.protobuf.StringValue": "google/protobuf/wrappers.proto",
+	"google.protobuf.BytesValue":  "google/protobuf/wrappers.proto",
+
+	// Options extended by custom options
+	"google.protobuf.FileOptions":      "google/protobuf/descriptor.proto",
+	"google.protobuf.MessageOptions":   "google/protobuf/descriptor.proto",
+	"google.protobuf.FieldOptions":     "google/protobuf/descriptor.proto",
+	"google.protobuf.OneofOptions":     "google/protobuf/descriptor.proto",
+	"google.protobuf.EnumOptions":      "google/protobuf/descriptor.proto",
+	"google.protobuf.EnumValueOptions": "google/protobuf/descriptor.proto",
+	"google.protobuf.ServiceOptions":   "google/protobuf/descriptor.proto",
+	"google.protobuf.MethodOptions":    "google/protobuf/descriptor.proto",
+
+	// googleapis common types
+	"google.type.Color":         "google/type/color.proto",
+	"google.type.Date":          "google/type/date.proto",
+	"google.type.DateTime":      "google/type/datetime.proto",
+	"google.type.TimeZone":      "google/type/datetime.proto",
+	"google.type.DayOfWeek":     "google/type/dayofweek.proto",
+	"google.type.Decimal":       "google/type/decimal.proto",
+	"google.type.Interval":      "google/type/interval.proto",
+	"google.type.LatLng":        "google/type/latlng.proto",
+	"google.type.Money":         "google/type/money.proto",
+	"google.type.Month":         "google/type/month.proto",
+	"google.type.PhoneNumber":   "google/type/phone_number.proto",
+	"google.type.PostalAddress": "google/type/postal_address.proto",
+	"google.type.TimeOfDay":     "google/type/timeofday.proto",
+}
+
+// useType records a type written to the current file, see collectImports
+func (g *Generator) useType(protoType string) {
+	if g.usedTypes != nil {
+		g.usedTypes[protoType] = true
+	}
+}
+
+// protoTypeImport returns the proto file declaring a type that isn't generated: the file given
+// for it or its package with type_imports or a custom mapping, or the file of a well-known type
+func (g *Generator) protoTypeImport(protoType string) (string, bool) {
+	protoType = strings.TrimPrefix(protoType, ".")
+	typeImports := g.formatGen.config.TypeImports
+	if file, ok := typeImports[protoType]; ok {
+		return file, true
+	}
+	if file, ok := g.mappedTypeImport(protoType); ok {
+		return file, true
+	}
+	if file, ok := wellKnownTypeFiles[protoType]; ok {
+		return file, true
+	}
+
+	// Types of a package declared with type_imports, the most specific package first
+	for pkg := protoType; strings.Contains(pkg, "."); {
+		pkg = pkg[:strings.LastIndex(pkg, ".")]
+		if file, ok := typeImports[pkg]; ok {
+			return file, true
+		}
+	}
+	return "", false
+}
+
+// typeImports returns the proto files declaring the external types written to the current file
+func (g *Generator) typeImports() []string {
+	var files []string
+	for _, protoType := range sortedKeys(g.usedTypes) {
+		if file, ok := g.protoTypeImport(protoType); ok {
+			files = append(files, file)
+		}
+	}
+	return files
+}
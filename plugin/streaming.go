@@ -0,0 +1,190 @@
+package plugin
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+)
+
+// Kinds of Go forms streaming the messages of an RPC
+const (
+	streamSlice    = "slice"     // []T, buffered in full (the form of annotated streams)
+	streamChan     = "chan"      // <-chan T parameter (requests) or result (responses)
+	streamSeq      = "seq"       // iter.Seq[T] parameter (requests) or result (responses)
+	streamSendChan = "send_chan" // chan<- T parameter the responses are sent to
+	streamCallback = "callback"  // func(T) error parameter the responses are sent through
+)
+
+// StreamForm is the Go form a method streams its requests or responses with
+type StreamForm struct {
+	Kind   string // One of the stream kinds
+	Format string // Go type with %s for the message type, e.g. <-chan %s
+}
+
+// streamElement returns the message type a parameter or result streams and its form. Channels
+// without direction stream requests as parameters and responses as results.
+func (g *Generator) streamElement(t ast.Expr, param bool) (ast.Expr, *StreamForm, bool) {
+	switch v := t.(type) {
+	case *ast.ChanType:
+		switch {
+		case v.Dir == ast.SEND && param:
+			return v.Value, &StreamForm{Kind: streamSendChan, Format: "chan<- %s"}, true
+		case v.Dir == ast.RECV:
+			return v.Value, &StreamForm{Kind: streamChan, Format: "<-chan %s"}, true
+		case v.Dir == ast.SEND|ast.RECV:
+			return v.Value, &StreamForm{Kind: streamChan, Format: "chan %s"}, true
+		}
+	case *ast.IndexExpr:
+		if g.getGoTypeName(v.X) == "iter.Seq" {
+			return v.Index, &StreamForm{Kind: streamSeq, Format: "iter.Seq[%s]"}, true
+		}
+	case *ast.FuncType:
+		// func(T) error
+		if !param || v.Params == nil || len(v.Params.List) != 1 || len(v.Params.List[0].Names) > 1 {
+			return nil, nil, false
+		}
+		if v.Results == nil || len(v.Results.List) != 1 || g.getGoTypeName(v.Results.List[0].Type) != "error" {
+			return nil, nil, false
+		}
+		return v.Params.List[0].Type, &StreamForm{Kind: streamCallback, Format: "func(%s) error"}, true
+	}
+	return nil, nil, false
+}
+
+// isResponseStream checks if a parameter form streams the responses
+func isResponseStream(form *StreamForm) bool {
+	return form.Kind == streamSendChan || form.Kind == streamCallback
+}
+
+// applyStreamForms makes a method streaming from its signature: <-chan T and iter.Seq[T]
+// parameters stream the requests, <-chan T and iter.Seq[T] results or chan<- T and func(T) error
+// parameters the responses. Slices of annotated streams are their buffered form.
+func (g *Generator) applyStreamForms(m *ProtoRPCMethod, params, results []ast.Expr, pkg, pkgPath string) {
+	request := false
+	for _, param := range params {
+		if paramType := g.getGoTypeName(param); paramType == "context.Context" || paramType == "*context.Context" {
+			continue
+		}
+		elem, form, ok := g.streamElement(param, true)
+		if ok && isResponseStream(form) {
+			if m.OutputStream == nil {
+				m.OutputStream = form
+				m.OriginalOutputType, m.OutputPackage, m.OutputType = g.streamedType(elem, m.Name, "Response", pkg, pkgPath)
+			}
+			continue
+		}
+		if !request {
+			request = true
+			if ok {
+				m.InputStream = form
+				m.OriginalInputType, m.InputPackage, m.InputType = g.streamedType(elem, m.Name, "Request", pkg, pkgPath)
+			}
+		}
+	}
+	if !request && m.OutputStream != nil {
+		// The only parameter sends the responses
+		m.InputType, m.OriginalInputType, m.InputPackage = "google.protobuf.Empty", "", ""
+	}
+
+	for _, result := range results {
+		if g.getGoTypeName(result) == "error" {
+			continue
+		}
+		if elem, form, ok := g.streamElement(result, false); ok && m.OutputStream == nil {
+			m.OutputStream = form
+			m.OriginalOutputType, m.OutputPackage, m.OutputType = g.streamedType(elem, m.Name, "Response", pkg, pkgPath)
+		}
+		break
+	}
+
+	if m.ClientStream && m.InputStream == nil {
+		m.InputStream = &StreamForm{Kind: streamSlice, Format: "[]%s"}
+	}
+	if m.ServerStream && m.OutputStream == nil {
+		m.OutputStream = &StreamForm{Kind: streamSlice, Format: "[]%s"}
+	}
+	m.ClientStream = m.InputStream != nil
+	m.ServerStream = m.OutputStream != nil
+	m.IsStreaming = m.ClientStream || m.ServerStream
+}
+
+// streamedType returns the Go type of the streamed messages as written outside of its package,
+// the package declaring it and its protobuf type
+func (g *Generator) streamedType(elem ast.Expr, methodName, suffix, pkg, pkgPath string) (string, string, string) {
+	goType := g.getGoTypeName(elem)
+	original, path := g.getQualifiedTypeName(goType, pkg, pkgPath)
+	protoType := strings.TrimPrefix(goType, "*")
+	if i := strings.LastIndex(protoType, "."); i >= 0 {
+		protoType = protoType[i+1:]
+	}
+	return original, path, g.wrapPrimitiveType(protoType, methodName, suffix)
+}
+
+// streamGoType spells the Go type streaming messages of a type in a form
+func streamGoType(form *StreamForm, goType string) string {
+	return fmt.Sprintf(form.Format, goType)
+}
+
+// streamSignature spells the signature of a streaming method with its Go stream types
+func (g *StubGenerator) streamSignature(m *MethodInfo, protoMethod ProtoRPCMethod) {
+	if !m.IsStreaming {
+		return
+	}
+
+	var params, args []string
+	if m.HasContext {
+		params = append(params, "ctx context.Context")
+		args = append(args, "ctx")
+	}
+	if form := protoMethod.InputStream; form != nil {
+		m.InputStream = form.Kind
+		params = append(params, "inputs "+streamGoType(form, m.OriginalInputType))
+		args = append(args, "inputs")
+	} else if m.OriginalInputType != "" {
+		params = append(params, "req "+m.OriginalInputType)
+		args = append(args, "req")
+	}
+
+	m.StreamResults = "error"
+	switch form := protoMethod.OutputStream; {
+	case form == nil:
+		if m.OriginalOutputType != "error" {
+			m.StreamResults = "(" + m.OriginalOutputType + ", error)"
+		}
+	case isResponseStream(form):
+		m.OutputStream = form.Kind
+		params = append(params, "outputs "+streamGoType(form, m.OriginalOutputType))
+		args = append(args, "outputs")
+	default:
+		m.OutputStream = form.Kind
+		m.StreamResults = "(" + streamGoType(form, m.OriginalOutputType) + ", error)"
+	}
+
+	m.StreamParams = strings.Join(params, ", ")
+	m.StreamArgs = strings.Join(args, ", ")
+}
+
+// drainsResponses checks if a streaming method of the services produces its responses on a
+// channel, which the adapters drain when the stream fails
+func (g *StubGenerator) drainsResponses() bool {
+	for _, service := range g.services {
+		for _, method := range service.Methods {
+			if method.OutputStream == streamChan || method.OutputStream == streamSendChan {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// usesStreamKind checks if a streaming method of the services uses a stream kind
+func (g *StubGenerator) usesStreamKind(kind string) bool {
+	for _, service := range g.services {
+		for _, method := range service.Methods {
+			if method.InputStream == kind || method.OutputStream == kind {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -12,6 +12,7 @@ func (g *StubGenerator) generateServiceAdapter() error {
 
 	// Use template-specific imports
 	templateData.PackageImports = g.getImportsForTemplate("adapter", templateData.PackageImports)
+	templateData.ProtobufImports = g.getProtobufImportsForTemplate("adapter", templateData)
 
 	// Execute adapter template
 	templateNames := templateConfig.GetTemplateNames()
@@ -267,6 +268,7 @@ func (g *StubGenerator) generateRegistrationHelpers() error {
 
 	// Use template-specific imports
 	templateData.PackageImports = g.getImportsForTemplate("registration", templateData.PackageImports)
+	templateData.ProtobufImports = g.getProtobufImportsForTemplate("registration", templateData)
 
 	// Execute registration template
 	templateNames := templateConfig.GetTemplateNames()
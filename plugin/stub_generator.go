@@ -22,6 +22,10 @@ type StubGenerator struct {
 	protoTypes      map[string]*TypeInfo
 	services        []*ServiceInfo
 	templateManager *TemplateManager
+	receivers       map[string]map[string]bool // Receiver kinds by source directory, see receiverKinds
+	packageAliases  map[string]string          // Import aliases by package path, see assignPackageAliases
+	protoPackages   map[string]*PackageImport  // Protobuf Go packages by namespace, see assignProtobufPackages
+	protoModule     string                     // Common module of the protobuf Go packages, see assignProtobufPackages
 
 	// Reference to main generator for parsed data
 	mainGenerator *Generator
@@ -32,13 +36,37 @@ type TypeInfo struct {
 	Name        string
 	Package     string
 	FullName    string
+	GoType      string // Go type spelled in the adapters, when it isn't <package>.<Name> (anonymous structs)
+	ProtoName   string // Go name of the generated protobuf type, when it isn't Name (nested messages)
+	Namespace   string // Namespace of the proto file declaring the type
 	Fields      []*FieldInfo
+	Unions      []*UnionInfo // @union interface fields, converted through a oneof
+	EnumValues  []*EnumValueInfo
 	IsMessage   bool
 	IsEnum      bool
 	IsService   bool
 	Annotations []annotations.Annotation
 }
 
+// protoScalarGoTypes maps protobuf scalar types to the Go types protoc generates for them
+var protoScalarGoTypes = map[string]string{
+	"double":   "float64",
+	"float":    "float32",
+	"int32":    "int32",
+	"int64":    "int64",
+	"uint32":   "uint32",
+	"uint64":   "uint64",
+	"sint32":   "int32",
+	"sint64":   "int64",
+	"fixed32":  "uint32",
+	"fixed64":  "uint64",
+	"sfixed32": "int32",
+	"sfixed64": "int64",
+	"bool":     "bool",
+	"string":   "string",
+	"bytes":    "[]byte",
+}
+
 // FieldInfo holds information about struct fields
 type FieldInfo struct {
 	Name         string // Field name from gonnotation (might be different from GoName)
@@ -53,16 +81,53 @@ type FieldInfo struct {
 	IsMap        bool
 	MapKeyType   string
 	MapValueType string
-	IsEmbedded   bool // If this is an embedded field
+	IsEmbedded   bool   // If this is an embedded field
+	Oneof        string // @proto.oneof group the field is a member of
+	ScalarType   string // Go type of the protobuf scalar a named primitive converts to (type UserID string -> string)
+	NamedType    string // Qualified Go type of a named primitive, for conversions from protobuf
+
+	goType      ast.Expr // Parsed Go type, anonymous structs replaced by their nested type
+	packagePath string   // Package declaring the field, its types are qualified with
+	wrapper     string   // Well-known wrapper of an optional scalar with optional_style: wrappers
+	presence    bool     // Value field whose protobuf field is a pointer tracking its presence
+
+	// Prefix of the helpers converting a list or map of collections, see collectCollectionConversions
+	collectionName string
+}
+
+// EnumValueInfo holds an enum value with its Go constant and protobuf value names
+type EnumValueInfo struct {
+	GoName       string
+	ProtoName    string
+	IsAlias      bool // Repeats the Go value of an earlier constant
+	IsProtoAlias bool // Repeats the proto number of an earlier value (allow_alias)
+}
+
+// UnionInfo holds a struct field whose type is an @union interface
+type UnionInfo struct {
+	GoName    string // Go field name
+	Interface string // Interface type, qualified when declared in another package
+	OneofName string // Oneof name in the schema
+	Variants  []*UnionVariantInfo
+}
+
+// UnionVariantInfo holds a variant of an @union interface
+type UnionVariantInfo struct {
+	TypeName  string // Implementing struct
+	Package   string // Package path of the struct
+	CaseName  string // Oneof case field name in the schema
+	IsPointer bool   // Whether only the pointer implements the interface (pointer receivers)
 }
 
 // ServiceInfo holds information about service interfaces
 type ServiceInfo struct {
-	Name     string
-	Package  string
-	FullName string
-	Methods  []*MethodInfo
-	IsStruct bool // true if it's a concrete struct, false if it's an interface
+	Name       string
+	Package    string // Import path of the package declaring the Go interface or struct
+	GoType     string // Go interface or struct, qualified with its package alias
+	ProtoAlias string // Alias of the protobuf Go package declaring the gRPC service
+	FullName   string
+	Methods    []*MethodInfo
+	IsStruct   bool // true if it's a concrete struct, false if it's an interface
 }
 
 // MethodInfo holds information about service methods
@@ -71,11 +136,26 @@ type MethodInfo struct {
 	InputType          string // Protobuf type (e.g., "google.protobuf.Int64Value")
 	OutputType         string // Protobuf type (e.g., "User")
 	OriginalInputType  string // Original Go type (e.g., "int64")
-	OriginalOutputType string // Original Go type (e.g., "*User")
+	OriginalOutputType string // Original Go type (e.g., "*models.User")
+	InputPackage       string // Import path of the package declaring the original input type
+	OutputPackage      string // Import path of the package declaring the original output type
+	InputProtoAlias    string // Alias of the protobuf Go package declaring the input message
+	OutputProtoAlias   string // Alias of the protobuf Go package declaring the output message
 	IsStreaming        bool
 	ClientStream       bool
 	ServerStream       bool
 	HasContext         bool // Whether original method has context.Context parameter
+
+	// Go forms of the streams, see the stream kinds: slice, chan or seq for the requests, and
+	// slice, chan, seq, send_chan or callback for the responses
+	InputStream  string
+	OutputStream string
+
+	// Signature of a streaming method and the arguments it's called with: ctx, req or inputs for
+	// the requests and outputs for a response parameter
+	StreamParams  string // e.g. ctx context.Context, inputs <-chan models.ChatMessage
+	StreamResults string // e.g. (iter.Seq[models.ChatMessage], error)
+	StreamArgs    string // e.g. ctx, inputs
 }
 
 // NewStubGenerator creates a new stub generator
@@ -87,6 +167,7 @@ func NewStubGenerator(config *StubConfig, pluginConfig *Config, ctx *goschemagen
 		originalTypes: make(map[string]*TypeInfo),
 		protoTypes:    make(map[string]*TypeInfo),
 		services:      make([]*ServiceInfo, 0),
+		receivers:     make(map[string]map[string]bool),
 		mainGenerator: mainGen,
 	}
 
@@ -112,6 +193,11 @@ func (g *StubGenerator) Generate() error {
 		return fmt.Errorf("failed to analyze original types: %w", err)
 	}
 
+	// Fields of mapped types and collections must be convertible before anything is written
+	if err := g.checkConversions(); err != nil {
+		return err
+	}
+
 	// Step 2: Generate type adapters
 	if err := g.generateTypeAdapters(); err != nil {
 		return fmt.Errorf("failed to generate type adapters: %w", err)
@@ -165,6 +251,11 @@ func (g *StubGenerator) generateAdapterFilesOnly() error {
 		return fmt.Errorf("failed to analyze original types: %w", err)
 	}
 
+	// Fields of mapped types and collections must be convertible before anything is written
+	if err := g.checkConversions(); err != nil {
+		return err
+	}
+
 	// Step 2: Generate type adapters
 	if err := g.generateTypeAdapters(); err != nil {
 		return fmt.Errorf("failed to generate type adapters: %w", err)
@@ -203,8 +294,19 @@ func (g *StubGenerator) generateAdapterFilesOnly() error {
 	return nil
 }
 
+// checkConversions checks that the fields of the analyzed types can be converted by the adapters
+func (g *StubGenerator) checkConversions() error {
+	if err := g.checkTypeMappings(); err != nil {
+		return err
+	}
+	return g.checkCollections()
+}
+
 // analyzeOriginalTypes scans the parsed context for types with protobuf annotations
 func (g *StubGenerator) analyzeOriginalTypes() error {
+	g.assignProtobufPackages()
+	g.assignPackageAliases()
+
 	// Include ALL structs from context - they were already filtered by the main generator
 	for _, structInfo := range g.ctx.Structs {
 		// Skip service structs - they don't need conversion functions
@@ -219,6 +321,11 @@ func (g *StubGenerator) analyzeOriginalTypes() error {
 			continue
 		}
 
+		// Skip struct-level extensions - they don't generate a message
+		if _, ok := getStructExtendee(structInfo); ok {
+			continue
+		}
+
 		// Skip generic types (they can't be converted to protobuf)
 		if strings.Contains(structInfo.Name, "[") || len(structInfo.Annotations) == 0 {
 			g.ctx.Logger.Debug(fmt.Sprintf("Skipping type %s (generic or no annotations)", structInfo.Name))
@@ -230,31 +337,25 @@ func (g *StubGenerator) analyzeOriginalTypes() error {
 			Name:        structInfo.Name,
 			Package:     structInfo.PackagePath,
 			FullName:    fmt.Sprintf("%s.%s", structInfo.PackagePath, structInfo.Name),
+			Namespace:   structInfo.Namespace,
 			IsMessage:   true,
 			Annotations: structInfo.Annotations,
 			Fields:      make([]*FieldInfo, 0),
 		}
 
-		// Analyze fields
-		for _, field := range structInfo.Fields {
-			fieldInfo := g.analyzeField(field)
-			typeInfo.Fields = append(typeInfo.Fields, fieldInfo)
+		// Structs declared with @message(nested_in=...) are generated as Parent_Child by protoc
+		if g.mainGenerator.getNestedIn(structInfo) != "" {
+			typeInfo.ProtoName = strings.ReplaceAll(g.mainGenerator.qualifiedNestedName(structInfo), ".", "_")
 		}
 
+		// Analyze fields
+		g.analyzeStructFields(typeInfo, structInfo, structInfo.Fields)
+
 		g.originalTypes[typeInfo.Name] = typeInfo
 		g.ctx.Logger.Debug(fmt.Sprintf("Added type for conversion: %s", typeInfo.Name))
 	} // Add enums from context
 	for _, enumInfo := range g.ctx.Enums {
-		typeInfo := &TypeInfo{
-			Name:        enumInfo.Name,
-			Package:     enumInfo.PackagePath,
-			FullName:    fmt.Sprintf("%s.%s", enumInfo.PackagePath, enumInfo.Name),
-			IsMessage:   false,
-			IsEnum:      true, // Mark as enum
-			Annotations: enumInfo.Annotations,
-			Fields:      make([]*FieldInfo, 0),
-		}
-
+		typeInfo := g.newEnumTypeInfo(enumInfo)
 		g.originalTypes[typeInfo.Name] = typeInfo
 		g.ctx.Logger.Debug(fmt.Sprintf("Added enum for conversion: %s", typeInfo.Name))
 	}
@@ -263,26 +364,36 @@ func (g *StubGenerator) analyzeOriginalTypes() error {
 	parsedServices := g.mainGenerator.GetParsedServices()
 	for _, protoService := range parsedServices {
 		serviceInfo := &ServiceInfo{
-			Name:     protoService.Name,
-			Package:  "", // Will be filled from context
-			FullName: protoService.Name,
-			Methods:  make([]*MethodInfo, 0),
-			IsStruct: protoService.IsStruct,
+			Name:       protoService.Name,
+			Package:    protoService.PackagePath,
+			GoType:     protoService.GoName,
+			ProtoAlias: g.protobufAlias(protoService.Namespace),
+			FullName:   protoService.Name,
+			Methods:    make([]*MethodInfo, 0),
+			IsStruct:   protoService.IsStruct,
+		}
+		if protoService.PackagePath != "" {
+			serviceInfo.GoType = g.getPackageAlias(protoService.PackagePath) + "." + protoService.GoName
 		}
 
 		// Convert proto methods to stub methods
 		for _, protoMethod := range protoService.Methods {
 			methodInfo := &MethodInfo{
 				Name:               protoMethod.Name,
-				InputType:          protoMethod.InputType,
-				OutputType:         protoMethod.OutputType,
-				OriginalInputType:  protoMethod.OriginalInputType,
-				OriginalOutputType: protoMethod.OriginalOutputType,
+				InputType:          g.messageTypeName(protoMethod.InputType),
+				OutputType:         g.messageTypeName(protoMethod.OutputType),
+				OriginalInputType:  g.aliasQualifiedType(protoMethod.OriginalInputType, protoMethod.InputPackage),
+				OriginalOutputType: g.aliasQualifiedType(protoMethod.OriginalOutputType, protoMethod.OutputPackage),
+				InputPackage:       protoMethod.InputPackage,
+				OutputPackage:      protoMethod.OutputPackage,
+				InputProtoAlias:    g.messageProtobufAlias(g.messageTypeName(protoMethod.InputType), protoService.Namespace),
+				OutputProtoAlias:   g.messageProtobufAlias(g.messageTypeName(protoMethod.OutputType), protoService.Namespace),
 				IsStreaming:        protoMethod.IsStreaming,
 				ClientStream:       protoMethod.ClientStream,
 				ServerStream:       protoMethod.ServerStream,
 				HasContext:         protoMethod.HasContext,
 			}
+			g.streamSignature(methodInfo, protoMethod)
 			serviceInfo.Methods = append(serviceInfo.Methods, methodInfo)
 		}
 
@@ -293,15 +404,7 @@ func (g *StubGenerator) analyzeOriginalTypes() error {
 	for _, enumInfo := range g.ctx.Enums {
 		for _, ann := range enumInfo.Annotations {
 			if g.isEnumAnnotation(&ann) {
-				typeInfo := &TypeInfo{
-					Name:        enumInfo.Name,
-					Package:     enumInfo.PackagePath,
-					FullName:    fmt.Sprintf("%s.%s", enumInfo.PackagePath, enumInfo.Name),
-					IsEnum:      true,
-					Annotations: enumInfo.Annotations,
-					Fields:      make([]*FieldInfo, 0), // Enums don't have fields
-				}
-
+				typeInfo := g.newEnumTypeInfo(enumInfo)
 				g.originalTypes[typeInfo.Name] = typeInfo
 				break
 			}
@@ -319,6 +422,246 @@ func (g *StubGenerator) analyzeOriginalTypes() error {
 	return nil
 }
 
+// newEnumTypeInfo describes an enum for conversion. Its values are kept so adapters convert
+// through lookup tables, since proto numbers don't follow the Go values once prefixed,
+// shifted by enum_style or backed by strings.
+func (g *StubGenerator) newEnumTypeInfo(enumInfo *parser.EnumInfo) *TypeInfo {
+	typeInfo := &TypeInfo{
+		Name:        enumInfo.Name,
+		Package:     enumInfo.PackagePath,
+		FullName:    fmt.Sprintf("%s.%s", enumInfo.PackagePath, enumInfo.Name),
+		ProtoName:   g.mainGenerator.getEnumName(enumInfo),
+		Namespace:   enumInfo.Namespace,
+		IsEnum:      true,
+		Annotations: enumInfo.Annotations,
+		Fields:      make([]*FieldInfo, 0), // Enums don't have fields
+	}
+
+	aliases := g.mainGenerator.enumAliases(enumInfo)
+	numbers := make(map[int]bool)
+	for i, v := range enumInfo.Values {
+		// Proto aliases (allow_alias) share the number of an earlier value
+		number := g.mainGenerator.getEnumValueNumber(enumInfo, i, typeInfo.ProtoName)
+		isProtoAlias := numbers[number]
+		numbers[number] = true
+
+		if v.Name == "" || v.Name[0] < 'A' || v.Name[0] > 'Z' {
+			continue // unexported constants can't be referenced from the adapters
+		}
+		typeInfo.EnumValues = append(typeInfo.EnumValues, &EnumValueInfo{
+			GoName:       v.Name,
+			ProtoName:    g.mainGenerator.getEnumValueName(v, typeInfo.ProtoName),
+			IsAlias:      aliases[v.Name],
+			IsProtoAlias: isProtoAlias,
+		})
+	}
+	return typeInfo
+}
+
+// analyzeStructFields analyzes the fields of a struct. Anonymous struct fields are registered as
+// types of their own, named after the protobuf type of their nested message (Parent_Field),
+// and @union interface fields are collected apart since they are converted through a oneof.
+func (g *StubGenerator) analyzeStructFields(typeInfo *TypeInfo, parent *parser.StructInfo, fields []*parser.FieldInfo) {
+	messageName := g.mainGenerator.getMessageName(parent, "")
+	if typeInfo.GoType != "" {
+		messageName = g.protoTypeName(typeInfo)
+	}
+	oneofs := make(map[*parser.FieldInfo]string)
+	for groupName, members := range g.mainGenerator.groupFieldsByOneof(fields, messageName) {
+		for _, member := range members {
+			oneofs[member] = groupName
+		}
+	}
+
+	for _, field := range fields {
+		if oneofName, cases := g.mainGenerator.unionCases(field); len(cases) > 0 {
+			typeInfo.Unions = append(typeInfo.Unions, g.analyzeUnion(field, oneofName, cases))
+			continue
+		}
+
+		// Aliases are converted as the type they stand for
+		oneofName := oneofs[field]
+		if unaliased := g.mainGenerator.unaliasType(field.Type); unaliased != field.Type {
+			aliased := *field
+			aliased.Type = unaliased
+			field = &aliased
+		}
+
+		fieldInfo := g.analyzeField(field)
+		fieldInfo.goType = field.Type
+		fieldInfo.packagePath = parent.PackagePath
+		fieldInfo.IsOptional = g.mainGenerator.isOptional(field)
+		fieldInfo.wrapper, _ = g.mainGenerator.optionalWrapper(field)
+		fieldInfo.Oneof = oneofName
+		if _, isPointer := field.Type.(*ast.StarExpr); !isPointer && fieldInfo.Oneof == "" {
+			fieldInfo.presence = g.mainGenerator.hasPointerPresence(field)
+		}
+
+		// Named primitives are converted to and from the scalar type of the protobuf field
+		if resolved, primitive, ok := g.mainGenerator.resolveNamedType(fieldInfo.Type); ok && primitive {
+			if scalar, ok := protoScalarGoTypes[g.mainGenerator.mapGoTypeToProto(&ast.Ident{Name: resolved})]; ok {
+				fieldInfo.ScalarType = scalar
+				fieldInfo.NamedType = fieldInfo.Type
+				if !strings.Contains(fieldInfo.NamedType, ".") {
+					fieldInfo.NamedType = g.getPackageAlias(parent.PackagePath) + "." + fieldInfo.NamedType
+				}
+			}
+		} else if builtin, ok := g.mainGenerator.builtinType(fieldInfo.Type); ok && builtin.scalar != "" {
+			// So are the builtin types converted with a type conversion (int8, net.IP)
+			fieldInfo.ScalarType = builtin.scalar
+			fieldInfo.NamedType = g.aliasQualifiedType(fieldInfo.Type, builtin.path)
+		}
+
+		// Nested collections convert to the wrapper messages declared for them
+		if isNestedCollection(field.Type) {
+			fieldInfo.collectionName = g.collectionHelpers(typeInfo, field.Type)
+		}
+
+		if st := anonymousStruct(field.Type); st != nil {
+			nestedName := g.protoTypeName(typeInfo) + "_" + field.GoName
+			nested := &TypeInfo{
+				Name:      nestedName,
+				Package:   parent.PackagePath,
+				FullName:  fmt.Sprintf("%s.%s", parent.PackagePath, nestedName),
+				GoType:    g.anonymousStructGoType(st, g.getPackageAlias(parent.PackagePath)),
+				Namespace: parent.Namespace,
+				IsMessage: true,
+				Fields:    make([]*FieldInfo, 0),
+			}
+			g.analyzeStructFields(nested, parent, anonymousStructFields(st))
+			g.originalTypes[nestedName] = nested
+
+			fieldInfo.goType = replaceAnonymousStruct(field.Type, nestedName)
+			fieldInfo.Type = g.getGoTypeName(fieldInfo.goType)
+			fieldInfo.ProtoType = nestedName
+		}
+		typeInfo.Fields = append(typeInfo.Fields, fieldInfo)
+	}
+}
+
+// analyzeUnion describes a field of an @union interface type and its variants
+func (g *StubGenerator) analyzeUnion(field *parser.FieldInfo, oneofName string, cases []unionCase) *UnionInfo {
+	iface := g.mainGenerator.findUnion(field.Type)
+	union := &UnionInfo{
+		GoName:    field.GoName,
+		Interface: g.getGoTypeName(field.Type),
+		OneofName: oneofName,
+	}
+	for _, c := range cases {
+		union.Variants = append(union.Variants, &UnionVariantInfo{
+			TypeName:  c.Variant.Name,
+			Package:   c.Variant.PackagePath,
+			CaseName:  g.mainGenerator.getFieldName(c.Field),
+			IsPointer: g.implementsWithPointer(c.Variant, iface),
+		})
+	}
+	return union
+}
+
+// implementsWithPointer checks if a variant implements its union only as a pointer,
+// i.e. one of the interface methods has a pointer receiver
+func (g *StubGenerator) implementsWithPointer(variant *parser.StructInfo, iface *parser.InterfaceInfo) bool {
+	if variant.SourceFile == "" {
+		return false
+	}
+
+	dir := filepath.Dir(variant.SourceFile)
+	kinds, ok := g.receivers[dir]
+	if !ok {
+		kinds = receiverKinds(dir)
+		g.receivers[dir] = kinds
+	}
+
+	for _, method := range iface.Methods {
+		if kinds[variant.Name+"."+method.Name] {
+			return true
+		}
+	}
+	return false
+}
+
+// protoTypeName returns the Go name of the protobuf type generated for a type
+func (g *StubGenerator) protoTypeName(typeInfo *TypeInfo) string {
+	if typeInfo.ProtoName != "" {
+		return typeInfo.ProtoName
+	}
+	return typeInfo.Name
+}
+
+// messageTypeName returns the Go name of the protobuf message the request or response type of
+// an RPC refers to, for types of other Go packages (billing.Invoice -> Invoice) and nested
+// messages (Settings -> User_Settings)
+func (g *StubGenerator) messageTypeName(protoType string) string {
+	if strings.HasPrefix(protoType, "google.protobuf.") {
+		return protoType
+	}
+	name := protoType
+	if _, typeName, qualified := strings.Cut(protoType, "."); qualified {
+		name = typeName
+	}
+	if typeInfo, ok := g.originalTypes[name]; ok {
+		return g.protoTypeName(typeInfo)
+	}
+	return protoType
+}
+
+// anonymousStructGoType spells an anonymous struct type the way the adapter package must write it
+// to be identical to the original: same field names, types and tags, with the named types of the
+// source package qualified with its alias
+func (g *StubGenerator) anonymousStructGoType(st *ast.StructType, pkgAlias string) string {
+	if st.Fields == nil || len(st.Fields.List) == 0 {
+		return "struct{}"
+	}
+
+	fields := make([]string, 0, len(st.Fields.List))
+	for _, field := range st.Fields.List {
+		var decl strings.Builder
+		for i, name := range field.Names {
+			if i > 0 {
+				decl.WriteString(", ")
+			}
+			decl.WriteString(name.Name)
+		}
+		if len(field.Names) > 0 {
+			decl.WriteString(" ")
+		}
+		decl.WriteString(g.qualifiedGoType(field.Type, pkgAlias))
+		if field.Tag != nil {
+			decl.WriteString(" " + field.Tag.Value)
+		}
+		fields = append(fields, decl.String())
+	}
+	return "struct{ " + strings.Join(fields, "; ") + " }"
+}
+
+// qualifiedGoType spells a type expression of the source package from the adapter package
+func (g *StubGenerator) qualifiedGoType(t ast.Expr, pkgAlias string) string {
+	switch v := t.(type) {
+	case *ast.Ident:
+		if ast.IsExported(v.Name) {
+			return pkgAlias + "." + v.Name
+		}
+		return v.Name
+	case *ast.StarExpr:
+		return "*" + g.qualifiedGoType(v.X, pkgAlias)
+	case *ast.ArrayType:
+		if lit, ok := v.Len.(*ast.BasicLit); ok {
+			return "[" + lit.Value + "]" + g.qualifiedGoType(v.Elt, pkgAlias)
+		}
+		return "[]" + g.qualifiedGoType(v.Elt, pkgAlias)
+	case *ast.MapType:
+		return "map[" + g.qualifiedGoType(v.Key, pkgAlias) + "]" + g.qualifiedGoType(v.Value, pkgAlias)
+	case *ast.SelectorExpr:
+		goType := g.getGoTypeName(v)
+		return g.aliasQualifiedType(goType, g.mappedTypePath(goType))
+	case *ast.StructType:
+		return g.anonymousStructGoType(v, pkgAlias)
+	case *ast.InterfaceType:
+		return "interface{}"
+	}
+	return g.getGoTypeName(t)
+}
+
 // analyzeField extracts field information for type mapping
 func (g *StubGenerator) analyzeField(field *parser.FieldInfo) *FieldInfo {
 	fieldInfo := &FieldInfo{
@@ -652,6 +995,9 @@ func (g *StubGenerator) getGoTypeName(t ast.Expr) string {
 	case *ast.StarExpr:
 		return "*" + g.getGoTypeName(v.X)
 	case *ast.ArrayType:
+		if length, ok := fixedByteArrayLen(v); ok {
+			return "[" + length + "]byte"
+		}
 		return "[]" + g.getGoTypeName(v.Elt)
 	case *ast.SelectorExpr:
 		return g.getGoTypeName(v.X) + "." + v.Sel.Name
@@ -689,7 +1035,7 @@ func (g *StubGenerator) getTemplateConfig() *TemplateConfig {
 	// Auto-detect protobuf package from options.go_package
 	if templateConfig.ProtobufPackage == "" && g.pluginConfig != nil && g.pluginConfig.Options != nil {
 		if goPackage, exists := g.pluginConfig.Options["go_package"]; exists {
-			templateConfig.ProtobufPackage = g.resolveProtobufPackage(goPackage)
+			templateConfig.ProtobufPackage = g.resolveProtobufPackage(g.mainGenerator.expandNamespace(goPackage))
 		}
 	}
 
@@ -807,10 +1153,13 @@ func (g *StubGenerator) generateProtobufGoFiles() error {
 	goPackage := templateConfig.ProtobufPackage
 	if g.pluginConfig != nil && g.pluginConfig.Options != nil {
 		if pkg, ok := g.pluginConfig.Options["go_package"]; ok && pkg != "" {
-			goPackage = pkg
+			goPackage = g.mainGenerator.expandNamespace(pkg)
 		}
 	}
 
+	// Namespaces with a go_package of their own are written relative to the common module
+	goPackage = g.protobufModule(goPackage)
+
 	// Determine output directory for protobuf Go files
 	outputDir := g.config.OutputDir
 	if outputDir == "" {
@@ -0,0 +1,270 @@
+package plugin
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PackageImport is an import of the generated Go files. Alias is empty when the package
+// is referenced by its own name.
+type PackageImport struct {
+	Alias string
+	Path  string
+}
+
+// reservedImportNames are the names the templates already use for their own imports
+var reservedImportNames = map[string]bool{
+	"context": true, "io": true, "log": true, "fmt": true, "grpc": true,
+	"emptypb": true, "wrapperspb": true, "timestamppb": true, "durationpb": true, "reflect": true,
+}
+
+// assignPackageAliases gives each package referenced by the adapters a unique alias: its
+// package name, or the parent directory and package name when two packages share a name
+// (internal/user/models, internal/billing/models -> usermodels, billingmodels)
+func (g *StubGenerator) assignPackageAliases() {
+	names := make(map[string]string)
+	for _, s := range g.ctx.Structs {
+		names[s.PackagePath] = s.Package
+	}
+	for _, e := range g.ctx.Enums {
+		names[e.PackagePath] = e.Package
+	}
+	for _, iface := range g.ctx.Interfaces {
+		names[iface.PackagePath] = iface.Package
+	}
+	for _, service := range g.mainGenerator.GetParsedServices() {
+		names[service.PackagePath] = names[service.PackagePath]
+		for _, method := range service.Methods {
+			names[method.InputPackage] = names[method.InputPackage]
+			names[method.OutputPackage] = names[method.OutputPackage]
+		}
+	}
+	for _, path := range append(g.mappedPackagePaths(), g.builtinPackagePaths()...) {
+		names[path] = names[path]
+	}
+	delete(names, "")
+
+	paths := make([]string, 0, len(names))
+	for path := range names {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	taken := make(map[string]bool)
+	for name := range reservedImportNames {
+		taken[name] = true
+	}
+	taken[g.getTemplateConfig().ProtobufAlias] = true
+	for _, pkg := range g.protoPackages {
+		taken[pkg.Alias] = true
+	}
+
+	g.packageAliases = make(map[string]string, len(paths))
+	for _, path := range paths {
+		segments := strings.Split(path, "/")
+		name := names[path]
+		if name == "" {
+			name = sanitizeImportName(segments[len(segments)-1])
+		}
+
+		alias := name
+		if taken[alias] && isStandardLibraryPackage(path) && reservedImportNames[alias] {
+			// Standard packages the templates import themselves keep their name
+			g.packageAliases[path] = alias
+			continue
+		}
+		if taken[alias] && len(segments) > 1 {
+			alias = sanitizeImportName(segments[len(segments)-2]) + name
+		}
+		for i := 2; taken[alias]; i++ {
+			alias = name + strconv.Itoa(i)
+		}
+		taken[alias] = true
+		g.packageAliases[path] = alias
+	}
+}
+
+// sanitizeImportName turns a path segment into a valid package identifier (go-money -> gomoney)
+func sanitizeImportName(segment string) string {
+	var result strings.Builder
+	for _, r := range strings.ToLower(segment) {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9' && result.Len() > 0) {
+			result.WriteRune(r)
+		}
+	}
+	if result.Len() == 0 {
+		return "pkg"
+	}
+	return result.String()
+}
+
+// getPackageAlias returns the alias a package is referenced by in the generated files
+func (g *StubGenerator) getPackageAlias(packagePath string) string {
+	if alias, ok := g.packageAliases[packagePath]; ok {
+		return alias
+	}
+	parts := strings.Split(packagePath, "/")
+	return sanitizeImportName(parts[len(parts)-1])
+}
+
+// packageImport returns the import of a package, aliased when the alias differs from its name
+func (g *StubGenerator) packageImport(packagePath string) *PackageImport {
+	alias := g.getPackageAlias(packagePath)
+	parts := strings.Split(packagePath, "/")
+	if alias == parts[len(parts)-1] {
+		alias = ""
+	}
+	return &PackageImport{Alias: alias, Path: packagePath}
+}
+
+// aliasQualifiedType rewrites the package qualifier of a Go type (*[]user.User) with the
+// alias of its package, keeping pointer and slice prefixes
+func (g *StubGenerator) aliasQualifiedType(goType, packagePath string) string {
+	if packagePath == "" {
+		return goType
+	}
+	base := strings.TrimLeft(goType, "*[]")
+	prefix := goType[:len(goType)-len(base)]
+	if _, name, qualified := strings.Cut(base, "."); qualified {
+		return prefix + g.getPackageAlias(packagePath) + "." + name
+	}
+	return goType
+}
+
+// assignProtobufPackages resolves the protobuf Go packages the adapters import. With the
+// namespace strategy, namespaces with a go_package of their own get a package aliased after
+// them (billing -> billingpb); the other types use the default package.
+func (g *StubGenerator) assignProtobufPackages() {
+	g.protoPackages = make(map[string]*PackageImport)
+	g.protoModule = ""
+
+	namespaced := g.mainGenerator.namespaceGoPackages()
+	if len(namespaced) == 0 {
+		return
+	}
+
+	config := g.getTemplateConfig()
+	goPackages := make([]string, 0, len(namespaced)+1)
+	if config.ProtobufPackage != "" {
+		goPackages = append(goPackages, config.ProtobufPackage)
+	}
+	for _, namespace := range sortedKeys(namespaced) {
+		goPackages = append(goPackages, namespaced[namespace])
+	}
+	g.protoModule = commonImportPath(goPackages)
+
+	taken := map[string]bool{config.ProtobufAlias: true}
+	for _, namespace := range sortedKeys(namespaced) {
+		name := sanitizeImportName(namespace) + "pb"
+		alias := name
+		for i := 2; taken[alias]; i++ {
+			alias = name + strconv.Itoa(i)
+		}
+		taken[alias] = true
+		g.protoPackages[namespace] = &PackageImport{
+			Alias: alias,
+			Path:  g.localProtobufPackage(namespaced[namespace]),
+		}
+	}
+}
+
+// protobufAlias returns the alias of the protobuf Go package of a namespace
+func (g *StubGenerator) protobufAlias(namespace string) string {
+	if pkg, ok := g.protoPackages[namespace]; ok {
+		return pkg.Alias
+	}
+	return g.getTemplateConfig().ProtobufAlias
+}
+
+// messageProtobufAlias returns the alias of the protobuf Go package declaring a message used by
+// an RPC, the package of the service when the message isn't a converted type
+func (g *StubGenerator) messageProtobufAlias(protoType, serviceNamespace string) string {
+	for _, typeInfo := range g.originalTypes {
+		if typeInfo.Name == protoType || g.protoTypeName(typeInfo) == protoType {
+			return g.protobufAlias(typeInfo.Namespace)
+		}
+	}
+	return g.protobufAlias(serviceNamespace)
+}
+
+// localProtobufPackage returns the import path adapters use for a go_package. With an output_dir,
+// protoc writes the Go files under it relative to the common module of the go_packages (see
+// generateProtobufGoFiles): github.com/acme/proto/v1 -> github.com/acme/proto/<output_dir>.
+func (g *StubGenerator) localProtobufPackage(goPackage string) string {
+	if g.config == nil || g.config.OutputDir == "" {
+		return goPackage
+	}
+	module := g.protobufModule(goPackage)
+	parts := strings.Split(module, "/")
+	if len(parts) < 2 {
+		return goPackage
+	}
+	return strings.Join(parts[:len(parts)-1], "/") + "/" + g.config.OutputDir + strings.TrimPrefix(goPackage, module)
+}
+
+// protobufModule returns the module protoc resolves output paths against: the common path of
+// all the go_packages when namespaces have their own, otherwise the go_package itself
+func (g *StubGenerator) protobufModule(goPackage string) string {
+	if g.protoModule != "" {
+		return g.protoModule
+	}
+	return goPackage
+}
+
+// commonImportPath returns the longest import path all the paths are nested in
+func commonImportPath(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+	common := strings.Split(paths[0], "/")
+	for _, path := range paths[1:] {
+		parts := strings.Split(path, "/")
+		n := 0
+		for n < len(common) && n < len(parts) && common[n] == parts[n] {
+			n++
+		}
+		common = common[:n]
+	}
+	return strings.Join(common, "/")
+}
+
+// getProtobufImportsForTemplate returns the protobuf Go packages a template references: those of
+// the converted types for the type adapters, those of the services (and of their messages for
+// adapters and clients) otherwise
+func (g *StubGenerator) getProtobufImportsForTemplate(templateName string, data *TemplateData) []*PackageImport {
+	aliases := make(map[string]bool)
+	switch templateName {
+	case "types":
+		for _, typeInfo := range data.Types {
+			aliases[typeInfo.ProtoAlias] = true
+		}
+	case "adapter", "client", "registration":
+		for _, service := range data.Services {
+			aliases[service.ProtoAlias] = true
+			if templateName != "adapter" {
+				continue
+			}
+			// Streamed messages are received and sent through the service stream types
+			for _, method := range service.Methods {
+				if !strings.HasPrefix(method.InputType, "google.protobuf.") && !method.ClientStream {
+					aliases[method.InputProtoAlias] = true
+				}
+				if !strings.HasPrefix(method.OutputType, "google.protobuf.") && !method.IsStreaming {
+					aliases[method.OutputProtoAlias] = true
+				}
+			}
+		}
+	}
+
+	var imports []*PackageImport
+	if aliases[data.ProtobufAlias] && data.ProtobufPackage != "" {
+		imports = append(imports, &PackageImport{Alias: data.ProtobufAlias, Path: data.ProtobufPackage})
+	}
+	for _, namespace := range sortedKeys(g.protoPackages) {
+		if pkg := g.protoPackages[namespace]; aliases[pkg.Alias] {
+			imports = append(imports, pkg)
+		}
+	}
+	return imports
+}
@@ -12,6 +12,7 @@ func (g *StubGenerator) generateTypeAdapters() error {
 
 	// Use template-specific imports
 	templateData.PackageImports = g.getImportsForTemplate("types", templateData.PackageImports)
+	templateData.ProtobufImports = g.getProtobufImportsForTemplate("types", templateData)
 
 	// Execute types template
 	templateNames := templateConfig.GetTemplateNames()
@@ -355,6 +356,7 @@ func (g *StubGenerator) generateClient() error {
 
 	// Use template-specific imports
 	templateData.PackageImports = g.getImportsForTemplate("client", templateData.PackageImports)
+	templateData.ProtobufImports = g.getProtobufImportsForTemplate("client", templateData)
 
 	// Execute client template
 	templateNames := templateConfig.GetTemplateNames()
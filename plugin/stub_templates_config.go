@@ -3,8 +3,10 @@ package plugin
 import (
 	"bytes"
 	"fmt"
+	"go/ast"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 )
 
@@ -13,18 +15,74 @@ type TemplateData struct {
 	ModulePath      string
 	ProtobufPackage string
 	ProtobufAlias   string
-	PackageImports  []string // Dynamic package imports
+	ProtobufImports []*PackageImport // Protobuf Go packages used by the template, several with per-namespace go_packages
+	PackageImports  []*PackageImport // Dynamic package imports, aliased when package names collide
 	Types           []*TemplateTypeInfo
 	Services        []*ServiceInfo
 	MapConversions  []*MapConversionInfo
+
+	// Conversions of the slices of named and narrow scalars, converted one element at a time
+	SliceConversions []*SliceConversionInfo
+
+	// Pointer and slice conversions of the custom mapped types
+	MappedConversions []*MappedConversionInfo
+
+	// Package alias of the builtin types converted by the helpers of the types template, by Go type
+	BuiltinTypes map[string]string
+
+	// Conversions of the lists and maps of collections, to and from their wrapper messages
+	CollectionConversions []*CollectionConversionInfo
+
+	// Time types convert to Timestamp and Duration rather than string and int64
+	PreserveTimeTypes bool
+
+	// Conversions of the optional scalar and enum fields
+	OptionalConversions []*OptionalConversionInfo
+
+	// Server streams send responses received from a channel, drained when the stream fails
+	DrainsResponses bool
 }
 
 // TemplateTypeInfo represents type information for templates
 type TemplateTypeInfo struct {
 	Name         string
 	PackageAlias string
+	GoType       string // Original Go type, e.g. models.User (anonymous structs are spelled out)
+	ProtoName    string // Go name of the protobuf type, e.g. User_Address for nested messages
+	ProtoAlias   string // Alias of the protobuf Go package declaring the type, e.g. pb
 	IsEnum       bool
+	EnumValues   []*TemplateEnumValue // Enum values, converted through lookup tables
 	Fields       []*TemplateFieldInfo
+	Unions       []*TemplateUnionInfo
+	Oneofs       []*TemplateOneofInfo
+}
+
+// TemplateEnumValue represents an enum value
+type TemplateEnumValue struct {
+	GoValue      string // Original Go constant, e.g. models.StatusActive
+	ProtoValue   string // Protobuf Go constant, e.g. Status_STATUS_ACTIVE
+	IsAlias      bool   // Repeats the Go value of an earlier constant, left out of the ToProto table
+	IsProtoAlias bool   // Repeats the proto number of an earlier value, left out of the FromProto table
+}
+
+// TemplateUnionInfo represents an @union interface field, converted through a oneof
+type TemplateUnionInfo struct {
+	GoName        string // Go field name
+	InterfaceType string // Original interface type, e.g. models.Shape
+	OneofName     string // Oneof name in the schema
+	OneofField    string // Protobuf Go struct field holding the oneof
+	ToProtoFunc   string
+	FromProtoFunc string
+	Variants      []*TemplateUnionVariant
+}
+
+// TemplateUnionVariant represents a variant of an @union interface
+type TemplateUnionVariant struct {
+	Name        string // Variant type name, prefix of its conversion functions
+	GoType      string // Original Go type, e.g. models.Circle
+	WrapperType string // Protobuf oneof wrapper type, e.g. Event_Circle
+	CaseField   string // Field of the wrapper type, e.g. Circle
+	IsPointer   bool   // Only the pointer implements the interface
 }
 
 // TemplateFieldInfo represents field information for templates
@@ -38,6 +96,7 @@ type TemplateFieldInfo struct {
 	Tag            string // Struct tag
 	IsRepeated     bool
 	IsEmbedded     bool
+	Oneof          string // @proto.oneof group, the field is set through the helpers of the oneof
 	// Conversion functions
 	ToProtoConversion   string // Function to convert from original to protobuf
 	FromProtoConversion string // Function to convert from protobuf to original
@@ -49,9 +108,22 @@ type MapConversionInfo struct {
 	FromProtoFuncName   string
 	OriginalType        string
 	ProtoType           string
-	ValueIsPointer      bool
-	ValueConversionFunc string
-	ValueFromProtoFunc  string
+	KeyToProto          string // Conversion of the key k
+	KeyFromProto        string
+	ValueToProto        string // Conversion of the value v, *v for pointers
+	ValueFromProto      string
+	ValueIsPointer      bool // Nil values are skipped, the others converted through the value they point to
+	ProtoValueIsMessage bool // Protobuf values are messages, nil ones are skipped
+}
+
+// SliceConversionInfo holds the conversions of a slice whose elements convert one at a time
+type SliceConversionInfo struct {
+	ToProtoFuncName   string
+	FromProtoFuncName string
+	OriginalType      string
+	ProtoType         string
+	ValueToProto      string // Conversion of the element v
+	ValueFromProto    string
 }
 
 // executeTemplateByName executes a template by name using the template manager
@@ -113,25 +185,14 @@ func (g *StubGenerator) prepareTemplateData(config *TemplateConfig) *TemplateDat
 	protobufPackage := config.ProtobufPackage
 	if protobufPackage == "" && g.pluginConfig != nil {
 		if goPackage, ok := g.pluginConfig.Options["go_package"]; ok {
-			protobufPackage = goPackage
+			protobufPackage = g.mainGenerator.expandNamespace(goPackage)
 		}
 	}
 
 	// For adapters, use local import path instead of external go_package
 	// This allows adapters to import from the locally generated protobuf files
-	if g.config != nil && g.config.OutputDir != "" && protobufPackage != "" {
-		// Extract the base path from the external go_package and replace with local path
-		// e.g., "github.com/example/proto/starwars/v1" -> "github.com/example/proto/starwars/generated/proto/v1"
-		if strings.Contains(protobufPackage, "/") {
-			// Find the module base by removing the version suffix
-			parts := strings.Split(protobufPackage, "/")
-			if len(parts) > 1 {
-				// Remove the last part (usually "v1") and add our output directory
-				moduleParts := parts[:len(parts)-1]
-				moduleBase := strings.Join(moduleParts, "/")
-				protobufPackage = moduleBase + "/" + g.config.OutputDir
-			}
-		}
+	if protobufPackage != "" {
+		protobufPackage = g.localProtobufPackage(protobufPackage)
 	}
 
 	return &TemplateData{
@@ -142,11 +203,22 @@ func (g *StubGenerator) prepareTemplateData(config *TemplateConfig) *TemplateDat
 		Types:           g.convertToTemplateTypes(g.originalTypes),
 		Services:        g.services,
 		MapConversions:  g.collectMapConversions(),
+
+		SliceConversions: g.collectSliceConversions(),
+
+		MappedConversions: g.collectMappedConversions(),
+		BuiltinTypes:      g.collectBuiltinTypes(),
+
+		CollectionConversions: g.collectCollectionConversions(),
+		PreserveTimeTypes:     g.mainGenerator.preserveTimeTypes(),
+		OptionalConversions:   g.collectOptionalConversions(),
+		DrainsResponses:       g.drainsResponses(),
 	}
 }
 
-// extractPackageImports extracts unique package imports from analyzed types
-func (g *StubGenerator) extractPackageImports() []string {
+// extractPackageImports extracts the unique packages of the analyzed types, which the
+// conversion functions reference
+func (g *StubGenerator) extractPackageImports() []*PackageImport {
 	packages := make(map[string]bool)
 
 	// Add packages from original types - use PackagePath if available, otherwise Package
@@ -162,16 +234,6 @@ func (g *StubGenerator) extractPackageImports() []string {
 		}
 	}
 
-	// Add packages from services
-	for _, serviceInfo := range g.services {
-		if serviceInfo.Package != "" && serviceInfo.Package != "main" {
-			packages[serviceInfo.Package] = true
-			if g.ctx != nil && g.ctx.Logger != nil {
-				g.ctx.Logger.Debug(fmt.Sprintf("Added package from service %s: %s", serviceInfo.Name, serviceInfo.Package))
-			}
-		}
-	}
-
 	// Filter out standard library imports - only include third-party packages
 	standardLibs := map[string]bool{
 		"context": true, "fmt": true, "io": true, "strings": true, "time": true,
@@ -180,7 +242,7 @@ func (g *StubGenerator) extractPackageImports() []string {
 	}
 
 	// Convert to slice, filtering standard library packages
-	result := make([]string, 0, len(packages))
+	result := make([]*PackageImport, 0, len(packages))
 	for pkg := range packages {
 		// Skip standard library packages (don't contain dots or start with known stdlib prefixes)
 		if standardLibs[pkg] || isStandardLibraryPackage(pkg) {
@@ -192,7 +254,7 @@ func (g *StubGenerator) extractPackageImports() []string {
 		if g.ctx != nil && g.ctx.Logger != nil {
 			g.ctx.Logger.Debug(fmt.Sprintf("Including package import: %s", pkg))
 		}
-		result = append(result, pkg)
+		result = append(result, g.packageImport(pkg))
 	}
 
 	return result
@@ -227,55 +289,82 @@ func isStandardLibraryPackage(pkg string) bool {
 }
 
 // getImportsForTemplate returns imports specific to a template
-func (g *StubGenerator) getImportsForTemplate(templateName string, baseImports []string) []string {
+func (g *StubGenerator) getImportsForTemplate(templateName string, baseImports []*PackageImport) []*PackageImport {
 	if g.ctx != nil && g.ctx.Logger != nil {
-		g.ctx.Logger.Debug(fmt.Sprintf("getImportsForTemplate(%s) - base imports: %v", templateName, baseImports))
+		g.ctx.Logger.Debug(fmt.Sprintf("getImportsForTemplate(%s) - base imports: %d", templateName, len(baseImports)))
 	}
 
 	imports := make(map[string]bool)
 
-	// Add base package imports (these are already filtered) - make a copy to avoid modifying original
-	for _, imp := range baseImports {
-		imports[imp] = true
+	// The conversion functions reference the packages of the converted types; the service
+	// templates only the packages of the services and of their method types
+	switch templateName {
+	case "service", "bridge", "adapter", "client", "registration":
+		for path := range g.servicePackages(templateName) {
+			imports[path] = true
+		}
+	default:
+		for _, imp := range baseImports {
+			imports[imp.Path] = true
+		}
+		for _, path := range g.mappedTypeImports() {
+			imports[path] = true
+		}
+		for _, path := range g.builtinTypeImports() {
+			imports[path] = true
+		}
+		for _, path := range g.timeTypeImports() {
+			imports[path] = true
+		}
+		for _, path := range g.optionalTypeImports() {
+			imports[path] = true
+		}
+		for _, path := range g.oneofTypeImports() {
+			imports[path] = true
+		}
 	}
+	delete(imports, "")
 
 	// Add template-specific imports based on what's actually used
 	switch templateName {
-	case "bridge":
-		// Only add context if there are streaming methods that actually need it
+	case "bridge", "service":
+		// Service interfaces and bridges spell the original signatures: context for the methods
+		// taking one, iter for the iter.Seq streams
 		needsContext := false
 		for _, service := range g.services {
 			for _, method := range service.Methods {
-				if method.IsStreaming {
-					needsContext = true
-					break
-				}
-			}
-			if needsContext {
-				break
+				needsContext = needsContext || method.HasContext
 			}
 		}
 		if needsContext {
 			imports["context"] = true
 			if g.ctx != nil && g.ctx.Logger != nil {
-				g.ctx.Logger.Debug(fmt.Sprintf("getImportsForTemplate(%s) - adding context for streaming methods", templateName))
+				g.ctx.Logger.Debug(fmt.Sprintf("getImportsForTemplate(%s) - adding context for methods taking one", templateName))
 			}
 		}
 		// Bridge doesn't use io, that's for client/adapter streaming
-
-	case "service":
-		// Service interfaces in our template don't actually use context
-		// They define original Go type methods without context parameters
-		// No additional imports needed for the service template
+		if g.usesStreamKind(streamSeq) {
+			imports["iter"] = true
+		}
 
 	case "adapter", "client":
-		// Adapters and clients always need context
-		if len(g.services) > 0 {
+		// Clients always need context (their call options travel in it), adapters for their unary
+		// methods (streams carry their own) and to cancel the service of a server stream
+		needsContext := templateName == "client"
+		for _, service := range g.services {
+			for _, method := range service.Methods {
+				needsContext = needsContext || !method.IsStreaming || (method.HasContext && method.ServerStream)
+			}
+		}
+		if needsContext || (templateName == "adapter" && g.drainsResponses()) {
 			imports["context"] = true
 			if g.ctx != nil && g.ctx.Logger != nil {
 				g.ctx.Logger.Debug(fmt.Sprintf("getImportsForTemplate(%s) - adding context for services", templateName))
 			}
 		}
+		if templateName == "adapter" && g.drainsResponses() {
+			imports["time"] = true
+		}
 
 		// Check for google.protobuf wrapper types and add necessary imports
 		needsWrappers := false
@@ -315,23 +404,14 @@ func (g *StubGenerator) getImportsForTemplate(templateName string, baseImports [
 			}
 		}
 
-		// Add io and log only for streaming methods in adapter/client
+		// Add io only for streaming methods in adapter/client, adapters only receive client streams
 		needsIO := false
-		needsLog := false
 		for _, service := range g.services {
 			for _, method := range service.Methods {
-				if method.IsStreaming {
+				if method.ClientStream || (method.IsStreaming && templateName == "client") {
 					needsIO = true
-					// log is only needed for client template
-					if templateName == "client" {
-						needsLog = true
-					}
-					break
 				}
 			}
-			if needsIO {
-				break
-			}
 		}
 		if needsIO {
 			imports["io"] = true
@@ -339,26 +419,61 @@ func (g *StubGenerator) getImportsForTemplate(templateName string, baseImports [
 				g.ctx.Logger.Debug(fmt.Sprintf("getImportsForTemplate(%s) - adding io for streaming", templateName))
 			}
 		}
-		if needsLog {
-			imports["log"] = true
-			if g.ctx != nil && g.ctx.Logger != nil {
-				g.ctx.Logger.Debug(fmt.Sprintf("getImportsForTemplate(%s) - adding log for streaming", templateName))
-			}
+		if templateName == "client" && g.usesStreamKind(streamSeq) {
+			imports["iter"] = true
 		}
 	}
 
 	// Convert back to slice and sort for consistent output
-	result := make([]string, 0, len(imports))
+	paths := make([]string, 0, len(imports))
 	for imp := range imports {
-		result = append(result, imp)
+		paths = append(paths, imp)
+	}
+	sort.Strings(paths)
+
+	result := make([]*PackageImport, 0, len(paths))
+	for _, path := range paths {
+		result = append(result, g.packageImport(path))
 	}
 
 	if g.ctx != nil && g.ctx.Logger != nil {
-		g.ctx.Logger.Debug(fmt.Sprintf("getImportsForTemplate(%s) - final imports: %v", templateName, result))
+		g.ctx.Logger.Debug(fmt.Sprintf("getImportsForTemplate(%s) - final imports: %v", templateName, paths))
 	}
 
 	return result
-} // getPackagePath returns the correct import path for a type
+}
+
+// servicePackages returns the packages a service template references: the packages declaring
+// the services (adapter, bridge, registration, and client for the interfaces it implements) and
+// those of the method types, which adapters
+// only spell out to receive client streams and to send responses through a parameter
+func (g *StubGenerator) servicePackages(templateName string) map[string]bool {
+	packages := make(map[string]bool)
+	for _, service := range g.services {
+		switch templateName {
+		case "adapter", "bridge", "registration", "client":
+			if service.Package != "" && (templateName != "client" || !service.IsStruct) {
+				packages[service.Package] = true
+			}
+		}
+		// Struct services are used as they are by the bridge
+		if templateName == "registration" || (templateName == "bridge" && service.IsStruct) {
+			continue
+		}
+		for _, method := range service.Methods {
+			if templateName != "adapter" || method.ClientStream {
+				packages[method.InputPackage] = true
+			}
+			if templateName != "adapter" || method.OutputStream == streamSendChan || method.OutputStream == streamCallback {
+				packages[method.OutputPackage] = true
+			}
+		}
+	}
+	delete(packages, "")
+	return packages
+}
+
+// getPackagePath returns the correct import path for a type
 func (g *StubGenerator) getPackagePath(typeInfo *TypeInfo) string {
 	// Check if the type has a FullName which might contain the full path
 	if typeInfo.FullName != "" {
@@ -403,6 +518,7 @@ func (g *StubGenerator) convertToTemplateTypes(types map[string]*TypeInfo) []*Te
 				Tag:                 field.Tag,
 				IsRepeated:          field.IsRepeated,
 				IsEmbedded:          field.IsEmbedded,
+				Oneof:               field.Oneof,
 				ToProtoConversion:   g.getToProtoConversion(field, field.GoName),
 				FromProtoConversion: g.getFromProtoConversion(field, protoFieldName),
 			}
@@ -411,25 +527,74 @@ func (g *StubGenerator) convertToTemplateTypes(types map[string]*TypeInfo) []*Te
 		// Extract package alias from the full package path
 		packageAlias := g.getPackageAlias(typeInfo.Package)
 
+		goType := typeInfo.GoType
+		if goType == "" {
+			goType = packageAlias + "." + typeInfo.Name
+		}
+
 		result = append(result, &TemplateTypeInfo{
 			Name:         typeInfo.Name,
 			PackageAlias: packageAlias,
+			GoType:       goType,
+			ProtoName:    g.protoTypeName(typeInfo),
+			ProtoAlias:   g.protobufAlias(typeInfo.Namespace),
 			IsEnum:       typeInfo.IsEnum,
+			EnumValues:   g.convertToTemplateEnumValues(typeInfo, packageAlias),
 			Fields:       templateFields,
+			Unions:       g.convertToTemplateUnions(typeInfo, packageAlias),
+			Oneofs:       g.convertToTemplateOneofs(typeInfo, templateFields),
 		})
 	}
 	return result
 }
 
-// getPackageAlias returns the alias to use for a package in templates
-func (g *StubGenerator) getPackageAlias(packagePath string) string {
-	if packagePath == "" {
-		return "models" // fallback
+// convertToTemplateEnumValues converts the values of an enum, following protoc
+// naming for the value constants (<Enum>_<VALUE>)
+func (g *StubGenerator) convertToTemplateEnumValues(typeInfo *TypeInfo, packageAlias string) []*TemplateEnumValue {
+	values := make([]*TemplateEnumValue, 0, len(typeInfo.EnumValues))
+	for _, v := range typeInfo.EnumValues {
+		values = append(values, &TemplateEnumValue{
+			GoValue:      packageAlias + "." + v.GoName,
+			ProtoValue:   g.protoTypeName(typeInfo) + "_" + v.ProtoName,
+			IsAlias:      v.IsAlias,
+			IsProtoAlias: v.IsProtoAlias,
+		})
 	}
+	return values
+}
+
+// convertToTemplateUnions converts the @union fields of a type, following protoc naming:
+// the oneof field is the CamelCase oneof name and each case has a <Message>_<Case> wrapper type
+func (g *StubGenerator) convertToTemplateUnions(typeInfo *TypeInfo, packageAlias string) []*TemplateUnionInfo {
+	protoName := g.protoTypeName(typeInfo)
+	unions := make([]*TemplateUnionInfo, 0, len(typeInfo.Unions))
+	for _, union := range typeInfo.Unions {
+		interfaceType := union.Interface
+		if !strings.Contains(interfaceType, ".") {
+			interfaceType = packageAlias + "." + interfaceType
+		}
 
-	// Use the last part of the path as alias
-	parts := strings.Split(packagePath, "/")
-	return parts[len(parts)-1]
+		templateUnion := &TemplateUnionInfo{
+			GoName:        union.GoName,
+			InterfaceType: interfaceType,
+			OneofName:     union.OneofName,
+			OneofField:    g.protoFieldNameFromTag(union.OneofName),
+			ToProtoFunc:   protoName + "_" + union.GoName + "ToProto",
+			FromProtoFunc: protoName + "_" + union.GoName + "FromProto",
+		}
+		for _, variant := range union.Variants {
+			caseField := g.protoFieldNameFromTag(variant.CaseName)
+			templateUnion.Variants = append(templateUnion.Variants, &TemplateUnionVariant{
+				Name:        variant.TypeName,
+				GoType:      g.getPackageAlias(variant.Package) + "." + variant.TypeName,
+				WrapperType: protoName + "_" + caseField,
+				CaseField:   caseField,
+				IsPointer:   variant.IsPointer,
+			})
+		}
+		unions = append(unions, templateUnion)
+	}
+	return unions
 }
 
 // extractModulePath extracts the base module path from a full package path
@@ -559,6 +724,40 @@ func (g *StubGenerator) getToProtoConversion(field *FieldInfo, goFieldName strin
 		return g.getToProtoConversion(&resolvedField, goFieldName)
 	}
 
+	// Value fields whose protobuf field tracks presence are always set
+	if field.presence {
+		valueField := *field
+		valueField.presence = false
+		return "presentValue(" + g.getToProtoConversion(&valueField, goFieldName) + ")"
+	}
+
+	// Lists and maps of collections convert through the helpers of their wrapper messages
+	if field.collectionName != "" {
+		return field.collectionName + "ToProto(orig." + goFieldName + ")"
+	}
+
+	// Custom mapped types convert through their configured functions
+	if mapping, ok := g.typeMapping(field.Type); ok {
+		if conversion, ok := g.mappedConversion(field.Type, mapping, "ToProto", "orig."+goFieldName); ok {
+			return conversion
+		}
+	}
+
+	// So are the types of builtinTypes that need more than a type conversion
+	if conversion, ok := g.builtinConversion(field.Type, "ToProto", "orig."+goFieldName); ok {
+		return conversion
+	}
+
+	// Time types convert to the well-known types, or to strings and nanoseconds
+	if conversion, ok := g.timeConversion(field.Type, "ToProto", "orig."+goFieldName); ok {
+		return conversion
+	}
+
+	// Optional scalars and enums keep nil as unset
+	if conversion, ok := g.getOptionalConversion(field, "ToProto", "orig."+goFieldName); ok {
+		return conversion
+	}
+
 	// Check if this is an enum field
 	if g.isEnumField(field.Type) {
 		// Extract enum type name from field type
@@ -572,14 +771,19 @@ func (g *StubGenerator) getToProtoConversion(field *FieldInfo, goFieldName strin
 		}
 	}
 
+	// Named primitives convert to the scalar type of the protobuf field
+	if field.ScalarType != "" {
+		return field.ScalarType + "(orig." + goFieldName + ")"
+	}
+
 	// Handle maps
 	if strings.HasPrefix(field.Type, "map[") {
-		return g.getMapToProtoConversion(field.Type, goFieldName)
+		return g.getMapToProtoConversion(field, goFieldName)
 	}
 
 	// Handle slices of non-enum types
 	if strings.HasPrefix(field.Type, "[]") {
-		return g.getSliceToProtoConversion(field.Type, goFieldName)
+		return g.getSliceToProtoConversion(field, goFieldName)
 	}
 
 	// Handle struct types that need conversion
@@ -606,6 +810,40 @@ func (g *StubGenerator) getFromProtoConversion(field *FieldInfo, protoFieldName
 		return g.getFromProtoConversion(&resolvedField, protoFieldName)
 	}
 
+	// and read through their getter, which returns the default value of unset fields
+	if field.presence {
+		valueField := *field
+		valueField.presence = false
+		return g.getFromProtoConversion(&valueField, "Get"+protoFieldName+"()")
+	}
+
+	// Lists and maps of collections convert through the helpers of their wrapper messages
+	if field.collectionName != "" {
+		return field.collectionName + "FromProto(proto." + protoFieldName + ")"
+	}
+
+	// Custom mapped types convert through their configured functions
+	if mapping, ok := g.typeMapping(field.Type); ok {
+		if conversion, ok := g.mappedConversion(field.Type, mapping, "FromProto", "proto."+protoFieldName); ok {
+			return conversion
+		}
+	}
+
+	// So are the types of builtinTypes that need more than a type conversion
+	if conversion, ok := g.builtinConversion(field.Type, "FromProto", "proto."+protoFieldName); ok {
+		return conversion
+	}
+
+	// Time types convert to the well-known types, or to strings and nanoseconds
+	if conversion, ok := g.timeConversion(field.Type, "FromProto", "proto."+protoFieldName); ok {
+		return conversion
+	}
+
+	// Optional scalars and enums keep nil as unset
+	if conversion, ok := g.getOptionalConversion(field, "FromProto", "proto."+protoFieldName); ok {
+		return conversion
+	}
+
 	// Check if this is an enum field
 	if g.isEnumField(field.Type) {
 		// Extract enum type name from field type
@@ -619,14 +857,19 @@ func (g *StubGenerator) getFromProtoConversion(field *FieldInfo, protoFieldName
 		}
 	}
 
+	// Named primitives convert back from the scalar type of the protobuf field
+	if field.NamedType != "" {
+		return field.NamedType + "(proto." + protoFieldName + ")"
+	}
+
 	// Handle maps
 	if strings.HasPrefix(field.Type, "map[") {
-		return g.getMapFromProtoConversion(field.Type, protoFieldName)
+		return g.getMapFromProtoConversion(field, protoFieldName)
 	}
 
 	// Handle slices of non-enum types
 	if strings.HasPrefix(field.Type, "[]") {
-		return g.getSliceFromProtoConversion(field.Type, protoFieldName)
+		return g.getSliceFromProtoConversion(field, protoFieldName)
 	}
 
 	// Handle struct types that need conversion
@@ -670,18 +913,9 @@ func (g *StubGenerator) extractTypeName(fieldType string) string {
 }
 
 // getMapToProtoConversion handles map type conversions
-func (g *StubGenerator) getMapToProtoConversion(mapType, goFieldName string) string {
-	// Extract key and value types from map[key]value
-	if matches := g.parseMapType(mapType); matches != nil {
-		keyType, valueType := matches[0], matches[1]
-
-		// For simple key types and struct value types, we need conversion
-		if g.isStructType(valueType) {
-			// Sanitize type names for function names
-			sanitizedKey := g.sanitizeTypeName(keyType)
-			sanitizedValue := g.sanitizeTypeName(valueType)
-			return fmt.Sprintf("ConvertMapToProto_%s_%s(orig.%s)", sanitizedKey, sanitizedValue, goFieldName)
-		}
+func (g *StubGenerator) getMapToProtoConversion(field *FieldInfo, goFieldName string) string {
+	if conversion, ok := g.mapConversion(field); ok {
+		return fmt.Sprintf("%s(orig.%s)", conversion.ToProtoFuncName, goFieldName)
 	}
 
 	// Default to direct assignment for simple maps
@@ -689,25 +923,22 @@ func (g *StubGenerator) getMapToProtoConversion(mapType, goFieldName string) str
 }
 
 // getMapFromProtoConversion handles map type conversions from proto
-func (g *StubGenerator) getMapFromProtoConversion(mapType, protoFieldName string) string {
-	// Extract key and value types from map[key]value
-	if matches := g.parseMapType(mapType); matches != nil {
-		keyType, valueType := matches[0], matches[1]
-
-		// For simple key types and struct value types, we need conversion
-		if g.isStructType(valueType) {
-			// Sanitize type names for function names
-			sanitizedKey := g.sanitizeTypeName(keyType)
-			sanitizedValue := g.sanitizeTypeName(valueType)
-			return fmt.Sprintf("ConvertMapFromProto_%s_%s(proto.%s)", sanitizedKey, sanitizedValue, protoFieldName)
-		}
+func (g *StubGenerator) getMapFromProtoConversion(field *FieldInfo, protoFieldName string) string {
+	if conversion, ok := g.mapConversion(field); ok {
+		return fmt.Sprintf("%s(proto.%s)", conversion.FromProtoFuncName, protoFieldName)
 	}
 
 	// Default to direct assignment for simple maps
 	return "proto." + protoFieldName
-} // getSliceToProtoConversion handles slice type conversions
-func (g *StubGenerator) getSliceToProtoConversion(sliceType, goFieldName string) string {
-	elementType := strings.TrimPrefix(sliceType, "[]")
+}
+
+// getSliceToProtoConversion handles slice type conversions
+func (g *StubGenerator) getSliceToProtoConversion(field *FieldInfo, goFieldName string) string {
+	if conversion, ok := g.sliceConversion(field); ok {
+		return fmt.Sprintf("%s(orig.%s)", conversion.ToProtoFuncName, goFieldName)
+	}
+
+	elementType := strings.TrimPrefix(field.Type, "[]")
 
 	if g.isStructType(elementType) {
 		typeName := g.extractTypeName(elementType)
@@ -719,8 +950,12 @@ func (g *StubGenerator) getSliceToProtoConversion(sliceType, goFieldName string)
 }
 
 // getSliceFromProtoConversion handles slice type conversions from proto
-func (g *StubGenerator) getSliceFromProtoConversion(sliceType, protoFieldName string) string {
-	elementType := strings.TrimPrefix(sliceType, "[]")
+func (g *StubGenerator) getSliceFromProtoConversion(field *FieldInfo, protoFieldName string) string {
+	if conversion, ok := g.sliceConversion(field); ok {
+		return fmt.Sprintf("%s(proto.%s)", conversion.FromProtoFuncName, protoFieldName)
+	}
+
+	elementType := strings.TrimPrefix(field.Type, "[]")
 
 	if g.isStructType(elementType) {
 		typeName := g.extractTypeName(elementType)
@@ -897,67 +1132,125 @@ func (g *StubGenerator) extractConcreteTypeFromAlias(aliasName, genericParam str
 // collectMapConversions collects all map conversion functions needed
 func (g *StubGenerator) collectMapConversions() []*MapConversionInfo {
 	conversions := make(map[string]*MapConversionInfo)
-
-	// Go through all types and find map fields that need conversion
 	for _, typeInfo := range g.originalTypes {
 		for _, field := range typeInfo.Fields {
-			if strings.HasPrefix(field.Type, "map[") {
-				if mapInfo := g.parseMapType(field.Type); mapInfo != nil {
-					keyType, valueType := mapInfo[0], mapInfo[1]
-
-					// Only generate conversions for maps with struct values
-					if g.isStructType(valueType) {
-						sanitizedKey := g.sanitizeTypeName(keyType)
-						sanitizedValue := g.sanitizeTypeName(valueType)
-
-						functionKey := fmt.Sprintf("%s_%s", sanitizedKey, sanitizedValue)
-
-						if _, exists := conversions[functionKey]; !exists {
-							valueIsPointer := strings.HasPrefix(valueType, "*")
-							cleanValueType := strings.TrimPrefix(valueType, "*")
-
-							// Get the simple type name for protobuf and function names
-							simpleTypeName := g.extractTypeName(cleanValueType)
-
-							// For adapter generation, always use package aliases for better readability
-							// Extract the simple type name and use the package alias
-							simpleType := g.extractTypeName(cleanValueType)
-
-							// Extract just the package name from the full package path
-							packageAlias := typeInfo.Package
-							if lastSlash := strings.LastIndex(packageAlias, "/"); lastSlash != -1 {
-								packageAlias = packageAlias[lastSlash+1:]
-							}
-
-							var originalMapType string
-							if valueIsPointer {
-								originalMapType = fmt.Sprintf("map[%s]*%s.%s", keyType, packageAlias, simpleType)
-							} else {
-								originalMapType = fmt.Sprintf("map[%s]%s.%s", keyType, packageAlias, simpleType)
-							}
-
-							conversions[functionKey] = &MapConversionInfo{
-								ToProtoFuncName:     fmt.Sprintf("ConvertMapToProto_%s", functionKey),
-								FromProtoFuncName:   fmt.Sprintf("ConvertMapFromProto_%s", functionKey),
-								OriginalType:        originalMapType,
-								ProtoType:           fmt.Sprintf("map[%s]*pb.%s", keyType, simpleTypeName),
-								ValueIsPointer:      valueIsPointer,
-								ValueConversionFunc: fmt.Sprintf("%sToProto", simpleTypeName),
-								ValueFromProtoFunc:  fmt.Sprintf("%sFromProto", simpleTypeName),
-							}
-						}
-					}
-				}
+			if conversion, ok := g.mapConversion(field); ok {
+				conversions[conversion.ToProtoFuncName] = conversion
 			}
 		}
 	}
 
-	// Convert map to slice
 	result := make([]*MapConversionInfo, 0, len(conversions))
-	for _, conv := range conversions {
-		result = append(result, conv)
+	for _, name := range sortedKeys(conversions) {
+		result = append(result, conversions[name])
+	}
+	return result
+}
+
+// mapConversion returns the helpers converting a map field whose keys or values differ from
+// those of the protobuf map: messages, enums, mapped and builtin types, pointers, named and
+// narrow scalars. Maps of collections convert through their wrappers instead.
+func (g *StubGenerator) mapConversion(field *FieldInfo) (*MapConversionInfo, bool) {
+	m, ok := field.goType.(*ast.MapType)
+	if !ok || field.collectionName != "" {
+		return nil, false
+	}
+	pkgAlias := g.getPackageAlias(field.packagePath)
+	key, ok := g.scalarConversion(m.Key, pkgAlias)
+	if !ok {
+		return nil, false
 	}
 
+	// Pointers convert through the values they point to, unless their helpers take the pointer
+	valueType, pointer := m.Value, false
+	if star, ok := m.Value.(*ast.StarExpr); ok {
+		if _, builtin, helpers := g.builtinHelpers(g.getGoTypeName(m.Value)); !helpers || !builtin.pointer {
+			valueType, pointer = star.X, true
+		}
+	}
+	value, ok := g.elementConversion(valueType, pkgAlias)
+	if !ok {
+		return nil, false
+	}
+	if !pointer && key.toFormat == "%s" && value.toFormat == "%s" {
+		// The map is assigned as is
+		return nil, false
+	}
+
+	mapTypes := g.parseMapType(field.Type)
+	functionKey := fmt.Sprintf("%s_%s", g.sanitizeTypeName(mapTypes[0]), g.sanitizeTypeName(mapTypes[1]))
+	goValue := "v"
+	if pointer {
+		goValue = "*v"
+	}
+	return &MapConversionInfo{
+		ToProtoFuncName:     fmt.Sprintf("ConvertMapToProto_%s", functionKey),
+		FromProtoFuncName:   fmt.Sprintf("ConvertMapFromProto_%s", functionKey),
+		OriginalType:        g.qualifiedGoType(m, pkgAlias),
+		ProtoType:           fmt.Sprintf("map[%s]%s", key.protoType, value.protoType),
+		KeyToProto:          key.toProto("k"),
+		KeyFromProto:        key.fromProto("k"),
+		ValueToProto:        value.toProto(goValue),
+		ValueFromProto:      value.fromProto("v"),
+		ValueIsPointer:      pointer,
+		ProtoValueIsMessage: strings.HasPrefix(value.protoType, "*"),
+	}, true
+}
+
+// sliceConversion returns the helpers converting a slice field whose elements differ from those
+// of the repeated protobuf field: named and narrow scalars and builtin types, converted with the
+// same conversion as a single value. Messages and enums convert through the slice helpers of their type.
+func (g *StubGenerator) sliceConversion(field *FieldInfo) (*SliceConversionInfo, bool) {
+	slice, ok := field.goType.(*ast.ArrayType)
+	if !ok || slice.Len != nil || isByteArray(slice) || field.collectionName != "" {
+		return nil, false
+	}
+	elementType := g.getGoTypeName(slice.Elt)
+	if _, known := g.originalTypes[g.extractTypeName(elementType)]; known {
+		return nil, false
+	}
+	if _, ok := g.mainGenerator.timeType(elementType); ok {
+		// Time types convert through the slice helpers of the types template
+		return nil, false
+	}
+
+	pkgAlias := g.getPackageAlias(field.packagePath)
+	value, ok := g.scalarConversion(slice.Elt, pkgAlias)
+	if _, _, builtin := g.builtinHelpers(elementType); builtin {
+		value, ok = g.elementConversion(slice.Elt, pkgAlias)
+	}
+	if !ok || value.toFormat == "%s" {
+		// The slice is assigned as is
+		return nil, false
+	}
+
+	// Named by the qualified element type: same-named types of other packages get their own
+	name := g.sanitizeTypeName(g.qualifiedGoType(slice.Elt, pkgAlias))
+	return &SliceConversionInfo{
+		ToProtoFuncName:   "ConvertSliceToProto_" + name,
+		FromProtoFuncName: "ConvertSliceFromProto_" + name,
+		OriginalType:      g.qualifiedGoType(slice, pkgAlias),
+		ProtoType:         "[]" + value.protoType,
+		ValueToProto:      value.toProto("v"),
+		ValueFromProto:    value.fromProto("v"),
+	}, true
+}
+
+// collectSliceConversions collects the slice conversion functions needed
+func (g *StubGenerator) collectSliceConversions() []*SliceConversionInfo {
+	conversions := make(map[string]*SliceConversionInfo)
+	for _, typeInfo := range g.originalTypes {
+		for _, field := range typeInfo.Fields {
+			if conversion, ok := g.sliceConversion(field); ok {
+				conversions[conversion.ToProtoFuncName] = conversion
+			}
+		}
+	}
+
+	result := make([]*SliceConversionInfo, 0, len(conversions))
+	for _, name := range sortedKeys(conversions) {
+		result = append(result, conversions[name])
+	}
 	return result
 }
 
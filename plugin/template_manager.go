@@ -12,8 +12,9 @@ import (
 //go:embed templates/*.tmpl
 var embeddedTemplates embed.FS
 
-// protobufTypeName converts protobuf type names to correct Go package references
-func protobufTypeName(typeName string) string {
+// protobufTypeName converts protobuf type names to correct Go package references. Regular
+// types are qualified with the alias of their protobuf package, pb by default.
+func protobufTypeName(typeName string, alias ...string) string {
 	switch typeName {
 	case "google.protobuf.Int64Value":
 		return "*wrapperspb.Int64Value"
@@ -36,7 +37,9 @@ func protobufTypeName(typeName string) string {
 	case "google.protobuf.Empty":
 		return "*emptypb.Empty"
 	default:
-		// For regular types, use pb. prefix
+		if len(alias) > 0 && alias[0] != "" {
+			return "*" + alias[0] + "." + typeName
+		}
 		return "*pb." + typeName
 	}
 }
@@ -0,0 +1,128 @@
+package plugin
+
+import (
+	"fmt"
+	"go/ast"
+	"strings"
+)
+
+// timeType is the protobuf representation of a time type: a well-known type, or with
+// preserve_time_types: false a scalar
+type timeType struct {
+	wellKnown string // Protobuf well-known type
+	protoPath string // Import path of the Go package of the well-known type
+	protoGo   string // Go type of the well-known type
+	scalar    string // Protobuf scalar used instead
+}
+
+const durationGoPackage = "google.golang.org/protobuf/types/known/durationpb"
+
+// timeTypes maps time.Time to a Timestamp or an RFC 3339 string, and time.Duration to a
+// Duration or int64 nanoseconds
+var timeTypes = map[string]timeType{
+	"time.Time":     {wellKnown: "google.protobuf.Timestamp", protoPath: timestampGoPackage, protoGo: "*timestamppb.Timestamp", scalar: "string"},
+	"time.Duration": {wellKnown: "google.protobuf.Duration", protoPath: durationGoPackage, protoGo: "*durationpb.Duration", scalar: "int64"},
+}
+
+// preserveTimeTypes checks if time types map to the well-known types, the default unless
+// generate_stubs.type_mappings.preserve_time_types is false
+func (g *Generator) preserveTimeTypes() bool {
+	stubs := g.formatGen.config.GenerateStubs
+	return stubs == nil || stubs.TypeMappings.PreserveTimeTypes == nil || *stubs.TypeMappings.PreserveTimeTypes
+}
+
+// timeType returns the representation of a time type, unless type_mappings or a custom mapping
+// map it otherwise
+func (g *Generator) timeType(goType string) (timeType, bool) {
+	if _, ok := g.customTypeMapping(goType); ok {
+		return timeType{}, false
+	}
+	t, ok := timeTypes[goType]
+	return t, ok
+}
+
+// timeProtoType returns the protobuf type of a time type
+func (g *Generator) timeProtoType(goType string) (string, bool) {
+	t, ok := g.timeType(goType)
+	if !ok {
+		return "", false
+	}
+	if g.preserveTimeTypes() {
+		return t.wellKnown, true
+	}
+	return t.scalar, true
+}
+
+// timeProtoGoType returns the Go type of the protobuf field of a time type
+func (g *StubGenerator) timeProtoGoType(t timeType) string {
+	if g.mainGenerator.preserveTimeTypes() {
+		return t.protoGo
+	}
+	return t.scalar
+}
+
+// timeConversion returns the conversion of a field of a time type, its pointer or slice, in a
+// direction (ToProto or FromProto). Pointers to the scalars are optional fields, nil when unset,
+// like the pointers to builtin types.
+func (g *StubGenerator) timeConversion(fieldType, direction, value string) (string, bool) {
+	goType := strings.TrimPrefix(fieldType, "*")
+	if _, ok := g.mainGenerator.timeType(goType); ok && goType != fieldType && !g.mainGenerator.preserveTimeTypes() {
+		return fmt.Sprintf("builtinOptional%s(%s, %s%s)", direction, value, g.sanitizeTypeName(goType), direction), true
+	}
+	return g.timeValueConversion(fieldType, direction, value)
+}
+
+// timeValueConversion returns the conversion of a time value, its pointer or slice, in a
+// direction (ToProto or FromProto), through the helpers of the types template. The pointer
+// helpers convert nil to the zero value, for the values of lists and maps which can't be unset.
+func (g *StubGenerator) timeValueConversion(fieldType, direction, value string) (string, bool) {
+	goType := strings.TrimLeft(fieldType, "*[]")
+	if _, ok := g.mainGenerator.timeType(goType); !ok {
+		return "", false
+	}
+	name := g.sanitizeTypeName(goType)
+	switch fieldType {
+	case goType:
+		return fmt.Sprintf("%s%s(%s)", name, direction, value), true
+	case "*" + goType:
+		return fmt.Sprintf("ConvertPointer%s_%s(%s)", direction, name, value), true
+	case "[]" + goType:
+		return fmt.Sprintf("%sSlice%s(%s)", name, direction, value), true
+	}
+	return "", false
+}
+
+// usedTimeTypes returns the time types the fields of the converted types use, anywhere in their
+// type (map[string][]time.Time)
+func (g *StubGenerator) usedTimeTypes() map[string]timeType {
+	used := make(map[string]timeType)
+	for _, typeInfo := range g.originalTypes {
+		for _, field := range typeInfo.Fields {
+			if field.goType == nil {
+				continue
+			}
+			ast.Inspect(field.goType, func(n ast.Node) bool {
+				if sel, ok := n.(*ast.SelectorExpr); ok {
+					goType := g.getGoTypeName(sel)
+					if t, ok := g.mainGenerator.timeType(goType); ok {
+						used[goType] = t
+					}
+				}
+				return true
+			})
+		}
+	}
+	return used
+}
+
+// timeTypeImports returns the packages the helpers of the used time types reference
+func (g *StubGenerator) timeTypeImports() []string {
+	var paths []string
+	for _, t := range g.usedTimeTypes() {
+		paths = append(paths, "time")
+		if g.mainGenerator.preserveTimeTypes() {
+			paths = append(paths, t.protoPath)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,261 @@
+package plugin
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TypeMapping maps a Go type that isn't parsed, such as decimal.Decimal, to a protobuf type.
+// It's written either as the protobuf type alone ("uuid.UUID": "string") or with the proto
+// file declaring the type and the Go functions the adapters convert values with.
+type TypeMapping struct {
+	ProtoType string `yaml:"proto_type"` // Protobuf type, e.g. google.type.Decimal
+	Import    string `yaml:"import"`     // Proto file declaring it, e.g. google/type/decimal.proto
+	GoType    string `yaml:"go_type"`    // Go type of the protobuf field, e.g. *google.golang.org/genproto/googleapis/type/decimal.Decimal
+	ToProto   string `yaml:"to_proto"`   // Converter to the protobuf type, e.g. github.com/acme/pbconv.DecimalToProto
+	FromProto string `yaml:"from_proto"` // Converter back to the Go type
+}
+
+// UnmarshalYAML accepts the plain protobuf type as well as the mapping
+func (m *TypeMapping) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		m.ProtoType = value.Value
+		return nil
+	}
+	type plain TypeMapping
+	if err := value.Decode((*plain)(m)); err != nil {
+		return fmt.Errorf("failed to decode type mapping: %w", err)
+	}
+	return nil
+}
+
+// typeMapping returns the mapping of a Go type. The mappings of
+// generate_stubs.type_mappings.custom_mappings take precedence over the plain protobuf types of
+// type_mappings, which are read as mappings without converters.
+func (c *Config) typeMapping(goType string) (TypeMapping, bool) {
+	if c.GenerateStubs != nil {
+		if mapping, ok := c.GenerateStubs.TypeMappings.CustomMappings[goType]; ok && mapping.ProtoType != "" {
+			return mapping, true
+		}
+	}
+	if protoType := c.TypeMappings[goType]; protoType != "" {
+		return TypeMapping{ProtoType: protoType}, true
+	}
+	return TypeMapping{}, false
+}
+
+// customTypeMapping returns the mapping of a Go type, see Config.typeMapping
+func (g *Generator) customTypeMapping(goType string) (TypeMapping, bool) {
+	return g.formatGen.config.typeMapping(goType)
+}
+
+// mappedTypeImport returns the proto file a custom mapping declares for a protobuf type
+func (g *Generator) mappedTypeImport(protoType string) (string, bool) {
+	stubs := g.formatGen.config.GenerateStubs
+	if stubs == nil {
+		return "", false
+	}
+	for _, goType := range sortedKeys(stubs.TypeMappings.CustomMappings) {
+		if mapping := stubs.TypeMappings.CustomMappings[goType]; mapping.ProtoType == protoType && mapping.Import != "" {
+			return mapping.Import, true
+		}
+	}
+	return "", false
+}
+
+// typeMapping returns the custom mapping of a field type (T, *T or []T) when it has converters
+func (g *StubGenerator) typeMapping(fieldType string) (TypeMapping, bool) {
+	if g.pluginConfig == nil || strings.HasPrefix(fieldType, "[]*") {
+		return TypeMapping{}, false
+	}
+	mapping, ok := g.pluginConfig.typeMapping(strings.TrimLeft(fieldType, "*[]"))
+	return mapping, ok && mapping.ToProto != "" && mapping.FromProto != ""
+}
+
+// goReference splits a qualified Go type or function (*github.com/acme/money.Amount) into the
+// name the adapters reference it by (*money.Amount) and the import path of its package
+func (g *StubGenerator) goReference(qualified string) (string, string) {
+	name := strings.TrimLeft(qualified, "*")
+	prefix := qualified[:len(qualified)-len(name)]
+
+	slash := strings.LastIndex(name, "/")
+	dot := strings.Index(name[slash+1:], ".")
+	if dot < 0 {
+		return qualified, ""
+	}
+	path := name[:slash+1+dot]
+	return prefix + g.getPackageAlias(path) + name[slash+1+dot:], path
+}
+
+// mappedTypePath returns the import path of the package of a mapped Go type (decimal.Decimal)
+func (g *StubGenerator) mappedTypePath(goType string) string {
+	pkg, name, qualified := strings.Cut(strings.TrimLeft(goType, "*[]"), ".")
+	if !qualified {
+		return ""
+	}
+	return g.mainGenerator.resolvePackagePath(pkg, name)
+}
+
+// mappedConversion returns the conversion of a field of a mapped type in a direction (ToProto or
+// FromProto). Pointers and slices go through the helpers generated for the mapping, see
+// collectMappedConversions, which need its go_type.
+func (g *StubGenerator) mappedConversion(fieldType string, mapping TypeMapping, direction, value string) (string, bool) {
+	name := g.sanitizeTypeName(strings.TrimLeft(fieldType, "*[]"))
+	switch {
+	case !isPointerOrSlice(fieldType):
+		converter := mapping.ToProto
+		if direction == "FromProto" {
+			converter = mapping.FromProto
+		}
+		ref, _ := g.goReference(converter)
+		return fmt.Sprintf("%s(%s)", ref, value), true
+	case mapping.GoType == "":
+		return "", false
+	case strings.HasPrefix(fieldType, "[]"):
+		return fmt.Sprintf("%sSlice%s(%s)", name, direction, value), true
+	default:
+		return fmt.Sprintf("ConvertPointer%s_%s(%s)", direction, name, value), true
+	}
+}
+
+// checkTypeMappings checks that the fields of mapped types can be converted: pointers, slices
+// and maps convert through helpers that need the go_type of the mapping and the package of the
+// mapped type, which otherwise would be referenced without being imported
+func (g *StubGenerator) checkTypeMappings() error {
+	for _, typeName := range sortedKeys(g.originalTypes) {
+		typeInfo := g.originalTypes[typeName]
+		for _, field := range typeInfo.Fields {
+			valueType := field.Type
+			if values := g.parseMapType(field.Type); values != nil {
+				valueType = values[1]
+			}
+			mapping, ok := g.typeMapping(valueType)
+			if !ok {
+				continue
+			}
+
+			helpers := valueType != field.Type || isPointerOrSlice(field.Type)
+			scalarMap := valueType != field.Type && protoScalarGoTypes[mapping.ProtoType] != ""
+			if !helpers || scalarMap {
+				continue
+			}
+			goType := strings.TrimLeft(valueType, "*[]")
+			if mapping.GoType == "" {
+				return fmt.Errorf("field %s.%s: the type mapping of %s needs a go_type to convert %s", typeInfo.Name, field.GoName, goType, field.Type)
+			}
+			if strings.Contains(goType, ".") && g.mappedTypePath(goType) == "" {
+				return fmt.Errorf("field %s.%s: can't resolve the package of the mapped type %s", typeInfo.Name, field.GoName, goType)
+			}
+		}
+	}
+	return nil
+}
+
+// isPointerOrSlice checks if a Go type is a pointer or a slice
+func isPointerOrSlice(goType string) bool {
+	return strings.HasPrefix(goType, "*") || strings.HasPrefix(goType, "[]")
+}
+
+// MappedConversionInfo holds the pointer and slice conversions of a mapped type
+type MappedConversionInfo struct {
+	Name          string // Sanitized Go type, prefix of the helpers, e.g. decimal_Decimal
+	GoType        string // Go type as referenced by the adapters, e.g. decimal.Decimal
+	ProtoType     string // Go type of the protobuf value, e.g. *decimal1.Decimal
+	ToProtoFunc   string
+	FromProtoFunc string
+}
+
+// collectMappedConversions returns the helpers of the mapped types used through pointer or
+// slice fields
+func (g *StubGenerator) collectMappedConversions() []*MappedConversionInfo {
+	conversions := make(map[string]*MappedConversionInfo)
+	for _, typeInfo := range g.originalTypes {
+		for _, field := range typeInfo.Fields {
+			mapping, ok := g.typeMapping(field.Type)
+			if !ok || mapping.GoType == "" || !isPointerOrSlice(field.Type) {
+				continue
+			}
+			goType := strings.TrimLeft(field.Type, "*[]")
+			name := g.sanitizeTypeName(goType)
+			if _, exists := conversions[name]; exists {
+				continue
+			}
+
+			protoType, _ := g.goReference(mapping.GoType)
+			toProto, _ := g.goReference(mapping.ToProto)
+			fromProto, _ := g.goReference(mapping.FromProto)
+			conversions[name] = &MappedConversionInfo{
+				Name:          name,
+				GoType:        g.aliasQualifiedType(goType, g.mappedTypePath(goType)),
+				ProtoType:     protoType,
+				ToProtoFunc:   toProto,
+				FromProtoFunc: fromProto,
+			}
+		}
+	}
+
+	result := make([]*MappedConversionInfo, 0, len(conversions))
+	for _, name := range sortedKeys(conversions) {
+		result = append(result, conversions[name])
+	}
+	return result
+}
+
+// mappedTypeImports returns the packages the conversions of mapped types reference: the
+// converters, and for the generated helpers the Go type and its protobuf type
+func (g *StubGenerator) mappedTypeImports() []string {
+	var paths []string
+	for _, typeInfo := range g.originalTypes {
+		for _, field := range typeInfo.Fields {
+			valueType := field.Type
+			if values := g.parseMapType(field.Type); values != nil {
+				valueType = values[1]
+			}
+			mapping, ok := g.typeMapping(valueType)
+			if !ok {
+				continue
+			}
+
+			// Pointers, slices and maps convert through generated helpers naming both types
+			helpers := valueType != field.Type || isPointerOrSlice(field.Type)
+			scalarMap := valueType != field.Type && protoScalarGoTypes[mapping.ProtoType] != ""
+			if helpers && mapping.GoType == "" && !scalarMap {
+				continue
+			}
+			_, toProto := g.goReference(mapping.ToProto)
+			_, fromProto := g.goReference(mapping.FromProto)
+			paths = append(paths, toProto, fromProto)
+			if helpers {
+				_, protoType := g.goReference(mapping.GoType)
+				paths = append(paths, protoType, g.mappedTypePath(valueType))
+			}
+		}
+	}
+	return paths
+}
+
+// mappedPackagePaths returns the packages of the mapped types and of their converters, which
+// get an alias like the packages of the parsed types
+func (g *StubGenerator) mappedPackagePaths() []string {
+	if g.config == nil {
+		return nil
+	}
+	var paths []string
+	for _, goType := range sortedKeys(g.config.TypeMappings.CustomMappings) {
+		mapping := g.config.TypeMappings.CustomMappings[goType]
+		if mapping.ToProto == "" || mapping.FromProto == "" {
+			continue
+		}
+		for _, qualified := range []string{mapping.ToProto, mapping.FromProto, mapping.GoType} {
+			if _, path := g.goReference(qualified); path != "" {
+				paths = append(paths, path)
+			}
+		}
+		if path := g.mappedTypePath(goType); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
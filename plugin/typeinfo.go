@@ -0,0 +1,393 @@
+package plugin
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pablor21/gonnotation/parser"
+	"golang.org/x/tools/go/packages"
+)
+
+// typeResolver resolves the Go types referenced by the parsed structs through go/types,
+// so named primitives, aliases and string-backed enums map by their underlying type
+// instead of by the identifier written in the source
+type typeResolver struct {
+	parsed   []*types.Package          // packages of the parsed types, searched for unqualified names
+	packages map[string]*types.Package // parsed packages and their imports, by path
+}
+
+// loadTypeResolver type-checks the packages in the given directories
+func loadTypeResolver(configDir string, dirs []string) (*typeResolver, error) {
+	// Types come from export data, dependencies aren't loaded from source
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedTypes | packages.NeedImports,
+		Dir:  configDir,
+	}
+	pkgs, err := packages.Load(cfg, dirs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load packages: %w", err)
+	}
+
+	// Export data written by a newer compiler than go/packages reads leaves the packages without
+	// types, they are type-checked with their dependencies then
+	for _, pkg := range pkgs {
+		if !pkg.IllTyped {
+			continue
+		}
+		cfg.Mode |= packages.NeedDeps
+		if pkgs, err = packages.Load(cfg, dirs...); err != nil {
+			return nil, fmt.Errorf("failed to load packages: %w", err)
+		}
+		break
+	}
+
+	r := &typeResolver{packages: make(map[string]*types.Package)}
+	for _, pkg := range pkgs {
+		if pkg.Types == nil {
+			continue
+		}
+		r.parsed = append(r.parsed, pkg.Types)
+		r.packages[pkg.Types.Path()] = pkg.Types
+		for _, imp := range pkg.Types.Imports() {
+			if _, exists := r.packages[imp.Path()]; !exists {
+				r.packages[imp.Path()] = imp
+			}
+		}
+	}
+	return r, nil
+}
+
+// lookup returns the type declared with a name as written in the parsed packages: Name or pkg.Name
+func (r *typeResolver) lookup(goType string) *types.TypeName {
+	pkgName, name, qualified := strings.Cut(goType, ".")
+	if !qualified {
+		for _, pkg := range r.parsed {
+			if obj, ok := pkg.Scope().Lookup(goType).(*types.TypeName); ok {
+				return obj
+			}
+		}
+		return nil
+	}
+
+	for _, path := range sortedKeys(r.packages) {
+		pkg := r.packages[path]
+		if pkg.Name() != pkgName {
+			continue
+		}
+		if obj, ok := pkg.Scope().Lookup(name).(*types.TypeName); ok {
+			return obj
+		}
+	}
+	return nil
+}
+
+// isParsed checks if a package is one of the parsed packages
+func (r *typeResolver) isParsed(pkg *types.Package) bool {
+	for _, parsed := range r.parsed {
+		if parsed == pkg {
+			return true
+		}
+	}
+	return false
+}
+
+// resolve returns the Go type a named type stands for: the basic underlying type of a named
+// primitive (type UserID string -> string) or the target of an alias. The second result
+// reports whether the resolved type is a primitive. Types with constants are enums and are
+// never resolved.
+func (r *typeResolver) resolve(goType string) (string, bool, bool) {
+	obj := r.lookup(goType)
+	if obj == nil {
+		return "", false, false
+	}
+
+	t := types.Unalias(obj.Type())
+	named, isNamed := t.(*types.Named)
+	if !isNamed || len(r.constantsOf(named)) == 0 {
+		if primitive := primitiveName(t.Underlying()); primitive != "" {
+			return primitive, true, true
+		}
+	}
+	if isNamed && obj.IsAlias() {
+		return r.typeName(named), false, true
+	}
+	return "", false, false
+}
+
+// basicProtoTypes maps the kinds of the basic Go types to their protobuf scalar
+var basicProtoTypes = map[types.BasicKind]string{
+	types.Bool:    "bool",
+	types.String:  "string",
+	types.Int:     "int32",
+	types.Int8:    "int32",
+	types.Int16:   "int32",
+	types.Int32:   "int32",
+	types.Int64:   "int64",
+	types.Uint:    "uint32",
+	types.Uint8:   "uint32",
+	types.Uint16:  "uint32",
+	types.Uint32:  "uint32",
+	types.Uint64:  "uint64",
+	types.Float32: "float",
+	types.Float64: "double",
+}
+
+// wellKnownGoPackages are the Go packages of the protobuf well-known types mapped by their name
+var wellKnownGoPackages = map[string]bool{
+	wrappersGoPackage: true,
+	"google.golang.org/protobuf/types/known/structpb": true,
+	"google.golang.org/protobuf/types/known/emptypb":  true,
+}
+
+// protoType returns the protobuf type of a Go type as written in the parsed packages: the scalar
+// of basic types and named primitives, the well-known type of the protobuf runtime types and Any
+// for interfaces. Enums, messages and aliases aren't resolved, nor are unknown names.
+func (r *typeResolver) protoType(goType string) (string, bool) {
+	obj := r.lookup(goType)
+	if obj == nil {
+		// Predeclared types: string, int, any...
+		obj, _ = types.Universe.Lookup(goType).(*types.TypeName)
+	}
+	if obj == nil || (obj.IsAlias() && obj.Pkg() != nil) {
+		return "", false
+	}
+
+	t := types.Unalias(obj.Type())
+	if named, ok := t.(*types.Named); ok {
+		if pkg := named.Obj().Pkg(); pkg != nil && wellKnownGoPackages[pkg.Path()] {
+			return "google.protobuf." + named.Obj().Name(), true
+		}
+		if len(r.constantsOf(named)) > 0 || named.Obj().Pkg() == nil {
+			// Enums and error
+			return "", false
+		}
+	}
+
+	switch u := t.Underlying().(type) {
+	case *types.Basic:
+		protoType, ok := basicProtoTypes[u.Kind()]
+		return protoType, ok
+	case *types.Slice:
+		if primitiveName(u) == "[]byte" {
+			return "bytes", true
+		}
+	case *types.Interface:
+		if _, ok := t.(*types.Named); !ok {
+			return "google.protobuf.Any", true
+		}
+	}
+	return "", false
+}
+
+// typeName returns the name a named type is referenced by in the schema: messages and enums of
+// the parsed packages by their name, other types qualified with their package (time.Time)
+func (r *typeResolver) typeName(named *types.Named) string {
+	obj := named.Obj()
+	if obj.Pkg() == nil || r.isParsed(obj.Pkg()) {
+		return obj.Name()
+	}
+	return obj.Pkg().Name() + "." + obj.Name()
+}
+
+// constantsOf returns the constants declared with a named type in its package, in declaration order
+func (r *typeResolver) constantsOf(named *types.Named) []*types.Const {
+	pkg := named.Obj().Pkg()
+	if pkg == nil {
+		return nil
+	}
+
+	var consts []*types.Const
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		if c, ok := scope.Lookup(name).(*types.Const); ok && types.Identical(c.Type(), named) {
+			consts = append(consts, c)
+		}
+	}
+	sort.Slice(consts, func(i, j int) bool { return consts[i].Pos() < consts[j].Pos() })
+	return consts
+}
+
+// primitiveName returns the Go name of a basic type, or []byte
+func primitiveName(t types.Type) string {
+	switch v := t.(type) {
+	case *types.Basic:
+		if v.Info()&types.IsUntyped != 0 {
+			return ""
+		}
+		return v.Name()
+	case *types.Slice:
+		if elem, ok := v.Elem().(*types.Basic); ok && elem.Kind() == types.Byte {
+			return "[]byte"
+		}
+	}
+	return ""
+}
+
+// loadTypeInfo loads go/types information for the parsed packages and registers the
+// string-backed enums (type Status string + constants) used by the parsed structs.
+// Without type information, types are mapped by their identifiers only.
+func (g *Generator) loadTypeInfo() {
+	dirs := make(map[string]bool)
+	for _, s := range g.ctx.Structs {
+		if s.SourceFile == "" {
+			continue
+		}
+		// Relative directories would be read as import paths
+		dir, err := filepath.Abs(filepath.Dir(s.SourceFile))
+		if err != nil {
+			continue
+		}
+		dirs[dir] = true
+	}
+	if len(dirs) == 0 {
+		return
+	}
+
+	configDir := ""
+	if g.ctx.CoreConfig != nil {
+		configDir = g.ctx.CoreConfig.ConfigDir
+	}
+	resolver, err := loadTypeResolver(configDir, sortedKeys(dirs))
+	if err != nil {
+		g.ctx.Logger.Debug(fmt.Sprintf("Type information not available, mapping types by name: %v", err))
+		return
+	}
+	g.types = resolver
+
+	// The parser reads every type declared with a basic type as an enum, the ones without
+	// constants are named primitives
+	enums := make([]*parser.EnumInfo, 0, len(g.ctx.Enums))
+	for _, e := range g.ctx.Enums {
+		if _, isPrimitive, ok := resolver.resolve(e.Name); ok && isPrimitive {
+			continue
+		}
+		enums = append(enums, e)
+	}
+	g.ctx.Enums = append(enums, g.collectStringEnums()...)
+}
+
+// collectStringEnums builds enums for the string-backed named types with constants referenced
+// by the fields of the parsed structs, unless they were already parsed as enums. Each enum is
+// placed in the source file of the first struct using it.
+func (g *Generator) collectStringEnums() []*parser.EnumInfo {
+	known := make(map[string]bool)
+	for _, e := range g.ctx.Enums {
+		known[e.Name] = true
+	}
+
+	var enums []*parser.EnumInfo
+	for _, s := range g.ctx.Structs {
+		for _, f := range s.Fields {
+			goType := strings.TrimLeft(g.getGoTypeName(f.Type), "*[]")
+			obj := g.types.lookup(goType)
+			if obj == nil || obj.IsAlias() || known[obj.Name()] {
+				continue
+			}
+			named, ok := obj.Type().(*types.Named)
+			if !ok {
+				continue
+			}
+			if basic, ok := named.Underlying().(*types.Basic); !ok || basic.Kind() != types.String {
+				continue
+			}
+			consts := g.types.constantsOf(named)
+			if len(consts) == 0 {
+				continue
+			}
+
+			enum := &parser.EnumInfo{
+				Name:        obj.Name(),
+				Package:     obj.Pkg().Name(),
+				PackagePath: obj.Pkg().Path(),
+				SourceFile:  s.SourceFile,
+			}
+			for _, c := range consts {
+				enum.Values = append(enum.Values, &parser.EnumValue{Name: c.Name()})
+			}
+			enums = append(enums, enum)
+			known[obj.Name()] = true
+		}
+	}
+	return enums
+}
+
+// resolveNamedType returns the Go type a named primitive or an alias stands for, and whether
+// it is a primitive. Enums are never resolved.
+func (g *Generator) resolveNamedType(goType string) (string, bool, bool) {
+	if g.types == nil {
+		return "", false, false
+	}
+	for _, e := range g.ctx.Enums {
+		if e.Name == goType {
+			return "", false, false
+		}
+	}
+	return g.types.resolve(goType)
+}
+
+// unaliasType returns a field type whose aliases, pointed to or repeated, are replaced by the
+// type they stand for, so adapters convert them as that type (type Stamp = time.Time)
+func (g *Generator) unaliasType(t ast.Expr) ast.Expr {
+	switch v := t.(type) {
+	case *ast.StarExpr:
+		if x := g.unaliasType(v.X); x != v.X {
+			return &ast.StarExpr{X: x}
+		}
+	case *ast.ArrayType:
+		if elt := g.unaliasType(v.Elt); elt != v.Elt {
+			return &ast.ArrayType{Len: v.Len, Elt: elt}
+		}
+	case *ast.Ident:
+		resolved, primitive, ok := g.resolveNamedType(v.Name)
+		if !ok || primitive || resolved == v.Name {
+			return t
+		}
+		if pkgName, name, qualified := strings.Cut(resolved, "."); qualified {
+			return &ast.SelectorExpr{X: ast.NewIdent(pkgName), Sel: ast.NewIdent(name)}
+		}
+		return ast.NewIdent(resolved)
+	}
+	return t
+}
+
+// resolveProtoType returns the protobuf type a Go type resolves to through go/types, see
+// typeResolver.protoType. Parsed enums are never resolved.
+func (g *Generator) resolveProtoType(goType string) (string, bool) {
+	for _, e := range g.ctx.Enums {
+		if e.Name == goType {
+			return "", false
+		}
+	}
+	return g.types.protoType(goType)
+}
+
+// enumAliases returns the constants of an enum that repeat the value of an earlier one
+// (type aliases of Go values), which can't be keys of the same lookup table
+func (g *Generator) enumAliases(e *parser.EnumInfo) map[string]bool {
+	aliases := make(map[string]bool)
+	if g.types == nil {
+		return aliases
+	}
+	obj := g.types.lookup(e.Name)
+	if obj == nil {
+		return aliases
+	}
+	named, ok := obj.Type().(*types.Named)
+	if !ok {
+		return aliases
+	}
+
+	seen := make(map[string]bool)
+	for _, c := range g.types.constantsOf(named) {
+		value := c.Val().ExactString()
+		if seen[value] {
+			aliases[c.Name()] = true
+		}
+		seen[value] = true
+	}
+	return aliases
+}
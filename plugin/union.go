@@ -0,0 +1,175 @@
+package plugin
+
+import (
+	"go/ast"
+	goparser "go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+
+	"github.com/pablor21/gonnotation/parser"
+)
+
+// unionCase is a oneof case generated for a variant of an @union interface
+type unionCase struct {
+	Variant *parser.StructInfo // Implementing struct
+	Field   *parser.FieldInfo  // Synthesized case field, named after the variant
+}
+
+// isUnion checks if an interface is annotated with @union
+func (g *Generator) isUnion(iface *parser.InterfaceInfo) bool {
+	for _, ann := range iface.Annotations {
+		name := strings.ToLower(ann.Name)
+		if name == "union" || strings.HasSuffix(name, ".union") {
+			return true
+		}
+	}
+	return false
+}
+
+// findUnion returns the @union interface a field type refers to, if any.
+// Only plain references are considered: unions can't be repeated or used as map values.
+func (g *Generator) findUnion(t ast.Expr) *parser.InterfaceInfo {
+	var typeName string
+	switch v := t.(type) {
+	case *ast.Ident:
+		typeName = v.Name
+	case *ast.SelectorExpr:
+		typeName = v.Sel.Name
+	default:
+		return nil
+	}
+
+	ctx := g.lookupContext()
+	if ctx == nil {
+		return nil
+	}
+	for _, iface := range ctx.Interfaces {
+		if iface.Name == typeName && g.isUnion(iface) {
+			return iface
+		}
+	}
+	return nil
+}
+
+// getUnionVariants returns the structs of a union, either listed with @union(variants="A,B")
+// or discovered as the structs implementing all the methods of the interface
+func (g *Generator) getUnionVariants(iface *parser.InterfaceInfo) []*parser.StructInfo {
+	ctx := g.lookupContext()
+	for _, ann := range iface.Annotations {
+		name := strings.ToLower(ann.Name)
+		if name != "union" && !strings.HasSuffix(name, ".union") {
+			continue
+		}
+		value, ok := ann.GetParamValue("variants")
+		if !ok || value == "" {
+			break
+		}
+
+		var variants []*parser.StructInfo
+		for _, variantName := range strings.Split(strings.Trim(value, "[]"), ",") {
+			variantName = strings.TrimSpace(variantName)
+			for _, s := range ctx.Structs {
+				if s.Name == variantName {
+					variants = append(variants, s)
+					break
+				}
+			}
+		}
+		return variants
+	}
+
+	if len(iface.Methods) == 0 {
+		return nil
+	}
+
+	var variants []*parser.StructInfo
+	for _, s := range ctx.Structs {
+		if s.IsGeneric || g.shouldSkipType(s) || g.hasOtherTypeAnnotation(s) {
+			continue
+		}
+
+		methods := make(map[string]bool)
+		for _, fn := range ctx.Functions {
+			if fn.Receiver != nil && fn.Receiver.TypeName == s.Name {
+				methods[fn.Name] = true
+			}
+		}
+
+		implements := true
+		for _, method := range iface.Methods {
+			if !methods[method.Name] {
+				implements = false
+				break
+			}
+		}
+		if implements {
+			variants = append(variants, s)
+		}
+	}
+	return variants
+}
+
+// unionCases returns the oneof name and cases of a field whose type is an @union interface.
+// The oneof is named after the field and each case after its variant (Circle -> circle).
+func (g *Generator) unionCases(f *parser.FieldInfo) (string, []unionCase) {
+	if g.isRepeated(f) {
+		return "", nil
+	}
+	iface := g.findUnion(f.Type)
+	if iface == nil {
+		return "", nil
+	}
+
+	var cases []unionCase
+	for _, variant := range g.getUnionVariants(iface) {
+		cases = append(cases, unionCase{
+			Variant: variant,
+			Field: &parser.FieldInfo{
+				Name:   variant.Name,
+				GoName: variant.Name,
+				Type:   &ast.Ident{Name: variant.Name},
+			},
+		})
+	}
+	return g.getFieldName(f), cases
+}
+
+// receiverKinds parses the Go files of a directory and reports, for each "Type.Method",
+// whether the method has a pointer receiver. Adapters need it to write type switches that
+// compile: a variant with pointer receivers only implements the union as a pointer.
+func receiverKinds(dir string) map[string]bool {
+	kinds := make(map[string]bool)
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return kinds
+	}
+
+	fset := token.NewFileSet()
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := goparser.ParseFile(fset, file, nil, goparser.SkipObjectResolution)
+		if err != nil {
+			continue
+		}
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Recv == nil || len(fn.Recv.List) == 0 {
+				continue
+			}
+
+			recv := fn.Recv.List[0].Type
+			isPointer := false
+			if star, ok := recv.(*ast.StarExpr); ok {
+				recv = star.X
+				isPointer = true
+			}
+			if ident, ok := recv.(*ast.Ident); ok {
+				kinds[ident.Name+"."+fn.Name.Name] = isPointer
+			}
+		}
+	}
+	return kinds
+}
@@ -2,6 +2,8 @@ package plugin
 
 import (
 	"fmt"
+	"go/ast"
+	"slices"
 	"strings"
 
 	"github.com/pablor21/gonnotation/parser"
@@ -38,6 +40,20 @@ func (pv *ProtoValidator) ValidateContext(ctx *goschemagen.GenerationContext, ge
 		if len(messageNamesForStruct) == 0 {
 			messageNamesForStruct = []string{gen.getMessageName(s, "")}
 		}
+		if gen.getNestedIn(s) != "" {
+			// Nested messages only have to be unique inside their parent
+			messageNamesForStruct = []string{gen.qualifiedNestedName(s)}
+		}
+
+		// Extension ranges don't exist in proto3
+		extensionRanges := gen.getExtensionRanges(s)
+		if len(extensionRanges) > 0 && gen.formatGen.config.Syntax == "proto3" {
+			errors = append(errors, parser.ValidationError{
+				Location: fmt.Sprintf("struct %s", s.Name),
+				Message:  "extension ranges are not allowed in proto3",
+				Severity: "error",
+			})
+		}
 
 		for _, msgName := range messageNamesForStruct {
 			// Check for duplicate message names
@@ -53,6 +69,7 @@ func (pv *ProtoValidator) ValidateContext(ctx *goschemagen.GenerationContext, ge
 			// Validate field numbers for this message
 			fieldNumbersByMessage[msgName] = make(map[int]string)
 			reservedNumbers := make(map[int]bool)
+			unionCaseFields := make(map[string]string)
 
 			// Collect reserved numbers from @proto.reserved annotation
 			for _, ann := range s.Annotations {
@@ -98,9 +115,70 @@ func (pv *ProtoValidator) ValidateContext(ctx *goschemagen.GenerationContext, ge
 					continue
 				}
 
+				// Unions are written as a oneof, which can't be repeated or used as a map value
+				var elementType ast.Expr
+				switch t := f.Type.(type) {
+				case *ast.ArrayType:
+					elementType = t.Elt
+				case *ast.MapType:
+					elementType = t.Value
+				}
+				if elementType != nil && gen.findUnion(elementType) != nil {
+					errors = append(errors, parser.ValidationError{
+						Location: fmt.Sprintf("message %s, field %s", msgName, f.Name),
+						Message:  "union fields can't be repeated or used as map values",
+						Severity: "error",
+					})
+				}
+				if iface := gen.findUnion(f.Type); iface != nil && len(gen.getUnionVariants(iface)) == 0 {
+					errors = append(errors, parser.ValidationError{
+						Location: fmt.Sprintf("message %s, field %s", msgName, f.Name),
+						Message:  fmt.Sprintf("union %s has no variants", iface.Name),
+						Severity: "error",
+					})
+				}
+
+				// The cases of the union oneofs of a message are fields of that message, so two
+				// unions sharing a variant would declare it twice
+				_, cases := gen.unionCases(f)
+				for _, c := range cases {
+					caseName := gen.getFieldName(c.Field)
+					if existingField, exists := unionCaseFields[caseName]; exists {
+						errors = append(errors, parser.ValidationError{
+							Location: fmt.Sprintf("message %s, field %s", msgName, f.Name),
+							Message:  fmt.Sprintf("union case %s is also a case of field '%s'", caseName, existingField),
+							Severity: "error",
+						})
+						continue
+					}
+					unionCaseFields[caseName] = f.Name
+				}
+
+				// Check proto2 default values against the field type
+				if gen.isProto2() {
+					if raw, ok := gen.getDefaultValue(f); ok {
+						if _, err := gen.formatDefaultValue(f, raw); err != nil {
+							errors = append(errors, parser.ValidationError{
+								Location: fmt.Sprintf("message %s, field %s", msgName, f.Name),
+								Message:  fmt.Sprintf("invalid default value: %v", err),
+								Severity: "error",
+							})
+						}
+					}
+				}
+
 				// Get field number
 				num := gen.getFieldNumberFromAnnotation(f)
 				if num > 0 {
+					// Check for overlaps with the extension ranges of the message
+					if numberInRanges(num, extensionRanges) {
+						errors = append(errors, parser.ValidationError{
+							Location: fmt.Sprintf("message %s, field %s", msgName, f.Name),
+							Message:  fmt.Sprintf("field number %d overlaps an extension range", num),
+							Severity: "error",
+						})
+					}
+
 					// Check for field number conflicts
 					if existingField, exists := fieldNumbersByMessage[msgName][num]; exists {
 						errors = append(errors, parser.ValidationError{
@@ -119,6 +197,15 @@ func (pv *ProtoValidator) ValidateContext(ctx *goschemagen.GenerationContext, ge
 						})
 					}
 
+					// Check if reusing the number of a field removed since the lockfile was written
+					if gen.lock != nil && gen.lock.IsReservedFieldNumber(msgName, num) {
+						errors = append(errors, parser.ValidationError{
+							Location: fmt.Sprintf("message %s, field %s", msgName, f.Name),
+							Message:  fmt.Sprintf("field number %d belonged to a removed field and is reserved in the lockfile", num),
+							Severity: "error",
+						})
+					}
+
 					// Validate field number range (protobuf limits)
 					if num < 1 || num > 536870911 {
 						errors = append(errors, parser.ValidationError{
@@ -143,12 +230,49 @@ func (pv *ProtoValidator) ValidateContext(ctx *goschemagen.GenerationContext, ge
 		}
 	}
 
+	// Validate the parents of nested messages
+	for _, s := range ctx.Structs {
+		if gen.getNestedIn(s) == "" || gen.hasOtherTypeAnnotation(s) {
+			continue
+		}
+		name := gen.qualifiedNestedName(s)
+		if parent := name[:strings.LastIndex(name, ".")]; !messageNames[parent] {
+			errors = append(errors, parser.ValidationError{
+				Location: fmt.Sprintf("struct %s", s.Name),
+				Message:  fmt.Sprintf("nested_in message %s not found", parent),
+				Severity: "error",
+			})
+		}
+	}
+
+	// Validate extension numbers against the ranges of extended messages declared in the schema,
+	// proto3 messages can't declare ranges
+	extensionRangesByMessage := make(map[string][][2]int)
+	for _, s := range ctx.Structs {
+		if ranges := gen.getExtensionRanges(s); len(ranges) > 0 {
+			extensionRangesByMessage[gen.getMessageName(s, "")] = ranges
+		}
+	}
+	declaresRanges := gen.isProto2() || gen.isEditions()
+	for _, ext := range gen.collectExtensions() {
+		ranges, ok := extensionRangesByMessage[ext.Extendee]
+		if messageNames[ext.Extendee] && (ok || declaresRanges) && !numberInRanges(ext.Number, ranges) {
+			errors = append(errors, parser.ValidationError{
+				Location: fmt.Sprintf("extension %s of %s", ext.Name, ext.Extendee),
+				Message:  fmt.Sprintf("extension number %d is not in an extension range of %s", ext.Number, ext.Extendee),
+				Severity: "error",
+			})
+		}
+	}
+
 	// Validate enums
 	enumNames := make(map[string]bool)
 	enumValuesByEnum := make(map[string]map[int]string) // enum -> value_number -> value_name
+	enumValueNames := make(map[string]string)           // proto_package.value_name -> enum, values are scoped to the package
 
 	for _, e := range ctx.Enums {
 		enumName := gen.getEnumName(e)
+		protoPackage := gen.typeProtoPackage(e.Package, e.Namespace)
 
 		// Check for duplicate enum names
 		if enumNames[enumName] {
@@ -162,30 +286,79 @@ func (pv *ProtoValidator) ValidateContext(ctx *goschemagen.GenerationContext, ge
 
 		// Validate enum values
 		enumValuesByEnum[enumName] = make(map[int]string)
+		allowAlias := gen.isEnumAliasAllowed(e)
+		hasAlias := false
+		reservedNumbers, reservedNames := gen.getEnumReserved(e)
 
 		for i, val := range e.Values {
-			valueNum := i // Default to index
+			valueNum := gen.getEnumValueNumber(e, i, enumName)
 
-			// Check for custom number in annotation
-			for _, ann := range val.Annotations {
-				name := strings.ToLower(ann.Name)
-				if name == "enumvalue" || strings.HasSuffix(name, ".enumvalue") {
-					if numStr := ann.Params["number"]; numStr != "" {
-						_, _ = fmt.Sscanf(numStr, "%d", &valueNum) // Ignore error, keep default if parse fails
-					}
+			// Check for duplicate enum value numbers, allowed as aliases with allow_alias
+			if existingValue, exists := enumValuesByEnum[enumName][valueNum]; exists {
+				hasAlias = true
+				if !allowAlias {
+					errors = append(errors, parser.ValidationError{
+						Location: fmt.Sprintf("enum %s, value %s", enumName, val.Name),
+						Message:  fmt.Sprintf("duplicate enum value number %d (also used by value '%s'), set @enum(allow_alias=true) to declare an alias", valueNum, existingValue),
+						Severity: "error",
+					})
 				}
+			} else {
+				enumValuesByEnum[enumName][valueNum] = val.Name
 			}
 
-			// Check for duplicate enum value numbers
-			if existingValue, exists := enumValuesByEnum[enumName][valueNum]; exists {
+			// Check for values using reserved numbers or names
+			if slices.Contains(reservedNumbers, valueNum) {
+				errors = append(errors, parser.ValidationError{
+					Location: fmt.Sprintf("enum %s, value %s", enumName, val.Name),
+					Message:  fmt.Sprintf("enum value number %d is reserved", valueNum),
+					Severity: "error",
+				})
+			}
+			// Check if an explicit number reuses the number of a value removed since the lockfile was written
+			if num, ok := gen.getExplicitEnumValueNumber(val); ok && gen.lock != nil && gen.lock.IsReservedEnumNumber(enumName, num) {
+				errors = append(errors, parser.ValidationError{
+					Location: fmt.Sprintf("enum %s, value %s", enumName, val.Name),
+					Message:  fmt.Sprintf("enum value number %d belonged to a removed value and is reserved in the lockfile", num),
+					Severity: "error",
+				})
+			}
+			if valueName := gen.getEnumValueName(val, enumName); slices.Contains(reservedNames, valueName) {
 				errors = append(errors, parser.ValidationError{
 					Location: fmt.Sprintf("enum %s, value %s", enumName, val.Name),
-					Message:  fmt.Sprintf("duplicate enum value number %d (also used by value '%s')", valueNum, existingValue),
+					Message:  fmt.Sprintf("enum value name %s is reserved", valueName),
 					Severity: "error",
 				})
 			}
 
-			enumValuesByEnum[enumName][valueNum] = val.Name
+			// Enum values are siblings of their enum, so two enums of a proto package can't share a
+			// value name, also when they are declared in different Go packages
+			valueName := gen.getEnumValueName(val, enumName)
+			if other, exists := enumValueNames[protoPackage+"."+valueName]; exists && other != enumName {
+				errors = append(errors, parser.ValidationError{
+					Location: fmt.Sprintf("enum %s, value %s", enumName, val.Name),
+					Message:  fmt.Sprintf("enum value name %s is also used by enum %s, enable enum_style.prefix_values", valueName, other),
+					Severity: "error",
+				})
+			}
+			enumValueNames[protoPackage+"."+valueName] = enumName
+
+			if i == 0 && valueNum != 0 && gen.enumsStartAtZero() && !gen.formatGen.config.EnumStyle.UnspecifiedZeroValue {
+				errors = append(errors, parser.ValidationError{
+					Location: fmt.Sprintf("enum %s, value %s", enumName, val.Name),
+					Message:  fmt.Sprintf("the first value of an open enum must be 0, got %d, enable enum_style.unspecified_zero_value", valueNum),
+					Severity: "error",
+				})
+			}
+		}
+
+		// protoc rejects allow_alias on enums without aliases, the option is left out
+		if allowAlias && !hasAlias {
+			errors = append(errors, parser.ValidationError{
+				Location: fmt.Sprintf("enum %s", enumName),
+				Message:  "allow_alias is set but no values share a number, the option is left out",
+				Severity: "warning",
+			})
 		}
 	}
 
@@ -209,3 +382,13 @@ func (pv *ProtoValidator) ValidateContext(ctx *goschemagen.GenerationContext, ge
 
 	return errors
 }
+
+// numberInRanges checks if a number falls in one of the inclusive ranges
+func numberInRanges(number int, ranges [][2]int) bool {
+	for _, r := range ranges {
+		if number >= r[0] && number <= r[1] {
+			return true
+		}
+	}
+	return false
+}
@@ -23,6 +23,7 @@ var annotationSpecs = []AnnotationSpec{
 			{Name: "name", Types: []string{"string"}, Description: "Custom message name"},
 			{Name: "description", Types: []string{"string"}},
 			{Name: "reserved", Types: []string{"bool", "string", "[]string"}, Description: "Mark all fields as reserved: empty or true (all), message name, or array of message names"},
+			{Name: "nested_in", Types: []string{"string"}, Description: "Declare the message inside another message (e.g. User or User.Address)"},
 		},
 		ValidOn: []ValidOn{annotations.AnnotationValidOnStruct},
 	},
@@ -32,7 +33,9 @@ var annotationSpecs = []AnnotationSpec{
 		Params: []Param{
 			{Name: "name", Types: []string{"string"}},
 			{Name: "description", Types: []string{"string"}},
-			{Name: "allow_alias", Types: []string{"bool"}},
+			{Name: "allow_alias", Types: []string{"bool"}, Description: "Allow values sharing a number (aliases)"},
+			{Name: "deprecated", Types: []string{"bool"}},
+			{Name: "enum_type", Types: []string{"string"}, EnumValues: []string{"open", "closed"}, Description: "Editions enum_type feature"},
 		},
 		ValidOn: []ValidOn{annotations.AnnotationValidOnEnum},
 	},
@@ -43,6 +46,7 @@ var annotationSpecs = []AnnotationSpec{
 			{Name: "name", Types: []string{"string"}},
 			{Name: "number", Types: []string{"int"}, Description: "Enum value number"},
 			{Name: "description", Types: []string{"string"}},
+			{Name: "deprecated", Types: []string{"bool"}},
 		},
 		ValidOn: []ValidOn{annotations.AnnotationValidOnEnumValue},
 	},
@@ -55,6 +59,15 @@ var annotationSpecs = []AnnotationSpec{
 		},
 		ValidOn: []ValidOn{annotations.AnnotationValidOnField},
 	},
+	{
+		Name:        "union",
+		Description: "Maps a sealed interface to a oneof with one case per implementing struct",
+		Params: []Param{
+			{Name: "variants", Types: []string{"string", "[]string"}, Description: "Implementing structs, in case order (default: discovered from the interface methods)"},
+			{Name: "description", Types: []string{"string"}},
+		},
+		ValidOn: []ValidOn{annotations.AnnotationValidOnInterface},
+	},
 	{
 		Name:        "service",
 		Description: "Defines a gRPC service",
@@ -106,14 +119,14 @@ var annotationSpecs = []AnnotationSpec{
 	},
 	{
 		Name:        "reserved",
-		Description: "Reserves field numbers or names",
+		Description: "Reserves field or enum value numbers and names",
 		Multiple:    true,
 		Params: []Param{
 			{Name: "for", Types: []string{"string", "[]string"}, Description: "Apply reserved only to specific messages: message name or array of names"},
-			{Name: "numbers", Types: []string{"string", "[]int"}, Description: "Reserved field numbers or ranges"},
-			{Name: "names", Types: []string{"string", "[]string"}, Description: "Reserved field names"},
+			{Name: "numbers", Types: []string{"string", "[]int"}, Description: "Reserved field or enum value numbers or ranges"},
+			{Name: "names", Types: []string{"string", "[]string"}, Description: "Reserved field or enum value names"},
 		},
-		ValidOn: []ValidOn{annotations.AnnotationValidOnStruct},
+		ValidOn: []ValidOn{annotations.AnnotationValidOnStruct, annotations.AnnotationValidOnEnum},
 	},
 	{
 		Name:        "option",
@@ -127,9 +140,24 @@ var annotationSpecs = []AnnotationSpec{
 	},
 	{
 		Name:        "extend",
-		Description: "Extends an existing message",
+		Description: "Extends an existing message: on a struct all its fields become extensions, on a field only that field",
 		Params: []Param{
 			{Name: "message", Types: []string{"string"}, IsRequired: true},
+			{Name: "number", Types: []string{"int"}, Description: "Extension field number (field-level only)"},
+			{Name: "name", Types: []string{"string"}, Description: "Extension field name (field-level only)"},
+			{Name: "type", Types: []string{"string"}, Description: "Extension field type (field-level only)"},
+			{Name: "description", Types: []string{"string"}},
+		},
+		ValidOn: []ValidOn{annotations.AnnotationValidOnStruct, annotations.AnnotationValidOnField},
+	},
+	{
+		Name:        "extensions",
+		Description: "Declares extension ranges on a message (proto2/editions)",
+		Multiple:    true,
+		Params: []Param{
+			{Name: "ranges", Types: []string{"string", "[]string"}, Description: "Ranges like \"100 to 199, 1000 to max\""},
+			{Name: "from", Types: []string{"int"}, Description: "First number of the range"},
+			{Name: "to", Types: []string{"int", "string"}, Description: "Last number of the range, or max"},
 		},
 		ValidOn: []ValidOn{annotations.AnnotationValidOnStruct},
 	},
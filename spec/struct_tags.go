@@ -28,6 +28,16 @@ var tagParams = []TagParam{
 		Types:       []string{"bool"},
 		Description: "Mark field as optional (proto3)",
 	},
+	{
+		Name:        "required",
+		Types:       []string{"bool"},
+		Description: "Mark field as required (proto2)",
+	},
+	{
+		Name:        "legacy_required",
+		Types:       []string{"bool"},
+		Description: "Use legacy required presence (editions)",
+	},
 	{
 		Name:        "packed",
 		Types:       []string{"bool"},
@@ -51,7 +61,7 @@ var tagParams = []TagParam{
 	{
 		Name:        "default",
 		Types:       []string{"string"},
-		Description: "Default value (proto2)",
+		Description: "Default value (proto2), checked against the field type",
 	},
 	{
 		Name:        "for",
@@ -0,0 +1,200 @@
+package main_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pablor21/gonnotation/parser"
+	"github.com/pablor21/protoschemagen/plugin"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func userSchema(mutate func(file *descriptorpb.FileDescriptorProto)) *descriptorpb.FileDescriptorSet {
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("schema.proto"),
+		Package: proto.String("acme.v1"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{{
+			Name: proto.String("User"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				{Name: proto.String("id"), Number: proto.Int32(1), Type: descriptorpb.FieldDescriptorProto_TYPE_INT32.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+				{Name: proto.String("name"), Number: proto.Int32(2), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()},
+				{Name: proto.String("tags"), Number: proto.Int32(3), Type: descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(), Label: descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()},
+			},
+		}},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("UserService"),
+			Method: []*descriptorpb.MethodDescriptorProto{
+				{Name: proto.String("GetUser"), InputType: proto.String(".acme.v1.User"), OutputType: proto.String(".acme.v1.User")},
+			},
+		}},
+	}
+	if mutate != nil {
+		mutate(file)
+	}
+	return &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{file}}
+}
+
+// TestCheckBreaking verifies that wire-incompatible changes are reported and compatible ones are not
+func TestCheckBreaking(t *testing.T) {
+	baseline := userSchema(nil)
+
+	tests := []struct {
+		name     string
+		mutate   func(file *descriptorpb.FileDescriptorProto)
+		expected string
+	}{
+		{"unchanged", nil, ""},
+		{"compatible type", func(f *descriptorpb.FileDescriptorProto) {
+			f.MessageType[0].Field[0].Type = descriptorpb.FieldDescriptorProto_TYPE_INT64.Enum()
+		}, ""},
+		{"number reused", func(f *descriptorpb.FileDescriptorProto) {
+			f.MessageType[0].Field[1].Type = descriptorpb.FieldDescriptorProto_TYPE_DOUBLE.Enum()
+		}, "field number 2 reused with a different type: was string, now double"},
+		{"removed without reservation", func(f *descriptorpb.FileDescriptorProto) {
+			f.MessageType[0].Field = f.MessageType[0].Field[:2]
+		}, "field 3 was removed without reserving its number"},
+		{"removed with reservation", func(f *descriptorpb.FileDescriptorProto) {
+			f.MessageType[0].Field = f.MessageType[0].Field[:2]
+			f.MessageType[0].ReservedRange = []*descriptorpb.DescriptorProto_ReservedRange{{Start: proto.Int32(3), End: proto.Int32(4)}}
+		}, ""},
+		{"repeated to singular", func(f *descriptorpb.FileDescriptorProto) {
+			f.MessageType[0].Field[2].Label = descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum()
+		}, "field 3 changed from repeated to singular"},
+		{"streaming changed", func(f *descriptorpb.FileDescriptorProto) {
+			f.Service[0].Method[0].ServerStreaming = proto.Bool(true)
+		}, "streaming mode changed from unary to server streaming"},
+		{"rpc removed", func(f *descriptorpb.FileDescriptorProto) {
+			f.Service[0].Method = nil
+		}, "acme.v1.UserService.GetUser: RPC was removed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := plugin.CheckBreaking(baseline, userSchema(tt.mutate))
+			if tt.expected == "" {
+				if len(changes) != 0 {
+					t.Errorf("Expected no breaking changes, got %v", changes)
+				}
+				return
+			}
+			if len(changes) != 1 || !strings.Contains(changes[0].String(), tt.expected) {
+				t.Errorf("Expected breaking change %q, got %v", tt.expected, changes)
+			}
+		})
+	}
+}
+
+// TestLoadProtoBaseline verifies that .proto sources are accepted as a baseline
+func TestLoadProtoBaseline(t *testing.T) {
+	dir := t.TempDir()
+	source := `syntax = "proto3";
+
+package acme.v1;
+
+message User {
+  int32 id = 1;
+  string name = 2;
+  repeated string tags = 3;
+}
+
+service UserService {
+  rpc GetUser(User) returns (User);
+}
+`
+	if err := os.WriteFile(filepath.Join(dir, "schema.proto"), []byte(source), 0644); err != nil {
+		t.Fatalf("Failed to write baseline: %v", err)
+	}
+
+	for _, path := range []string{dir, filepath.Join(dir, "schema.proto")} {
+		baseline, err := plugin.LoadDescriptorBaseline(path)
+		if err != nil {
+			t.Fatalf("Failed to load baseline %s: %v", path, err)
+		}
+		if changes := plugin.CheckBreaking(baseline, userSchema(nil)); len(changes) != 0 {
+			t.Errorf("Expected no breaking changes against %s, got %v", path, changes)
+		}
+		removed := userSchema(func(f *descriptorpb.FileDescriptorProto) {
+			f.MessageType[0].Field = f.MessageType[0].Field[:2]
+		})
+		if changes := plugin.CheckBreaking(baseline, removed); len(changes) != 1 {
+			t.Errorf("Expected the removed field to be reported against %s, got %v", path, changes)
+		}
+	}
+}
+
+// TestBreakingCheckNamespaces verifies that the generated schema is checked file by file, with
+// the package of each namespace
+func TestBreakingCheckNamespaces(t *testing.T) {
+	orders := `syntax = "proto3";
+
+package acme.orders.v1;
+
+import "customers.proto";
+
+message Order {
+  acme.customers.v1.Customer customer = 1;
+  int64 total = 2;
+}
+`
+
+	tests := []struct {
+		name      string
+		customers string
+		wantErr   bool
+	}{
+		{
+			name: "unchanged",
+			customers: `syntax = "proto3";
+
+package acme.customers.v1;
+
+message Customer {
+  string name = 1;
+}
+`,
+		},
+		{
+			name: "removed field",
+			customers: `syntax = "proto3";
+
+package acme.customers.v1;
+
+message Customer {
+  string name = 1;
+  string email = 2;
+}
+`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, source := range map[string]string{"orders.proto": orders, "customers.proto": tt.customers} {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(source), 0644); err != nil {
+					t.Fatalf("Failed to write baseline: %v", err)
+				}
+			}
+
+			_, err := generateProtoFiles(t, &plugin.Config{
+				Syntax:             "proto3",
+				GenerationStrategy: parser.GenStrategyNamespace,
+				AutoNumberFields:   true,
+				StartFieldNumber:   1,
+				Namespaces: map[string]plugin.NamespaceConfig{
+					"customers": {Package: "acme.customers.v1"},
+					"orders":    {Package: "acme.orders.v1"},
+				},
+				BreakingCheck: &plugin.BreakingCheckConfig{Enabled: true, Against: dir, FailOnBreaking: true},
+			}, filepath.Join("testdata", "graph", "shop", "shop.go"))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error: %v, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package main_test
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestClientImplementsService verifies that the client declares every method of the service with
+// its original signature, whatever its streaming mode, so it can replace a local implementation
+func TestClientImplementsService(t *testing.T) {
+	client := chatStubs(t)["client.go"]
+
+	expected := []string{
+		"var _ chat.ChatService = (*ChatServiceClient)(nil)",
+		"func NewChatServiceClient(conn grpc.ClientConnInterface, opts ...ClientOption) *ChatServiceClient {",
+		"func (c *ChatServiceClient) Post(ctx context.Context, req chat.Message) (chat.Message, error) {",
+		"func (c *ChatServiceClient) Subscribe(ctx context.Context, req chat.Message) (<-chan chat.Message, error) {",
+		"func (c *ChatServiceClient) Replay(req chat.Message) (iter.Seq[chat.Message], error) {",
+		"func (c *ChatServiceClient) Feed(ctx context.Context, req chat.Message, outputs chan<- chat.Message) error {",
+		"func (c *ChatServiceClient) Watch(ctx context.Context, req chat.Message, outputs func(chat.Message) error) error {",
+		"func (c *ChatServiceClient) Upload(ctx context.Context, inputs <-chan chat.Message) (chat.Message, error) {",
+		"func (c *ChatServiceClient) Collect(inputs iter.Seq[chat.Message]) (chat.Message, error) {",
+		"func (c *ChatServiceClient) Chat(ctx context.Context, inputs <-chan chat.Message) (<-chan chat.Message, error) {",
+	}
+	for _, expected := range expected {
+		if !strings.Contains(client, expected) {
+			t.Errorf("Expected to find: %s\nIn client.go:\n%s", expected, client)
+		}
+	}
+}
+
+// TestClientOptions verifies that every call is made with the context of the caller, or of the
+// client for the methods without one, and with the call options of the client and of the context
+func TestClientOptions(t *testing.T) {
+	client := chatStubs(t)["client.go"]
+
+	expected := []string{
+		"func WithCallOptions(opts ...grpc.CallOption) ClientOption {",
+		"func WithContext(ctx context.Context) ClientOption {",
+		"func WithStreamErrorHandler(handler func(method string, err error)) ClientOption {",
+		"func ContextWithCallOptions(ctx context.Context, opts ...grpc.CallOption) context.Context {",
+		"protoResp, err := c.client.Post(ctx, protoReq, c.options.callOptionsFor(ctx)...)",
+		"stream, err := c.client.Upload(ctx, c.options.callOptionsFor(ctx)...)",
+		// Replay and Collect take no context
+		"ctx, cancel := context.WithCancel(c.options.context())\n\tstream, err := c.client.Replay(",
+		"ctx, cancel := context.WithCancel(c.options.context())\n\tdefer cancel()\n\tstream, err := c.client.Collect(",
+	}
+	for _, expected := range expected {
+		if !strings.Contains(client, expected) {
+			t.Errorf("Expected to find: %s\nIn client.go:\n%s", expected, client)
+		}
+	}
+
+	// One call for each method, the background context is only the default of WithContext
+	if got := strings.Count(client, "c.options.callOptionsFor(ctx)..."); got != 8 {
+		t.Errorf("Expected 8 calls made with the call options, got %d", got)
+	}
+	if got := strings.Count(client, "return context.Background()"); got != 1 {
+		t.Errorf("Expected 1 return of context.Background(), got %d\nIn client.go:\n%s", got, client)
+	}
+}
+
+// TestClientStreams verifies that the client streams requests and responses in every mode:
+// returned response streams report their errors to the handler, client streams receive the
+// response once the requests are sent, and bidirectional streams send while they receive
+func TestClientStreams(t *testing.T) {
+	client := chatStubs(t)["client.go"]
+
+	expected := []string{
+		`c.options.streamFailed("Subscribe", err)`,
+		`c.options.streamFailed("Replay", err)`,
+		`c.options.streamFailed("Chat", streamError(err, sendErr))`,
+		"if !yield(MessageFromProto(resp)) {",
+		"case outputs <- MessageFromProto(resp):",
+		"if err := outputs(MessageFromProto(resp)); err != nil {",
+		"for item := range inputs {",
+		"err = stream.CloseSend()",
+		"func streamError(recvErr error, sendErr <-chan error) error {",
+	}
+	for _, expected := range expected {
+		if !strings.Contains(client, expected) {
+			t.Errorf("Expected to find: %s\nIn client.go:\n%s", expected, client)
+		}
+	}
+
+	// Upload and Collect
+	if got := strings.Count(client, "protoResp, err := stream.CloseAndRecv()"); got != 2 {
+		t.Errorf("Expected 2 client streams receiving their response, got %d", got)
+	}
+}
@@ -0,0 +1,59 @@
+package main_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pablor21/protoschemagen/plugin"
+)
+
+// TestCollectionWrappers verifies that lists and maps whose values are collections are declared
+// with wrapper messages, nested ones included
+func TestCollectionWrappers(t *testing.T) {
+	schema, err := generateProto(t, &plugin.Config{
+		Package:          "acme.v1",
+		Syntax:           "proto3",
+		AutoNumberFields: true,
+		StartFieldNumber: 1,
+	}, filepath.Join("testdata", "collections", "matrix.go"))
+	if err != nil {
+		t.Fatalf("Failed to generate protobuf schema: %v", err)
+	}
+
+	expected := []string{
+		"message DoubleList {",
+		"repeated double values = 1;",
+		"repeated DoubleList rows = 1;",
+		"message StringList {",
+		"repeated string values = 1;",
+		"map<string, StringList> tags = 2;",
+		"message Int32List {",
+		"repeated int32 values = 1;",
+		"message Int32ListList {",
+		"repeated Int32List values = 1;",
+		"map<string, Int32ListList> grid = 3;",
+	}
+	for _, expected := range expected {
+		if !strings.Contains(schema, expected) {
+			t.Errorf("Expected to find: %s\nIn schema:\n%s", expected, schema)
+		}
+	}
+}
+
+// TestCollectionConversionErrors verifies that the stubs fail, naming the field, when the values
+// of a collection can't be converted by the adapters
+func TestCollectionConversionErrors(t *testing.T) {
+	_, err := generateStubs(t, &plugin.Config{
+		Package:          "acme.v1",
+		Syntax:           "proto3",
+		AutoNumberFields: true,
+		StartFieldNumber: 1,
+	}, filepath.Join("testdata", "collections", "levels", "levels.go"))
+	if err == nil {
+		t.Fatal("Expected the stubs to fail on the Levels field")
+	}
+	if !strings.Contains(err.Error(), "Thresholds.Levels") {
+		t.Errorf("Expected the error to name Thresholds.Levels, got: %v", err)
+	}
+}
@@ -0,0 +1,180 @@
+package main_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/pablor21/protoschemagen/plugin"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// TestDescriptorOutput verifies that the descriptor output is a valid descriptor set declaring the
+// messages, enums and services of the .proto file written with it
+func TestDescriptorOutput(t *testing.T) {
+	generated, err := generateProtoFiles(t, &plugin.Config{
+		Package:          "acme.store.v1",
+		Syntax:           "proto3",
+		AutoNumberFields: true,
+		StartFieldNumber: 1,
+		GenerateService:  true,
+		OutputFormats:    []string{"descriptor"},
+		Options:          map[string]string{"go_package": "github.com/acme/store/v1;storev1"},
+	}, filepath.Join("testdata", "descriptor", "store.go"))
+	if err != nil {
+		t.Fatalf("Failed to generate protobuf schema: %v", err)
+	}
+
+	var descriptor []byte
+	for _, path := range sortedPaths(generated) {
+		if !strings.HasSuffix(path, ".proto") {
+			descriptor = []byte(generated[path])
+		}
+	}
+	if descriptor == nil {
+		t.Fatalf("Expected a descriptor file, got %v", sortedPaths(generated))
+	}
+
+	emitted := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(descriptor, emitted); err != nil {
+		t.Fatalf("Failed to decode the descriptor set: %v", err)
+	}
+	if _, err := protodesc.NewFiles(emitted); err != nil {
+		t.Fatalf("Descriptor set doesn't resolve: %v", err)
+	}
+
+	expected := []string{
+		"enum acme.store.v1.Status",
+		"field acme.store.v1.GetOrderRequest.id = 1 LABEL_OPTIONAL TYPE_INT64 ",
+		"field acme.store.v1.Item.quantity = 2 LABEL_OPTIONAL TYPE_INT32 ",
+		"field acme.store.v1.Item.sku = 1 LABEL_OPTIONAL TYPE_STRING ",
+		"field acme.store.v1.Order.NotesEntry.key = 1 LABEL_OPTIONAL TYPE_STRING ",
+		"field acme.store.v1.Order.NotesEntry.value = 2 LABEL_OPTIONAL TYPE_STRING ",
+		"field acme.store.v1.Order.id = 1 LABEL_OPTIONAL TYPE_INT64 ",
+		"field acme.store.v1.Order.items = 3 LABEL_REPEATED TYPE_MESSAGE .acme.store.v1.Item",
+		"field acme.store.v1.Order.notes = 4 LABEL_REPEATED TYPE_MESSAGE .acme.store.v1.Order.NotesEntry",
+		"field acme.store.v1.Order.status = 2 LABEL_OPTIONAL TYPE_ENUM .acme.store.v1.Status",
+		"field acme.store.v1.Order.total = 5 LABEL_OPTIONAL TYPE_DOUBLE ",
+		"file acme.store.v1 go_package=github.com/acme/store/v1;storev1",
+		"message acme.store.v1.GetOrderRequest map_entry=false",
+		"message acme.store.v1.Item map_entry=false",
+		"message acme.store.v1.Order map_entry=false",
+		"message acme.store.v1.Order.NotesEntry map_entry=true",
+		"rpc acme.store.v1.OrderService.GetOrder(.acme.store.v1.GetOrderRequest) .acme.store.v1.Order client_streaming=false server_streaming=false",
+		"rpc acme.store.v1.OrderService.PlaceOrder(.acme.store.v1.Order) .acme.store.v1.Order client_streaming=false server_streaming=false",
+		"value acme.store.v1.Status.STATUS_CLOSED = 2",
+		"value acme.store.v1.Status.STATUS_OPEN = 1",
+		"value acme.store.v1.Status.STATUS_UNSPECIFIED = 0",
+	}
+	if got := describeSet(emitted); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Descriptor set doesn't match the proto file\ngot:\n%s\nexpected:\n%s",
+			strings.Join(got, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+// TestDescriptorRoundTrip verifies that the descriptor output describes the same schema as the
+// .proto file written with it, once compiled
+func TestDescriptorRoundTrip(t *testing.T) {
+	generated, err := generateProtoFiles(t, &plugin.Config{
+		Package:          "acme.store.v1",
+		Syntax:           "proto3",
+		AutoNumberFields: true,
+		StartFieldNumber: 1,
+		GenerateService:  true,
+		OutputFormats:    []string{"descriptor"},
+		Options:          map[string]string{"go_package": "github.com/acme/store/v1;storev1"},
+	}, filepath.Join("testdata", "descriptor", "store.go"))
+	if err != nil {
+		t.Fatalf("Failed to generate protobuf schema: %v", err)
+	}
+
+	dir := t.TempDir()
+	var descriptor []byte
+	for _, path := range sortedPaths(generated) {
+		if strings.HasSuffix(path, ".proto") {
+			if err := os.WriteFile(filepath.Join(dir, filepath.Base(path)), []byte(generated[path]), 0644); err != nil {
+				t.Fatalf("Failed to write %s: %v", path, err)
+			}
+			continue
+		}
+		descriptor = []byte(generated[path])
+	}
+	if descriptor == nil {
+		t.Fatalf("Expected a descriptor file, got %v", sortedPaths(generated))
+	}
+
+	emitted := &descriptorpb.FileDescriptorSet{}
+	if err := proto.Unmarshal(descriptor, emitted); err != nil {
+		t.Fatalf("Failed to decode the descriptor set: %v", err)
+	}
+	compiled, err := plugin.LoadDescriptorBaseline(dir)
+	if err != nil {
+		t.Fatalf("Failed to compile the generated proto file: %v", err)
+	}
+
+	if got, want := describeSet(emitted), describeSet(compiled); !reflect.DeepEqual(got, want) {
+		t.Errorf("Descriptor set doesn't match the compiled proto file\nemitted:\n%s\ncompiled:\n%s",
+			strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if changes := plugin.CheckBreaking(compiled, emitted); len(changes) != 0 {
+		t.Errorf("Expected no differences against the compiled proto file, got %v", changes)
+	}
+}
+
+// describeSet lists the declarations of the generated files of a descriptor set, one line each
+func describeSet(set *descriptorpb.FileDescriptorSet) []string {
+	var lines []string
+	for _, file := range set.File {
+		if strings.HasPrefix(file.GetName(), "google/protobuf/") {
+			continue
+		}
+		prefix := file.GetPackage()
+		lines = append(lines, fmt.Sprintf("file %s go_package=%s", prefix, file.GetOptions().GetGoPackage()))
+		for _, msg := range file.MessageType {
+			lines = append(lines, describeMessage(prefix, msg)...)
+		}
+		for _, enum := range file.EnumType {
+			lines = append(lines, describeEnum(prefix, enum)...)
+		}
+		for _, service := range file.Service {
+			for _, method := range service.Method {
+				lines = append(lines, fmt.Sprintf("rpc %s.%s.%s(%s) %s client_streaming=%v server_streaming=%v",
+					prefix, service.GetName(), method.GetName(), method.GetInputType(), method.GetOutputType(),
+					method.GetClientStreaming(), method.GetServerStreaming()))
+			}
+		}
+	}
+	sort.Strings(lines)
+	return lines
+}
+
+func describeMessage(prefix string, msg *descriptorpb.DescriptorProto) []string {
+	name := prefix + "." + msg.GetName()
+	lines := []string{fmt.Sprintf("message %s map_entry=%v", name, msg.GetOptions().GetMapEntry())}
+	for _, field := range msg.Field {
+		lines = append(lines, fmt.Sprintf("field %s.%s = %d %s %s %s", name, field.GetName(),
+			field.GetNumber(), field.GetLabel(), field.GetType(), field.GetTypeName()))
+	}
+	for _, nested := range msg.NestedType {
+		lines = append(lines, describeMessage(name, nested)...)
+	}
+	for _, enum := range msg.EnumType {
+		lines = append(lines, describeEnum(name, enum)...)
+	}
+	return lines
+}
+
+func describeEnum(prefix string, enum *descriptorpb.EnumDescriptorProto) []string {
+	name := prefix + "." + enum.GetName()
+	lines := []string{"enum " + name}
+	for _, value := range enum.Value {
+		lines = append(lines, fmt.Sprintf("value %s.%s = %d", name, value.GetName(), value.GetNumber()))
+	}
+	return lines
+}
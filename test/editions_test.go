@@ -0,0 +1,133 @@
+package main_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pablor21/protoschemagen/plugin"
+)
+
+func editionsConfig(presence string) *plugin.Config {
+	return &plugin.Config{
+		Package:          "acme.v1",
+		Syntax:           plugin.SyntaxEditions,
+		AutoNumberFields: true,
+		StartFieldNumber: 1,
+		Features:         plugin.EditionFeatures{FieldPresence: presence},
+		Options:          map[string]string{"go_package": "github.com/acme/accounts/v1"},
+	}
+}
+
+// TestEditionsSchema verifies the edition header, that file features are only written when they
+// differ from the edition defaults, and the presence features of single fields
+func TestEditionsSchema(t *testing.T) {
+	tests := []struct {
+		name       string
+		presence   string
+		expected   []string
+		unexpected []string
+	}{
+		{
+			name:     "edition defaults",
+			presence: "",
+			expected: []string{
+				`edition = "2023";`,
+				"int64 id = 1 [features.field_presence = LEGACY_REQUIRED];",
+				"string name = 2;",
+				"string nickname = 3;",
+				"repeated string tags = 6;",
+			},
+			unexpected: []string{"syntax =", "option features.field_presence", "optional "},
+		},
+		{
+			name:     "implicit presence",
+			presence: "implicit",
+			expected: []string{
+				`edition = "2023";`,
+				"option features.field_presence = IMPLICIT;",
+				"int64 id = 1 [features.field_presence = LEGACY_REQUIRED];",
+				"string name = 2;",
+				"string nickname = 3 [features.field_presence = EXPLICIT];",
+			},
+		},
+		{
+			name:       "explicit presence written as the default",
+			presence:   "EXPLICIT",
+			expected:   []string{"string nickname = 3;"},
+			unexpected: []string{"option features.field_presence"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := generateProto(t, editionsConfig(tt.presence), filepath.Join("testdata", "editions", "account.go"))
+			if err != nil {
+				t.Fatalf("Failed to generate protobuf schema: %v", err)
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(schema, expected) {
+					t.Errorf("Expected to find: %s\nIn schema:\n%s", expected, schema)
+				}
+			}
+			for _, unexpected := range tt.unexpected {
+				if strings.Contains(schema, unexpected) {
+					t.Errorf("Expected not to find: %s\nIn schema:\n%s", unexpected, schema)
+				}
+			}
+		})
+	}
+}
+
+// TestEditionsStubPresence verifies that the adapters set value fields with explicit or legacy
+// required presence through a pointer and read them through their getter
+func TestEditionsStubPresence(t *testing.T) {
+	tests := []struct {
+		name     string
+		presence string
+		expected []string
+	}{
+		{
+			name:     "explicit presence",
+			presence: "",
+			expected: []string{
+				"Id: presentValue(orig.ID),",
+				"Name: presentValue(orig.Name),",
+				"Nickname: orig.Nickname,",
+				"Plan: presentValue(PlanToProto(orig.Plan)),",
+				"Avatar: orig.Avatar,",
+				"ID: proto.GetId(),",
+				"Name: proto.GetName(),",
+				"Plan: PlanFromProto(proto.GetPlan()),",
+				"func presentValue[T any](val T) *T",
+			},
+		},
+		{
+			name:     "implicit presence",
+			presence: "IMPLICIT",
+			expected: []string{
+				"Id: presentValue(orig.ID),",
+				"Name: orig.Name,",
+				"Nickname: orig.Nickname,",
+				"Plan: PlanToProto(orig.Plan),",
+				"ID: proto.GetId(),",
+				"Name: proto.Name,",
+				"Plan: PlanFromProto(proto.Plan),",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stubs, err := generateStubs(t, editionsConfig(tt.presence), filepath.Join("testdata", "editions", "account.go"))
+			if err != nil {
+				t.Fatalf("Failed to generate stubs: %v", err)
+			}
+			for _, expected := range tt.expected {
+				if !strings.Contains(stubs["types.go"], expected) {
+					t.Errorf("Expected to find: %s\nIn types.go:\n%s", expected, stubs["types.go"])
+				}
+			}
+		})
+	}
+}
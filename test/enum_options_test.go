@@ -0,0 +1,96 @@
+package main_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pablor21/protoschemagen/plugin"
+)
+
+func enumOptionsConfig() *plugin.Config {
+	return &plugin.Config{
+		Package:          "acme.v1",
+		Syntax:           "proto3",
+		AutoNumberFields: true,
+		StartFieldNumber: 1,
+		Options:          map[string]string{"go_package": "github.com/acme/levels/v1"},
+	}
+}
+
+// TestEnumOptions verifies allow_alias, the deprecated and custom options of enums and values,
+// and the reserved numbers and names of enums
+func TestEnumOptions(t *testing.T) {
+	schema, err := generateProto(t, enumOptionsConfig(), filepath.Join("testdata", "enums", "options", "level.go"))
+	if err != nil {
+		t.Fatalf("Failed to generate protobuf schema: %v", err)
+	}
+
+	expected := []string{
+		"enum Level {\n  option allow_alias = true;\n  option (acme.enum_label) = \"severity\";",
+		"LEVEL_HIGH = 2 [deprecated = true];",
+		"LEVEL_SEVERE = 2;",
+		"reserved 5, 8, 9;",
+		`reserved "LEVEL_LEGACY";`,
+		"enum Mode {\n  option deprecated = true;\n  MODE_OFF = 0;",
+		`MODE_ON = 1 [deprecated = true, (acme.value_label) = "on"];`,
+	}
+	for _, expected := range expected {
+		if !strings.Contains(schema, expected) {
+			t.Errorf("Expected to find: %s\nIn schema:\n%s", expected, schema)
+		}
+	}
+
+	// Mode allows aliases but has none, which protoc rejects, and the options of values aren't
+	// options of the file
+	for _, unexpected := range []string{"enum Mode {\n  option allow_alias", "\noption (acme.value_label)"} {
+		if strings.Contains(schema, unexpected) {
+			t.Errorf("Expected not to find: %s\nIn schema:\n%s", unexpected, schema)
+		}
+	}
+}
+
+// TestEnumOptionsValidation verifies that values can only share a number with allow_alias and
+// can't use reserved numbers or names
+func TestEnumOptionsValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		errors string
+	}{
+		{name: "alias without allow_alias", file: "alias/alias.go", errors: "1 error(s)"},
+		{name: "reserved number and name", file: "conflict/conflict.go", errors: "2 error(s)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := generateProto(t, enumOptionsConfig(), filepath.Join("testdata", "enums", "options", tt.file))
+			if err == nil {
+				t.Fatal("Expected the generation to fail")
+			}
+			if !strings.Contains(err.Error(), tt.errors) {
+				t.Errorf("Expected %s, got: %v", tt.errors, err)
+			}
+		})
+	}
+}
+
+// TestEnumAliasStubs verifies that the adapters leave proto aliases out of the table keyed by
+// protobuf values, where they would repeat the key of the value they alias
+func TestEnumAliasStubs(t *testing.T) {
+	stubs, err := generateStubs(t, enumOptionsConfig(), filepath.Join("testdata", "enums", "options", "level.go"))
+	if err != nil {
+		t.Fatalf("Failed to generate stubs: %v", err)
+	}
+	types := stubs["types.go"]
+
+	expected := []string{
+		"\toptions.LevelSevere: pb.Level_LEVEL_SEVERE,\n",
+		"var LevelFromProtoValues = map[pb.Level]options.Level{\n\tpb.Level_LEVEL_LOW: options.LevelLow,\n\tpb.Level_LEVEL_MEDIUM: options.LevelMedium,\n\tpb.Level_LEVEL_HIGH: options.LevelHigh,\n}",
+	}
+	for _, expected := range expected {
+		if !strings.Contains(types, expected) {
+			t.Errorf("Expected to find: %s\nIn types.go:\n%s", expected, types)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pablor21/protoschemagen/plugin"
+)
+
+// TestEnumStyle verifies the prefixing and numbering of enum values
+func TestEnumStyle(t *testing.T) {
+	statusFile := filepath.Join("testdata", "enums", "status.go")
+
+	tests := []struct {
+		name      string
+		style     plugin.EnumStyleConfig
+		expected  []string
+		forbidden []string
+	}{
+		{
+			name:     "go names",
+			expected: []string{"STATUS_UNSPECIFIED = 0;", "STATUS_ACTIVE = 1;", "RED = 0;", "GREEN = 1;"},
+		},
+		{
+			name:      "prefixed values",
+			style:     plugin.EnumStyleConfig{PrefixValues: true},
+			expected:  []string{"STATUS_ACTIVE = 1;", "COLOR_RED = 0;", "COLOR_GREEN = 1;"},
+			forbidden: []string{"STATUS_STATUS_ACTIVE"},
+		},
+		{
+			name:     "unspecified zero value",
+			style:    plugin.EnumStyleConfig{PrefixValues: true, UnspecifiedZeroValue: true},
+			expected: []string{"STATUS_UNSPECIFIED = 0;", "STATUS_ACTIVE = 1;", "STATUS_INACTIVE = 2;", "COLOR_UNSPECIFIED = 0;", "COLOR_RED = 1;", "COLOR_GREEN = 2;"},
+		},
+		{
+			name:     "offset",
+			style:    plugin.EnumStyleConfig{PrefixValues: true, UnspecifiedZeroValue: true, Offset: 10},
+			expected: []string{"STATUS_UNSPECIFIED = 0;", "STATUS_ACTIVE = 10;", "STATUS_INACTIVE = 11;", "COLOR_RED = 10;"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schema, err := generateProto(t, &plugin.Config{
+				Package:          "acme.v1",
+				Syntax:           "proto3",
+				AutoNumberFields: true,
+				StartFieldNumber: 1,
+				EnumStyle:        test.style,
+			}, statusFile)
+			if err != nil {
+				t.Fatalf("Failed to generate protobuf schema: %v", err)
+			}
+
+			for _, expected := range test.expected {
+				if !strings.Contains(schema, expected) {
+					t.Errorf("Expected to find: %s\nIn schema:\n%s", expected, schema)
+				}
+			}
+			for _, forbidden := range test.forbidden {
+				if strings.Contains(schema, forbidden) {
+					t.Errorf("Expected not to find: %s\nIn schema:\n%s", forbidden, schema)
+				}
+			}
+		})
+	}
+}
+
+// TestEnumValueCollisions verifies that enums of different Go packages merged into one proto
+// package can't share value names, unless prefix_values is set
+func TestEnumValueCollisions(t *testing.T) {
+	files := []string{
+		filepath.Join("testdata", "enums", "status.go"),
+		filepath.Join("testdata", "enums", "billing", "paint.go"),
+	}
+
+	tests := []struct {
+		name    string
+		style   plugin.EnumStyleConfig
+		wantErr bool
+	}{
+		{name: "go names collide", wantErr: true},
+		{name: "prefixed values", style: plugin.EnumStyleConfig{PrefixValues: true}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := generateProto(t, &plugin.Config{
+				Package:          "acme.v1",
+				Syntax:           "proto3",
+				AutoNumberFields: true,
+				StartFieldNumber: 1,
+				EnumStyle:        test.style,
+			}, files...)
+			if (err != nil) != test.wantErr {
+				t.Errorf("Expected error: %v, got: %v", test.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package main_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pablor21/gonnotation/parser"
+	"github.com/pablor21/protoschemagen/plugin"
+)
+
+// TestFileGraph verifies the references and imports between the files of the multi-file
+// strategies
+func TestFileGraph(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy parser.GenStrategy
+		files    []string
+		wantErr  bool
+		expected map[string][]string // Generated file -> content it must contain
+	}{
+		{
+			name:     "same-named types of different packages",
+			strategy: parser.GenStrategyPackage,
+			files: []string{
+				filepath.Join("testdata", "graph", "user", "user.go"),
+				filepath.Join("testdata", "graph", "billing", "billing.go"),
+			},
+			expected: map[string][]string{
+				"user.proto": {
+					`import "billing.proto";`,
+					"  Address home = 1;",
+					"  billing.Address billing = 2;",
+				},
+				"billing.proto": {"message Address {", "string iban = 1;"},
+			},
+		},
+		{
+			name:     "import cycle",
+			strategy: parser.GenStrategyNamespace,
+			files:    []string{filepath.Join("testdata", "graph", "cycle", "cycle.go")},
+			wantErr:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			generated, err := generateProtoFiles(t, &plugin.Config{
+				Syntax:             "proto3",
+				GenerationStrategy: test.strategy,
+				AutoNumberFields:   true,
+				StartFieldNumber:   1,
+			}, test.files...)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Expected error: %v, got: %v", test.wantErr, err)
+			}
+
+			for file, expected := range test.expected {
+				content, ok := generated[file]
+				if !ok {
+					t.Errorf("Expected file %s to be generated, got %v", file, sortedPaths(generated))
+					continue
+				}
+				for _, e := range expected {
+					if !strings.Contains(content, e) {
+						t.Errorf("Expected to find in %s: %s\nIn schema:\n%s", file, e, content)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package main_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pablor21/protoschemagen/plugin"
+)
+
+// TestByteArrays verifies that byte slices and fixed-size byte arrays, however the byte type is
+// spelled, are bytes fields and not repeated ones
+func TestByteArrays(t *testing.T) {
+	schema, err := generateProto(t, &plugin.Config{
+		Package:          "acme.v1",
+		Syntax:           "proto3",
+		AutoNumberFields: true,
+		StartFieldNumber: 1,
+	}, filepath.Join("testdata", "types", "blob.go"))
+	if err != nil {
+		t.Fatalf("Failed to generate protobuf schema: %v", err)
+	}
+
+	for _, expected := range []string{"bytes data = 1;", "bytes raw = 2;", "bytes digest = 3;", "repeated int32 deltas = 4;"} {
+		if !strings.Contains(schema, expected) {
+			t.Errorf("Expected to find: %s\nIn schema:\n%s", expected, schema)
+		}
+	}
+	if strings.Contains(schema, "repeated bytes") {
+		t.Errorf("Expected no repeated bytes field in schema:\n%s", schema)
+	}
+}
@@ -0,0 +1,99 @@
+package main_test
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/pablor21/gonnotation/parser"
+	"github.com/pablor21/protoschemagen/plugin"
+)
+
+// parseFiles parses the given Go files into a generation context
+func parseFiles(t *testing.T, files ...string) *parser.GenerationContext {
+	t.Helper()
+
+	p := parser.NewParser()
+	if err := p.ParsePackages(files); err != nil {
+		t.Fatalf("Failed to parse %v: %v", files, err)
+	}
+
+	structs := p.ExtractStructs()
+	enums := p.ExtractEnums()
+	interfaces := p.ExtractInterfaces()
+	functions := p.ExtractFunctions()
+
+	ctx := parser.NewGenerationContextWithInterfaces(
+		p, structs, interfaces, enums, functions,
+		structs, interfaces, enums, functions,
+		&parser.CoreConfig{}, nil, nil, make(map[string]bool),
+	)
+	if ctx == nil {
+		t.Fatal("Failed to build generation context")
+	}
+	return ctx
+}
+
+// generateProto parses the given Go files and generates their proto schema
+func generateProto(t *testing.T, config *plugin.Config, files ...string) (string, error) {
+	t.Helper()
+
+	schema, err := plugin.NewPlugin(config).Generate(parseFiles(t, files...))
+	return string(schema), err
+}
+
+// generateProtoFiles parses the given Go files and generates their proto files, by path
+func generateProtoFiles(t *testing.T, config *plugin.Config, files ...string) (map[string]string, error) {
+	t.Helper()
+
+	output, err := plugin.NewPlugin(config).GenerateMulti(parseFiles(t, files...))
+	if err != nil {
+		return nil, err
+	}
+	generated := make(map[string]string)
+	for _, file := range output.Files {
+		generated[file.Path] = string(file.Content)
+	}
+	return generated, nil
+}
+
+// generateStubs parses the given Go files and generates their stubs into a temporary adapter
+// package, returning the generated Go files by name
+func generateStubs(t *testing.T, config *plugin.Config, files ...string) (map[string]string, error) {
+	t.Helper()
+
+	if config.GenerateStubs == nil {
+		config.GenerateStubs = &plugin.StubConfig{Enabled: true}
+	}
+	adapterDir := t.TempDir()
+	config.GenerateStubs.AdapterPackage = adapterDir
+
+	if _, err := generateProtoFiles(t, config, files...); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(adapterDir)
+	if err != nil {
+		t.Fatalf("Failed to read the adapter package: %v", err)
+	}
+	stubs := make(map[string]string)
+	for _, entry := range entries {
+		content, err := os.ReadFile(filepath.Join(adapterDir, entry.Name()))
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", entry.Name(), err)
+		}
+		stubs[entry.Name()] = string(content)
+	}
+	return stubs, nil
+}
+
+// sortedPaths returns the paths of the generated files, sorted
+func sortedPaths(generated map[string]string) []string {
+	paths := make([]string, 0, len(generated))
+	for path := range generated {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
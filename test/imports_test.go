@@ -0,0 +1,107 @@
+package main_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pablor21/protoschemagen/plugin"
+)
+
+// TestTypeImports verifies that each file imports the proto files of the well-known and mapped
+// types it uses, RPC wrappers and Empty included, and only those
+func TestTypeImports(t *testing.T) {
+	tests := []struct {
+		name       string
+		multiple   bool
+		expected   map[string][]string // Generated file -> content it must contain
+		unexpected map[string][]string
+	}{
+		{
+			name: "single file",
+			expected: map[string][]string{
+				"schema.proto": {
+					`import "acme/money/amount.proto";`,
+					`import "google/protobuf/any.proto";`,
+					`import "google/protobuf/duration.proto";`,
+					`import "google/protobuf/empty.proto";`,
+					`import "google/protobuf/timestamp.proto";`,
+					`import "google/protobuf/wrappers.proto";`,
+					`import "google/type/decimal.proto";`,
+					"acme.money.Amount amount = 6;",
+					"google.type.Decimal rate = 7;",
+					"rpc Post(Entry) returns (google.protobuf.Empty);",
+					"rpc Count(Tag) returns (google.protobuf.Int64Value);",
+				},
+			},
+		},
+		{
+			name:     "one file per type",
+			multiple: true,
+			expected: map[string][]string{
+				"schema/entry.proto": {
+					`import "acme/money/amount.proto";`,
+					`import "google/protobuf/any.proto";`,
+					`import "google/protobuf/duration.proto";`,
+					`import "google/protobuf/timestamp.proto";`,
+					`import "google/protobuf/wrappers.proto";`,
+					`import "google/type/decimal.proto";`,
+				},
+				"schema/ledger_service.proto": {
+					`import "google/protobuf/empty.proto";`,
+					`import "google/protobuf/wrappers.proto";`,
+				},
+			},
+			unexpected: map[string][]string{
+				"schema/entry.proto":          {"empty.proto"},
+				"schema/ledger_service.proto": {"timestamp.proto", "amount.proto"},
+				"schema/tag.proto":            {"import "},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			config := &plugin.Config{
+				Package:          "acme.v1",
+				Syntax:           "proto3",
+				AutoNumberFields: true,
+				StartFieldNumber: 1,
+				GenerateService:  true,
+				OptionalStyle:    "wrappers",
+				TypeImports:      map[string]string{"acme.money": "acme/money/amount.proto"},
+			}
+			config.TypeMappings = map[string]string{
+				"money.Amount":  "acme.money.Amount",
+				"money.Decimal": "google.type.Decimal",
+			}
+			if test.multiple {
+				config.GenerationStrategy = plugin.GenStrategyMultiple
+				config.Output = "schema/{type_name}.proto"
+			}
+
+			generated, err := generateProtoFiles(t, config, filepath.Join("testdata", "imports", "ledger", "ledger.go"))
+			if err != nil {
+				t.Fatalf("Failed to generate protobuf schema: %v", err)
+			}
+			for file, expected := range test.expected {
+				for _, e := range expected {
+					if !strings.Contains(generated[file], e) {
+						t.Errorf("Expected to find in %s: %s\nIn schema:\n%s", file, e, generated[file])
+					}
+				}
+			}
+			for file, unexpected := range test.unexpected {
+				content, ok := generated[file]
+				if !ok {
+					t.Fatalf("Expected a %s file, got %v", file, sortedPaths(generated))
+				}
+				for _, u := range unexpected {
+					if strings.Contains(content, u) {
+						t.Errorf("Expected not to find in %s: %s\nIn schema:\n%s", file, u, content)
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package main_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/pablor21/protoschemagen/plugin"
+)
+
+// TestLockFileNumbering verifies that locked numbers are reused, new fields get
+// fresh numbers and removed fields are reserved
+func TestLockFileNumbering(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "protoschemagen.lock.json")
+
+	lock, err := plugin.LoadLockFile(path)
+	if err != nil {
+		t.Fatalf("Failed to load missing lockfile: %v", err)
+	}
+
+	// First run: id, name, email numbered in order
+	counter := 1
+	for _, name := range []string{"id", "name", "email"} {
+		lock.RecordField("User", name, lock.NextFieldNumber("User", &counter, nil))
+	}
+	lock.ReconcileMessage("User", map[string]int{"id": 1, "name": 2, "email": 3})
+	if err := lock.Save(); err != nil {
+		t.Fatalf("Failed to save lockfile: %v", err)
+	}
+
+	// Second run: "name" was removed and "phone" was added
+	lock, err = plugin.LoadLockFile(path)
+	if err != nil {
+		t.Fatalf("Failed to load lockfile: %v", err)
+	}
+
+	for name, expected := range map[string]int{"id": 1, "email": 3} {
+		if num, ok := lock.FieldNumber("User", name); !ok || num != expected {
+			t.Errorf("Expected %s to keep number %d, got %d (found: %v)", name, expected, num, ok)
+		}
+	}
+
+	counter = 1
+	phone := lock.NextFieldNumber("User", &counter, nil)
+	if phone != 4 {
+		t.Errorf("Expected new field to get fresh number 4, got %d", phone)
+	}
+	lock.RecordField("User", "phone", phone)
+
+	reserved := lock.ReconcileMessage("User", map[string]int{"id": 1, "email": 3, "phone": 4})
+	if !reflect.DeepEqual(reserved, []int{2}) {
+		t.Errorf("Expected removed field number 2 to be reserved, got %v", reserved)
+	}
+	if names := lock.ReservedFieldNames("User"); !reflect.DeepEqual(names, []string{"name"}) {
+		t.Errorf("Expected removed field name to be reserved, got %v", names)
+	}
+
+	// A removed number is never handed out again
+	counter = 1
+	if next := lock.NextFieldNumber("User", &counter, nil); next != 5 {
+		t.Errorf("Expected next fresh number to be 5, got %d", next)
+	}
+}
+
+// TestLockFileRenumbering verifies that the old number of a renumbered field is only reserved
+// while no other field uses it
+func TestLockFileRenumbering(t *testing.T) {
+	lock := plugin.NewLockFile()
+	lock.RecordField("User", "id", 1)
+	lock.RecordField("User", "name", 2)
+
+	// The fields swap their explicit numbers
+	lock.RecordField("User", "id", 2)
+	lock.RecordField("User", "name", 1)
+	if reserved := lock.ReconcileMessage("User", map[string]int{"id": 2, "name": 1}); len(reserved) != 0 {
+		t.Errorf("Expected no reserved numbers after swapping numbers in use, got %v", reserved)
+	}
+
+	// A field moving to a free number leaves its old one reserved
+	lock.RecordField("User", "name", 3)
+	if reserved := lock.ReconcileMessage("User", map[string]int{"id": 2, "name": 3}); !reflect.DeepEqual(reserved, []int{1}) {
+		t.Errorf("Expected old number 1 to be reserved, got %v", reserved)
+	}
+}
+
+// TestLockFileEnumNumberReuse verifies that the numbers of removed enum values stay reserved,
+// and that explicit numbers can't take them back
+func TestLockFileEnumNumberReuse(t *testing.T) {
+	lock := plugin.NewLockFile()
+	lock.RecordEnumValue("Priority", "PRIORITY_LOW", 0)
+	lock.RecordEnumValue("Priority", "PRIORITY_HIGH", 1)
+	lock.RecordEnumValue("Priority", "PRIORITY_URGENT", 2)
+
+	// PRIORITY_URGENT was removed
+	reserved, _ := lock.ReconcileEnum("Priority", map[string]int{"PRIORITY_LOW": 0, "PRIORITY_HIGH": 1})
+	if !reflect.DeepEqual(reserved, []int{2}) {
+		t.Fatalf("Expected removed value number 2 to be reserved, got %v", reserved)
+	}
+
+	// A value moving to the removed number doesn't release it
+	lock.RecordEnumValue("Priority", "PRIORITY_HIGH", 2)
+	if !lock.IsReservedEnumNumber("Priority", 2) {
+		t.Error("Expected number 2 to stay reserved after a value was moved to it")
+	}
+
+	// The generation fails when an explicit number takes a removed one
+	path := filepath.Join(t.TempDir(), "protoschemagen.lock.json")
+	lock, err := plugin.LoadLockFile(path)
+	if err != nil {
+		t.Fatalf("Failed to load missing lockfile: %v", err)
+	}
+	lock.RecordEnumValue("Priority", "PRIORITY_LOW", 0)
+	lock.RecordEnumValue("Priority", "PRIORITY_HIGH", 2)
+	lock.ReconcileEnum("Priority", map[string]int{"PRIORITY_LOW": 0})
+	if err := lock.Save(); err != nil {
+		t.Fatalf("Failed to save lockfile: %v", err)
+	}
+
+	_, err = generateProto(t, &plugin.Config{
+		Package:          "acme.v1",
+		Syntax:           "proto3",
+		AutoNumberFields: true,
+		StartFieldNumber: 1,
+		LockFile:         path,
+	}, filepath.Join("testdata", "lockfile", "priority.go"))
+	if err == nil {
+		t.Error("Expected the explicit number 2 of PRIORITY_URGENT to fail as reserved in the lockfile")
+	}
+}
@@ -0,0 +1,61 @@
+package main_test
+
+import (
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pablor21/protoschemagen/plugin"
+)
+
+// TestMapConversions verifies that maps of messages, message pointers and enums convert through
+// per-map helpers, and that maps of scalars are assigned as they are
+func TestMapConversions(t *testing.T) {
+	stubs, err := generateStubs(t, &plugin.Config{
+		Package:          "acme.v1",
+		Syntax:           "proto3",
+		AutoNumberFields: true,
+		StartFieldNumber: 1,
+		Options:          map[string]string{"go_package": "github.com/acme/warehouse/v1"},
+	}, filepath.Join("testdata", "maps", "warehouse.go"))
+	if err != nil {
+		t.Fatalf("Failed to generate stubs: %v", err)
+	}
+	types := stubs["types.go"]
+	if _, err := parser.ParseFile(token.NewFileSet(), "types.go", types, 0); err != nil {
+		t.Fatalf("Generated types.go doesn't parse: %v\n%s", err, types)
+	}
+
+	expected := []string{
+		"Counts: orig.Counts,",
+		"Counts: proto.Counts,",
+		"Items: ConvertMapToProto_string_Item(orig.Items),",
+		"Items: ConvertMapFromProto_string_Item(proto.Items),",
+		"Refs: ConvertMapToProto_string_StarItem(orig.Refs),",
+		"Refs: ConvertMapFromProto_string_StarItem(proto.Refs),",
+		"Grades: ConvertMapToProto_int64_Grade(orig.Grades),",
+		"Grades: ConvertMapFromProto_int64_Grade(proto.Grades),",
+
+		// Messages are stored by pointer in the protobuf maps
+		"func ConvertMapToProto_string_Item(orig map[string]warehouse.Item) map[string]*pb.Item {",
+		"func ConvertMapFromProto_string_Item(proto map[string]*pb.Item) map[string]warehouse.Item {",
+		"result[k] = ItemToProto(v)",
+		// Pointers skip the nil values a protobuf map can't hold
+		"if v != nil {\n\t\t\tresult[k] = ItemToProto(*v)\n\t\t}",
+		"converted := ItemFromProto(v)\n\t\tresult[k] = &converted",
+		// Enums convert through their value tables
+		"func ConvertMapToProto_int64_Grade(orig map[int64]warehouse.Grade) map[int64]pb.Grade {",
+		"result[k] = GradeToProto(v)",
+		"result[k] = GradeFromProto(v)",
+	}
+	for _, expected := range expected {
+		if !strings.Contains(types, expected) {
+			t.Errorf("Expected to find: %s\nIn types.go:\n%s", expected, types)
+		}
+	}
+	if strings.Contains(types, "ConvertMapToProto_string_int32") {
+		t.Errorf("Expected maps of scalars not to get a helper\nIn types.go:\n%s", types)
+	}
+}
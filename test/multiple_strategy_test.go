@@ -0,0 +1,72 @@
+package main_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pablor21/protoschemagen/plugin"
+)
+
+// TestMultipleStrategy verifies that the multiple strategy writes one file per type, named after
+// the type with {type_name}
+func TestMultipleStrategy(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		wantErr  bool
+		files    []string
+		expected map[string][]string // Generated file -> content it must contain
+	}{
+		{
+			name:   "one file per type",
+			output: "schema/{type_name}.proto",
+			files:  []string{"schema/category.proto", "schema/product.proto", "schema/product_status.proto"},
+			expected: map[string][]string{
+				"schema/product.proto": {
+					`import "category.proto";`,
+					`import "product_status.proto";`,
+					"message Product {",
+					"message Variant {",
+				},
+				"schema/category.proto":       {"message Category {"},
+				"schema/product_status.proto": {"enum ProductStatus {"},
+			},
+		},
+		{
+			name:    "pattern without the type name",
+			output:  "schema/types.proto",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			generated, err := generateProtoFiles(t, &plugin.Config{
+				Syntax:             "proto3",
+				GenerationStrategy: plugin.GenStrategyMultiple,
+				Output:             test.output,
+				AutoNumberFields:   true,
+				StartFieldNumber:   1,
+			}, filepath.Join("testdata", "multiple", "catalog.go"))
+			if (err != nil) != test.wantErr {
+				t.Fatalf("Expected error: %v, got: %v", test.wantErr, err)
+			}
+			if test.wantErr {
+				return
+			}
+
+			if paths := sortedPaths(generated); !reflect.DeepEqual(paths, test.files) {
+				t.Errorf("Expected files %v, got %v", test.files, paths)
+			}
+			for file, expected := range test.expected {
+				for _, e := range expected {
+					if !strings.Contains(generated[file], e) {
+						t.Errorf("Expected to find in %s: %s\nIn schema:\n%s", file, e, generated[file])
+					}
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package main_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pablor21/protoschemagen/plugin"
+)
+
+func nestedConfig() *plugin.Config {
+	return &plugin.Config{
+		Package:          "acme.v1",
+		Syntax:           "proto3",
+		AutoNumberFields: true,
+		StartFieldNumber: 1,
+		Options:          map[string]string{"go_package": "github.com/acme/users/v1"},
+	}
+}
+
+// TestNestedMessages verifies that anonymous struct fields and nested_in structs are declared
+// inside their parent message, and that the schema compiles
+func TestNestedMessages(t *testing.T) {
+	schema, err := generateProto(t, nestedConfig(), filepath.Join("testdata", "nested", "user.go"))
+	if err != nil {
+		t.Fatalf("Failed to generate protobuf schema: %v", err)
+	}
+
+	expected := []string{
+		"message User {\n  message Address {\n    string street = 1;\n    string city = 2;\n  }",
+		"  message Contacts {\n    string email = 1;\n  }",
+		"  message Settings {",
+		"    message Layout {\n      int32 columns = 1;\n    }",
+		"    User.Settings.Layout layout = 2;",
+		"  User.Address address = 2;",
+		"  repeated User.Contacts contacts = 3;",
+		"  User.Settings settings = 4;",
+	}
+	for _, expected := range expected {
+		if !strings.Contains(schema, expected) {
+			t.Errorf("Expected to find: %s\nIn schema:\n%s", expected, schema)
+		}
+	}
+	for _, unexpected := range []string{"unknown", "\nmessage Settings", "\nmessage Layout"} {
+		if strings.Contains(schema, unexpected) {
+			t.Errorf("Expected not to find: %s\nIn schema:\n%s", unexpected, schema)
+		}
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "user.proto"), []byte(schema), 0644); err != nil {
+		t.Fatalf("Failed to write the schema: %v", err)
+	}
+	compiled, err := plugin.LoadDescriptorBaseline(dir)
+	if err != nil {
+		t.Fatalf("Failed to compile the generated schema: %v", err)
+	}
+	lines := strings.Join(describeSet(compiled), "\n")
+	for _, expected := range []string{
+		"message acme.v1.User.Address map_entry=false",
+		"message acme.v1.User.Settings.Layout map_entry=false",
+		"field acme.v1.User.contacts = 3 LABEL_REPEATED TYPE_MESSAGE .acme.v1.User.Contacts",
+	} {
+		if !strings.Contains(lines, expected) {
+			t.Errorf("Expected the compiled schema to declare: %s\nIn:\n%s", expected, lines)
+		}
+	}
+}
+
+// TestNestedMessageStubs verifies that the adapters convert nested messages with functions named
+// after the struct they are generated from and the nested protobuf type
+func TestNestedMessageStubs(t *testing.T) {
+	stubs, err := generateStubs(t, nestedConfig(), filepath.Join("testdata", "nested", "user.go"))
+	if err != nil {
+		t.Fatalf("Failed to generate stubs: %v", err)
+	}
+	types := stubs["types.go"]
+
+	expected := []string{
+		"func User_AddressToProto(orig struct{ Street string; City string }) *pb.User_Address {",
+		"func User_AddressFromProto(proto *pb.User_Address) struct{ Street string; City string } {",
+		"func User_ContactsSliceToProto(orig []struct{ Email string }) []*pb.User_Contacts {",
+		"func SettingsToProto(orig nested.Settings) *pb.User_Settings {",
+		"func LayoutFromProto(proto *pb.User_Settings_Layout) nested.Layout {",
+		"Address: User_AddressToProto(orig.Address),",
+		"Contacts: User_ContactsSliceFromProto(proto.Contacts),",
+		"Settings: SettingsToProto(orig.Settings),",
+		"Layout: LayoutFromProto(proto.Layout),",
+	}
+	for _, expected := range expected {
+		if !strings.Contains(types, expected) {
+			t.Errorf("Expected to find: %s\nIn types.go:\n%s", expected, types)
+		}
+	}
+}
+
+// TestNestedInMissingParent verifies that a nested_in parent that isn't a message fails the
+// generation
+func TestNestedInMissingParent(t *testing.T) {
+	_, err := generateProto(t, nestedConfig(), filepath.Join("testdata", "nested", "orphan", "orphan.go"))
+	if err == nil {
+		t.Fatal("Expected the missing nested_in parent to fail the generation")
+	}
+	if !strings.Contains(err.Error(), "1 error(s)") {
+		t.Errorf("Expected the missing parent to be reported, got: %v", err)
+	}
+}
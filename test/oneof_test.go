@@ -0,0 +1,56 @@
+package main_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pablor21/protoschemagen/plugin"
+)
+
+// TestOneofMultipleMembers verifies that when several members of a oneof are set, the adapters
+// convert the first one in declaration order, and that the generated doc comments say so
+func TestOneofMultipleMembers(t *testing.T) {
+	stubs, err := generateStubs(t, &plugin.Config{
+		Package:          "acme.v1",
+		Syntax:           "proto3",
+		AutoNumberFields: true,
+		StartFieldNumber: 1,
+		Options:          map[string]string{"go_package": "github.com/acme/contacts/v1"},
+	}, filepath.Join("testdata", "oneof", "contact.go"))
+	if err != nil {
+		t.Fatalf("Failed to generate stubs: %v", err)
+	}
+	types := stubs["types.go"]
+
+	expected := []string{
+		"// When several members of the channel oneof are set, only the first one in declaration order\n// is converted, see Contact_ChannelToProto\nfunc ContactToProto(",
+		"Contact_ChannelToProto(proto, orig)",
+		"Contact_ChannelFromProto(proto, &orig)",
+		"case *pb.Contact_Phone:\n\t\torig.Phone = func() *string { v := proto.GetPhone(); return &v }()",
+	}
+	for _, expected := range expected {
+		if !strings.Contains(types, expected) {
+			t.Errorf("Expected to find: %s\nIn types.go:\n%s", expected, types)
+		}
+	}
+
+	// The cases are checked in declaration order, so the first member set wins
+	cases := []string{
+		"case orig.Email != \"\":\n\t\tproto.Channel = &pb.Contact_Email{Email: orig.Email}",
+		"case orig.Phone != nil:\n\t\tproto.Channel = &pb.Contact_Phone{Phone: *orig.Phone}",
+		"case orig.Pager != 0:\n\t\tproto.Channel = &pb.Contact_Pager{Pager: orig.Pager}",
+	}
+	last := -1
+	for _, c := range cases {
+		index := strings.Index(types, c)
+		if index < 0 {
+			t.Errorf("Expected to find: %s\nIn types.go:\n%s", c, types)
+			continue
+		}
+		if index < last {
+			t.Errorf("Expected the case %q to come after the members declared before it", c)
+		}
+		last = index
+	}
+}
@@ -0,0 +1,117 @@
+package main_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pablor21/protoschemagen/plugin"
+)
+
+// TestOptionalFields verifies that pointer fields keep nil as unset, written as proto3 optional
+// fields or as well-known wrappers with optional_style: wrappers, and that messages and the types
+// converted through helpers of their own keep their representation in both styles
+func TestOptionalFields(t *testing.T) {
+	tests := []struct {
+		name       string
+		style      string
+		schema     []string
+		types      []string
+		unexpected []string
+	}{
+		{
+			name:  "optional",
+			style: "",
+			schema: []string{
+				"string name = 1;",
+				"optional string nickname = 2;",
+				"optional int32 age = 3;",
+				"optional double score = 4;",
+				"optional Tier tier = 5;",
+				"Address home = 6;",
+				"optional string website = 7;",
+				"optional string last_seen = 8;",
+			},
+			types: []string{
+				"Nickname: orig.Nickname,",
+				"Score: orig.Score,",
+				"Age: ConvertPointerToProto_int(orig.Age),",
+				"Age: ConvertPointerFromProto_int(proto.Age),",
+				"Tier: ConvertPointerToProto_Tier(orig.Tier),",
+				"Home: ConvertPointerToProto_Address(orig.Home),",
+				"Home: ConvertPointerFromProto_Address(proto.Home),",
+				"func ConvertPointerToProto_int(orig *int) *int32 {\n\tif orig == nil {\n\t\treturn nil\n\t}",
+				"func ConvertPointerFromProto_Tier(proto *pb.Tier) *profile.Tier {\n\tif proto == nil {\n\t\treturn nil\n\t}",
+			},
+			unexpected: []string{"google/protobuf/wrappers.proto", "wrapperspb"},
+		},
+		{
+			name:  "wrappers",
+			style: "wrappers",
+			schema: []string{
+				`import "google/protobuf/wrappers.proto";`,
+				"google.protobuf.StringValue nickname = 2;",
+				"google.protobuf.Int32Value age = 3;",
+				"google.protobuf.DoubleValue score = 4;",
+				"optional Tier tier = 5;",
+				"Address home = 6;",
+				"optional string website = 7;",
+				"optional string last_seen = 8;",
+			},
+			types: []string{
+				`"google.golang.org/protobuf/types/known/wrapperspb"`,
+				"Nickname: ConvertPointerToProto_string(orig.Nickname),",
+				"Nickname: ConvertPointerFromProto_string(proto.Nickname),",
+				"Score: ConvertPointerToProto_float64(orig.Score),",
+				"return wrapperspb.Int32(int32(*orig))",
+				"result := int(proto.GetValue())",
+				"Website: builtinOptionalToProto(orig.Website, url_URLValueToProto),",
+				"LastSeen: builtinOptionalToProto(orig.LastSeen, time_TimeToProto),",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preserve := false
+			config := &plugin.Config{
+				Package:          "acme.v1",
+				Syntax:           "proto3",
+				AutoNumberFields: true,
+				StartFieldNumber: 1,
+				OptionalStyle:    tt.style,
+				Options:          map[string]string{"go_package": "github.com/acme/profile/v1"},
+				GenerateStubs: &plugin.StubConfig{
+					Enabled:      true,
+					TypeMappings: plugin.TypeMappingConfig{PreserveTimeTypes: &preserve},
+				},
+			}
+			file := filepath.Join("testdata", "optional", "profile.go")
+
+			stubs, err := generateStubs(t, config, file)
+			if err != nil {
+				t.Fatalf("Failed to generate stubs: %v", err)
+			}
+			schema, err := generateProto(t, config, file)
+			if err != nil {
+				t.Fatalf("Failed to generate protobuf schema: %v", err)
+			}
+			for _, expected := range tt.schema {
+				if !strings.Contains(schema, expected) {
+					t.Errorf("Expected to find: %s\nIn schema:\n%s", expected, schema)
+				}
+			}
+			types := stubs["types.go"]
+			for _, expected := range tt.types {
+				if !strings.Contains(types, expected) {
+					t.Errorf("Expected to find: %s\nIn types.go:\n%s", expected, types)
+				}
+			}
+			for _, unexpected := range tt.unexpected {
+				if strings.Contains(schema+types, unexpected) {
+					t.Errorf("Expected not to find: %s\nIn schema:\n%s\nIn types.go:\n%s", unexpected, schema, types)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package main_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pablor21/protoschemagen/plugin"
+)
+
+func packagesConfig() *plugin.Config {
+	return &plugin.Config{
+		Package:          "acme.v1",
+		Syntax:           "proto3",
+		AutoNumberFields: true,
+		StartFieldNumber: 1,
+		GenerateService:  true,
+		Options:          map[string]string{"go_package": "github.com/acme/users/v1"},
+	}
+}
+
+func packagesFiles() []string {
+	return []string{
+		filepath.Join("testdata", "services", "user", "user.go"),
+		filepath.Join("testdata", "services", "billing", "billing.go"),
+	}
+}
+
+// TestPackageQualifiedTypes verifies that the types of another Go package written to the same
+// proto file are referenced by their message name
+func TestPackageQualifiedTypes(t *testing.T) {
+	schema, err := generateProto(t, packagesConfig(), packagesFiles()...)
+	if err != nil {
+		t.Fatalf("Failed to generate protobuf schema: %v", err)
+	}
+
+	expected := []string{
+		"message Invoice {",
+		"repeated Invoice invoices = 2;",
+		"rpc GetUser(User) returns (User);",
+		"rpc LastInvoice(User) returns (Invoice);",
+	}
+	for _, expected := range expected {
+		if !strings.Contains(schema, expected) {
+			t.Errorf("Expected to find: %s\nIn schema:\n%s", expected, schema)
+		}
+	}
+	if strings.Contains(schema, "billing.Invoice") {
+		t.Errorf("Expected not to find: billing.Invoice\nIn schema:\n%s", schema)
+	}
+}
+
+// TestPackageQualifiedStubs verifies that the stubs convert the types of each Go package through
+// their own adapters and import the packages their signatures use
+func TestPackageQualifiedStubs(t *testing.T) {
+	config := packagesConfig()
+	config.GenerateStubs = &plugin.StubConfig{
+		Enabled:                  true,
+		OriginalServiceInterface: true,
+		RegistrationHelpers:      true,
+	}
+	stubs, err := generateStubs(t, config, packagesFiles()...)
+	if err != nil {
+		t.Fatalf("Failed to generate stubs: %v", err)
+	}
+
+	expected := map[string][]string{
+		"adapter.go": {
+			"LastInvoice(ctx context.Context, req *pb.User) (*pb.Invoice, error)",
+			"return InvoiceToProto(result), nil",
+		},
+		"service.go": {
+			`"billing"`,
+			"LastInvoice(ctx context.Context, req user.User) (billing.Invoice, error)",
+		},
+		"bridge.go": {
+			`"billing"`,
+			"LastInvoice(ctx context.Context, req user.User) (billing.Invoice, error)",
+		},
+		"client.go": {
+			`"billing"`,
+			"LastInvoice(ctx context.Context, req user.User) (billing.Invoice, error)",
+			"InvoiceFromProto(protoResp)",
+		},
+		"types.go": {
+			"func InvoiceToProto(orig billing.Invoice) *pb.Invoice",
+			"Invoices: InvoiceSliceToProto(orig.Invoices),",
+			"Invoices: InvoiceSliceFromProto(proto.Invoices),",
+		},
+	}
+	for path, expected := range expected {
+		content, ok := stubs[path]
+		if !ok {
+			t.Fatalf("Expected a %s file, got %v", path, sortedPaths(stubs))
+		}
+		for _, expected := range expected {
+			if !strings.Contains(content, expected) {
+				t.Errorf("Expected to find: %s\nIn %s:\n%s", expected, path, content)
+			}
+		}
+	}
+
+	// The adapter only converts protobuf messages, it never names a billing type
+	for _, unexpected := range []string{`"billing"`, "pb.billing", "billing.InvoiceToProto"} {
+		if strings.Contains(stubs["adapter.go"], unexpected) {
+			t.Errorf("Expected not to find: %s\nIn adapter.go:\n%s", unexpected, stubs["adapter.go"])
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package main_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pablor21/protoschemagen/plugin"
+)
+
+func proto2Config(syntax string) *plugin.Config {
+	return &plugin.Config{
+		Package:          "acme.v1",
+		Syntax:           syntax,
+		AutoNumberFields: true,
+		StartFieldNumber: 1,
+		Options:          map[string]string{"go_package": "github.com/acme/orders/v1"},
+	}
+}
+
+// TestProto2Schema verifies the labels, typed defaults, extension ranges and struct-level
+// extensions of proto2 schemas
+func TestProto2Schema(t *testing.T) {
+	schema, err := generateProto(t, proto2Config("proto2"), filepath.Join("testdata", "proto2", "order.go"))
+	if err != nil {
+		t.Fatalf("Failed to generate protobuf schema: %v", err)
+	}
+
+	expected := []string{
+		`syntax = "proto2";`,
+		// @field(required=true) and @validate(required=true)
+		"required int64 id = 1;",
+		"required string customer = 6;",
+		// Defaults: quoted strings, enum values by their Go constant, floats
+		`optional string state = 2 [default = "pending"];`,
+		"optional Priority priority = 3 [default = PRIORITY_HIGH];",
+		"optional double limit = 4 [default = -inf];",
+		"optional float ratio = 5 [default = 2.5];",
+		"optional string note = 7;",
+		"optional bytes blob = 8;",
+		// N-M and N to max ranges
+		"extensions 100 to 199, 1000 to max;",
+		// Extensions are numbered from the first range, skipping explicit numbers
+		"extend Order {",
+		"optional string created_by = 101;",
+		"optional string source = 100;",
+	}
+	for _, expected := range expected {
+		if !strings.Contains(schema, expected) {
+			t.Errorf("Expected to find: %s\nIn schema:\n%s", expected, schema)
+		}
+	}
+}
+
+// TestRequiredLabels verifies that required fields only get the required label in proto2
+func TestRequiredLabels(t *testing.T) {
+	file := filepath.Join("testdata", "proto2", "customer", "customer.go")
+
+	schema, err := generateProto(t, proto2Config("proto2"), file)
+	if err != nil {
+		t.Fatalf("Failed to generate proto2 schema: %v", err)
+	}
+	for _, expected := range []string{"required int64 id = 1;", "required string email = 2;", "optional string name = 3;"} {
+		if !strings.Contains(schema, expected) {
+			t.Errorf("Expected to find: %s\nIn schema:\n%s", expected, schema)
+		}
+	}
+
+	schema, err = generateProto(t, proto2Config("proto3"), file)
+	if err != nil {
+		t.Fatalf("Failed to generate proto3 schema: %v", err)
+	}
+	for _, unexpected := range []string{"required int64", "required string"} {
+		if strings.Contains(schema, unexpected) {
+			t.Errorf("Expected not to find: %s\nIn proto3 schema:\n%s", unexpected, schema)
+		}
+	}
+}
+
+// TestProto2DefaultErrors verifies that defaults that don't fit the field type fail the generation
+func TestProto2DefaultErrors(t *testing.T) {
+	dir := t.TempDir()
+	source := `package defaults
+
+// @proto.enum
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+)
+
+// @proto.message
+type Order struct {
+	// @proto.field(default=%s)
+	Value %s
+}
+`
+	tests := []struct {
+		name     string
+		value    string
+		goType   string
+		expected bool
+	}{
+		{"int", "42", "int32", true},
+		{"hex int", "0x10", "int64", true},
+		{"int out of range", "3000000000", "int32", false},
+		{"invalid float", "fast", "float64", false},
+		{"nan", "nan", "float32", true},
+		{"bool", "maybe", "bool", false},
+		{"unknown enum value", "PriorityUrgent", "Priority", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(dir, strings.ReplaceAll(tt.name, " ", "_")+".go")
+			if err := os.WriteFile(file, []byte(fmt.Sprintf(source, tt.value, tt.goType)), 0644); err != nil {
+				t.Fatalf("Failed to write %s: %v", file, err)
+			}
+
+			_, err := generateProto(t, proto2Config("proto2"), file)
+			if (err == nil) != tt.expected {
+				t.Errorf("Expected default %s for %s to be valid: %v, got: %v", tt.value, tt.goType, tt.expected, err)
+			}
+		})
+	}
+}
+
+// TestProto2ExtensionValidation verifies that field numbers can't overlap extension ranges and
+// that extensions must be numbered inside them
+func TestProto2ExtensionValidation(t *testing.T) {
+	_, err := generateProto(t, proto2Config("proto2"), filepath.Join("testdata", "proto2", "overlap", "overlap.go"))
+	if err == nil {
+		t.Fatal("Expected the overlapping field and extension numbers to fail the generation")
+	}
+	if !strings.Contains(err.Error(), "2 error(s)") {
+		t.Errorf("Expected the field and the extension to be reported, got: %v", err)
+	}
+}
+
+// TestProto2StubPresence verifies that the adapters set proto2 value fields through a pointer
+// and read them through their getter
+func TestProto2StubPresence(t *testing.T) {
+	stubs, err := generateStubs(t, proto2Config("proto2"), filepath.Join("testdata", "proto2", "order.go"))
+	if err != nil {
+		t.Fatalf("Failed to generate stubs: %v", err)
+	}
+
+	expected := []string{
+		"Id: presentValue(orig.ID),",
+		"State: presentValue(orig.State),",
+		"Priority: presentValue(PriorityToProto(orig.Priority)),",
+		"Note: orig.Note,",
+		"Blob: orig.Blob,",
+		"ID: proto.GetId(),",
+		"Priority: PriorityFromProto(proto.GetPriority()),",
+		"Note: proto.Note,",
+	}
+	for _, expected := range expected {
+		if !strings.Contains(stubs["types.go"], expected) {
+			t.Errorf("Expected to find: %s\nIn types.go:\n%s", expected, stubs["types.go"])
+		}
+	}
+}
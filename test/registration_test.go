@@ -0,0 +1,57 @@
+package main_test
+
+import (
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pablor21/protoschemagen/plugin"
+)
+
+// TestRegistrationHelpers verifies that each service is registered with the server through its
+// adapter, and that RegisterAll registers the interface and struct services it's given
+func TestRegistrationHelpers(t *testing.T) {
+	stubs, err := generateStubs(t, &plugin.Config{
+		Package:          "acme.v1",
+		Syntax:           "proto3",
+		AutoNumberFields: true,
+		StartFieldNumber: 1,
+		GenerateService:  true,
+		Options:          map[string]string{"go_package": "github.com/acme/registry/v1"},
+		GenerateStubs: &plugin.StubConfig{
+			Enabled:                  true,
+			OriginalServiceInterface: true,
+			RegistrationHelpers:      true,
+		},
+	}, filepath.Join("testdata", "registry", "registry.go"))
+	if err != nil {
+		t.Fatalf("Failed to generate stubs: %v", err)
+	}
+	registration, ok := stubs["registration.go"]
+	if !ok {
+		t.Fatalf("Expected a registration.go file, got %v", sortedPaths(stubs))
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "registration.go", registration, 0); err != nil {
+		t.Fatalf("Generated registration.go doesn't parse: %v\n%s", err, registration)
+	}
+
+	expected := []string{
+		"func RegisterGreeterService(server grpc.ServiceRegistrar, service registry.GreeterService) {\n\tpb.RegisterGreeterServiceServer(server, NewGreeterServiceAdapter(service))",
+		"func RegisterEchoService(server grpc.ServiceRegistrar, service registry.EchoService) {\n\tpb.RegisterEchoServiceServer(server, NewEchoServiceAdapter(service))",
+		"GreeterService registry.GreeterService",
+		"EchoService *registry.EchoService",
+		"func RegisterAll(server grpc.ServiceRegistrar, impls Services) {",
+		"if impls.GreeterService != nil {\n\t\tRegisterGreeterService(server, impls.GreeterService)",
+		"if impls.EchoService != nil {\n\t\tRegisterEchoService(server, *impls.EchoService)",
+	}
+	for _, expected := range expected {
+		if !strings.Contains(registration, expected) {
+			t.Errorf("Expected to find: %s\nIn registration.go:\n%s", expected, registration)
+		}
+	}
+	if strings.Contains(registration, "_ = adapter") {
+		t.Errorf("Expected the adapters to be registered\nIn registration.go:\n%s", registration)
+	}
+}
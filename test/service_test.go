@@ -0,0 +1,122 @@
+package main_test
+
+import (
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pablor21/protoschemagen/plugin"
+)
+
+// chatStubs generates the stubs of the chat service, one method for each streaming mode
+func chatStubs(t *testing.T) map[string]string {
+	t.Helper()
+
+	stubs, err := generateStubs(t, &plugin.Config{
+		Package:          "acme.chat.v1",
+		Syntax:           "proto3",
+		AutoNumberFields: true,
+		StartFieldNumber: 1,
+		GenerateService:  true,
+		Options:          map[string]string{"go_package": "github.com/acme/chat/v1"},
+		GenerateStubs: &plugin.StubConfig{
+			Enabled:                  true,
+			OriginalServiceInterface: true,
+			StreamingSupport:         true,
+			RegistrationHelpers:      true,
+		},
+	}, filepath.Join("testdata", "chat", "chat.go"))
+	if err != nil {
+		t.Fatalf("Failed to generate stubs: %v", err)
+	}
+	for name, content := range stubs {
+		if _, err := parser.ParseFile(token.NewFileSet(), name, content, 0); err != nil {
+			t.Fatalf("Generated %s doesn't parse: %v\n%s", name, err, content)
+		}
+	}
+	return stubs
+}
+
+// TestServiceInterfaceSignatures verifies that the service interface declares each method with
+// the signature of the original one, whatever its streaming mode
+func TestServiceInterfaceSignatures(t *testing.T) {
+	service := chatStubs(t)["service.go"]
+
+	expected := []string{
+		"Post(ctx context.Context, req chat.Message) (chat.Message, error)",
+		"Subscribe(ctx context.Context, req chat.Message) (<-chan chat.Message, error)",
+		"Replay(req chat.Message) (iter.Seq[chat.Message], error)",
+		"Feed(ctx context.Context, req chat.Message, outputs chan<- chat.Message) error",
+		"Watch(ctx context.Context, req chat.Message, outputs func(chat.Message) error) error",
+		"Upload(ctx context.Context, inputs <-chan chat.Message) (chat.Message, error)",
+		"Collect(inputs iter.Seq[chat.Message]) (chat.Message, error)",
+		"Chat(ctx context.Context, inputs <-chan chat.Message) (<-chan chat.Message, error)",
+		`"context"`,
+		`"iter"`,
+	}
+	for _, expected := range expected {
+		if !strings.Contains(service, expected) {
+			t.Errorf("Expected to find: %s\nIn service.go:\n%s", expected, service)
+		}
+	}
+}
+
+// TestAdapterStreamLifetimes verifies that a failed server stream drains the responses of the
+// service for a bounded time, and that the client stream stops being received once the handler
+// returned
+func TestAdapterStreamLifetimes(t *testing.T) {
+	adapter := chatStubs(t)["adapter.go"]
+
+	expected := []string{
+		// Send errors drain the channel the service keeps sending on
+		"if err := stream.Send(MessageToProto(item)); err != nil {\n\t\t\tdrainResponses(stream.Context(), outputs)\n\t\t\treturn err",
+		"var ResponseDrainTimeout = 30 * time.Second",
+		"func drainResponses[T any](ctx context.Context, outputs <-chan T) {",
+		"timeout = time.After(ResponseDrainTimeout)",
+		// Returning early stops the receiving goroutine
+		"stopRecv := make(chan struct{})\n\tdefer close(stopRecv)",
+		"case inputs <- MessageFromProto(req):\n\t\t\tcase <-stopRecv:\n\t\t\t\treturn",
+		"ctx, cancel := context.WithCancel(stream.Context())\n\tdefer cancel()",
+	}
+	for _, expected := range expected {
+		if !strings.Contains(adapter, expected) {
+			t.Errorf("Expected to find: %s\nIn adapter.go:\n%s", expected, adapter)
+		}
+	}
+
+	// Subscribe, Feed and Chat send a channel of responses, Replay and Watch don't need draining
+	if got := strings.Count(adapter, "drainResponses(stream.Context(), outputs)"); got != 3 {
+		t.Errorf("Expected 3 drained server streams, got %d", got)
+	}
+	// Upload and Chat receive a channel of requests, Collect receives them as the service iterates
+	if got := strings.Count(adapter, "defer close(stopRecv)"); got != 2 {
+		t.Errorf("Expected 2 client streams stopped on return, got %d", got)
+	}
+}
+
+// TestAdapterWithoutDrainedStreams verifies that the drain helper is only written when a server
+// stream sends a channel of responses
+func TestAdapterWithoutDrainedStreams(t *testing.T) {
+	stubs, err := generateStubs(t, &plugin.Config{
+		Package:          "acme.store.v1",
+		Syntax:           "proto3",
+		AutoNumberFields: true,
+		StartFieldNumber: 1,
+		GenerateService:  true,
+		Options:          map[string]string{"go_package": "github.com/acme/store/v1"},
+	}, filepath.Join("testdata", "descriptor", "store.go"))
+	if err != nil {
+		t.Fatalf("Failed to generate stubs: %v", err)
+	}
+	adapter, ok := stubs["adapter.go"]
+	if !ok {
+		t.Fatalf("Expected an adapter.go file, got %v", sortedPaths(stubs))
+	}
+	for _, unexpected := range []string{"drainResponses", "ResponseDrainTimeout", `"time"`} {
+		if strings.Contains(adapter, unexpected) {
+			t.Errorf("Expected not to find: %s\nIn adapter.go:\n%s", unexpected, adapter)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package chat
+
+import (
+	"context"
+	"iter"
+)
+
+// @proto.message
+type Message struct {
+	Text string
+}
+
+// @proto.service
+type ChatService interface {
+	Post(ctx context.Context, msg Message) (Message, error)
+	Subscribe(ctx context.Context, req Message) (<-chan Message, error)
+	Replay(req Message) (iter.Seq[Message], error)
+	Feed(ctx context.Context, req Message, outputs chan<- Message) error
+	Watch(ctx context.Context, req Message, send func(Message) error) error
+	Upload(ctx context.Context, inputs <-chan Message) (Message, error)
+	Collect(inputs iter.Seq[Message]) (Message, error)
+	Chat(ctx context.Context, inputs <-chan Message) (<-chan Message, error)
+}
@@ -0,0 +1,14 @@
+package levels
+
+// @proto.enum
+type Level int
+
+const (
+	LevelLow Level = iota
+	LevelHigh
+)
+
+// @proto.message
+type Thresholds struct {
+	Levels [][]*Level
+}
@@ -0,0 +1,7 @@
+package collections
+
+type Matrix struct {
+	Rows [][]float64
+	Tags map[string][]string
+	Grid map[string][][]int32
+}
@@ -0,0 +1,38 @@
+package store
+
+import "context"
+
+// @proto.enum
+type Status int
+
+const (
+	StatusUnspecified Status = iota
+	StatusOpen
+	StatusClosed
+)
+
+// @proto.message
+type Item struct {
+	Sku      string
+	Quantity int32
+}
+
+// @proto.message
+type Order struct {
+	ID     int64
+	Status Status
+	Items  []Item
+	Notes  map[string]string
+	Total  float64
+}
+
+// @proto.message
+type GetOrderRequest struct {
+	ID int64
+}
+
+// @proto.service
+type OrderService interface {
+	GetOrder(ctx context.Context, req GetOrderRequest) (Order, error)
+	PlaceOrder(ctx context.Context, order Order) (Order, error)
+}
@@ -0,0 +1,20 @@
+package editions
+
+// @proto.enum
+type Plan int
+
+const (
+	PlanFree Plan = iota
+	PlanPro
+)
+
+// @proto.message
+type Account struct {
+	// @proto.field(legacy_required=true)
+	ID       int64
+	Name     string
+	Nickname *string
+	Plan     Plan
+	Avatar   []byte
+	Tags     []string
+}
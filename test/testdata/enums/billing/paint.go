@@ -0,0 +1,9 @@
+package billing
+
+// @proto.enum
+type Paint int
+
+const (
+	Red Paint = iota
+	Blue
+)
@@ -0,0 +1,10 @@
+package alias
+
+// @proto.enum
+type Level int
+
+const (
+	LevelLow Level = iota
+	// @proto.enumvalue(number=0)
+	LevelNone
+)
@@ -0,0 +1,11 @@
+package conflict
+
+// @proto.enum
+// @proto.reserved(numbers="1", names="LEVEL_HIGH")
+type Level int
+
+const (
+	LevelLow Level = iota
+	LevelMedium
+	LevelHigh
+)
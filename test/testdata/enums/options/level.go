@@ -0,0 +1,25 @@
+package options
+
+// @proto.enum(allow_alias=true)
+// @proto.reserved(numbers="5, 8 to 9", names="LEVEL_LEGACY")
+// @proto.option(name="(acme.enum_label)", value="severity")
+type Level int
+
+const (
+	LevelLow Level = iota
+	LevelMedium
+	// @proto.enumvalue(deprecated=true)
+	LevelHigh
+	// @proto.enumvalue(number=2)
+	LevelSevere
+)
+
+// @proto.enum(allow_alias=true, deprecated=true)
+type Mode int
+
+const (
+	ModeOff Mode = iota
+	// @proto.deprecated
+	// @proto.option(name="(acme.value_label)", value="on")
+	ModeOn
+)
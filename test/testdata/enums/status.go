@@ -0,0 +1,18 @@
+package enums
+
+// @proto.enum
+type Status int
+
+const (
+	StatusUnspecified Status = iota
+	StatusActive
+	StatusInactive
+)
+
+// @proto.enum
+type Color int
+
+const (
+	Red Color = iota
+	Green
+)
@@ -0,0 +1,9 @@
+package billing
+
+type Address struct {
+	Iban string
+}
+
+type Invoice struct {
+	Number string
+}
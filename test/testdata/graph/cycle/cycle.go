@@ -0,0 +1,11 @@
+package cycle
+
+// @namespace("orders")
+type Order struct {
+	Customer *Customer
+}
+
+// @namespace("customers")
+type Customer struct {
+	LastOrder *Order
+}
@@ -0,0 +1,12 @@
+package shop
+
+// @namespace("customers")
+type Customer struct {
+	Name string
+}
+
+// @namespace("orders")
+type Order struct {
+	Customer Customer
+	Total    int64
+}
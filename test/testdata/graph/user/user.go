@@ -0,0 +1,12 @@
+package user
+
+import "github.com/pablor21/protoschemagen/test/testdata/graph/billing"
+
+type Address struct {
+	Street string
+}
+
+type Customer struct {
+	Home    Address
+	Billing billing.Address
+}
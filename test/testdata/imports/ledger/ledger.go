@@ -0,0 +1,28 @@
+package ledger
+
+import (
+	"context"
+	"time"
+
+	"github.com/pablor21/protoschemagen/test/testdata/imports/money"
+)
+
+type Entry struct {
+	ID       string
+	PostedAt time.Time
+	Window   time.Duration
+	Note     *string
+	Details  any
+	Amount   money.Amount
+	Rate     money.Decimal
+}
+
+type Tag struct {
+	Name string
+}
+
+// @proto.service
+type LedgerService interface {
+	Post(ctx context.Context, entry Entry) error
+	Count(ctx context.Context, tag Tag) (int64, error)
+}
@@ -0,0 +1,12 @@
+package money
+
+// Amount is mapped to a protobuf type with type_mappings
+type Amount struct {
+	Units    int64
+	Currency string
+}
+
+// Decimal is mapped to a protobuf type as well
+type Decimal struct {
+	Value string
+}
@@ -0,0 +1,10 @@
+package pricing
+
+import "github.com/pablor21/protoschemagen/test/testdata/imports/money"
+
+// @proto.message
+type Price struct {
+	Rate    money.Decimal
+	Floor   *money.Decimal
+	History []money.Decimal
+}
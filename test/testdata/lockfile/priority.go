@@ -0,0 +1,11 @@
+package lockfile
+
+// @proto.enum
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+
+	// @proto.enumvalue(number=2)
+	PriorityUrgent
+)
@@ -0,0 +1,22 @@
+package warehouse
+
+// @proto.enum
+type Grade int
+
+const (
+	GradeA Grade = iota
+	GradeB
+)
+
+// @proto.message
+type Item struct {
+	Sku string
+}
+
+// @proto.message
+type Stock struct {
+	Counts map[string]int32
+	Items  map[string]Item
+	Refs   map[string]*Item
+	Grades map[int64]Grade
+}
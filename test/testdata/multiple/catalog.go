@@ -0,0 +1,25 @@
+package catalog
+
+// @proto.enum
+type ProductStatus int
+
+const (
+	ProductStatusDraft ProductStatus = iota
+	ProductStatusPublished
+)
+
+type Category struct {
+	Name string
+}
+
+type Product struct {
+	Name     string
+	Category Category
+	Status   ProductStatus
+	Variants []Variant
+}
+
+// @message(nested_in="Product")
+type Variant struct {
+	Sku string
+}
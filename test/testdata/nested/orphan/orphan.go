@@ -0,0 +1,6 @@
+package orphan
+
+// @proto.message(nested_in="Missing")
+type Orphan struct {
+	Name string
+}
@@ -0,0 +1,25 @@
+package nested
+
+// @proto.message
+type User struct {
+	Name    string
+	Address struct {
+		Street string
+		City   string
+	}
+	Contacts []struct {
+		Email string
+	}
+	Settings Settings
+}
+
+// @proto.message(nested_in="User")
+type Settings struct {
+	Theme  string
+	Layout Layout
+}
+
+// @proto.message(nested_in="Settings")
+type Layout struct {
+	Columns int32
+}
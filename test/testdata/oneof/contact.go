@@ -0,0 +1,12 @@
+package oneof
+
+// @proto.message
+type Contact struct {
+	Name string
+	// @proto.oneof(group="channel")
+	Email string
+	// @proto.oneof(group="channel")
+	Phone *string
+	// @proto.oneof(group="channel")
+	Pager int32
+}
@@ -0,0 +1,31 @@
+package profile
+
+import (
+	"net/url"
+	"time"
+)
+
+// @proto.enum
+type Tier int
+
+const (
+	TierFree Tier = iota
+	TierPaid
+)
+
+// @proto.message
+type Address struct {
+	City string
+}
+
+// @proto.message
+type Profile struct {
+	Name     string
+	Nickname *string
+	Age      *int
+	Score    *float64
+	Tier     *Tier
+	Home     *Address
+	Website  *url.URL
+	LastSeen *time.Time
+}
@@ -0,0 +1,10 @@
+package customer
+
+// @proto.message
+type Customer struct {
+	// @proto.field(required=true)
+	ID int64
+	// @validate(required=true)
+	Email string
+	Name  string
+}
@@ -0,0 +1,35 @@
+package proto2
+
+// @proto.enum
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityHigh
+)
+
+// @proto.message
+// @proto.extensions(ranges="100-199, 1000 to max")
+type Order struct {
+	// @proto.field(required=true)
+	ID int64
+	// @proto.field(default="pending")
+	State string
+	// @proto.field(default=PriorityHigh)
+	Priority Priority
+	// @proto.field(default="-inf")
+	Limit float64
+	// @proto.field(default=2.5)
+	Ratio float32
+	// @validate(required=true)
+	Customer string
+	Note     *string
+	Blob     []byte
+}
+
+// @proto.extend(message="Order")
+type OrderAudit struct {
+	CreatedBy string
+	// @proto.field(number=100)
+	Source string
+}
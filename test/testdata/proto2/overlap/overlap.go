@@ -0,0 +1,14 @@
+package overlap
+
+// @proto.message
+// @proto.extensions(from=100, to=199)
+type Order struct {
+	// @proto.field(number=150)
+	ID int64
+}
+
+// @proto.extend(message="Order")
+type OrderAudit struct {
+	// @proto.field(number=50)
+	CreatedBy string
+}
@@ -0,0 +1,21 @@
+package registry
+
+import "context"
+
+// @proto.message
+type Greeting struct {
+	Text string
+}
+
+// @proto.service
+type GreeterService interface {
+	Greet(ctx context.Context, req Greeting) (Greeting, error)
+}
+
+// @proto.service
+type EchoService struct{}
+
+// @proto.rpc
+func (s *EchoService) Echo(ctx context.Context, req Greeting) (Greeting, error) {
+	return req, nil
+}
@@ -0,0 +1,7 @@
+package billing
+
+// @proto.message
+type Invoice struct {
+	Number string
+	Total  int64
+}
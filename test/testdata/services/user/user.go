@@ -0,0 +1,19 @@
+package user
+
+import (
+	"context"
+
+	"github.com/pablor21/protoschemagen/test/testdata/services/billing"
+)
+
+// @proto.message
+type User struct {
+	Name     string
+	Invoices []billing.Invoice
+}
+
+// @proto.service
+type UserService interface {
+	GetUser(ctx context.Context, req User) (User, error)
+	LastInvoice(ctx context.Context, req User) (billing.Invoice, error)
+}
@@ -0,0 +1,12 @@
+package session
+
+import "time"
+
+// @proto.message
+type Session struct {
+	CreatedAt time.Time
+	UpdatedAt *time.Time
+	Logins    []time.Time
+	Timeout   time.Duration
+	Expiries  map[string]time.Time
+}
@@ -0,0 +1,8 @@
+package types
+
+type Blob struct {
+	Data   []byte
+	Raw    []uint8
+	Digest [16]uint8
+	Deltas []int8
+}
@@ -0,0 +1,26 @@
+package ids
+
+import "time"
+
+type UserID string
+
+type Score int64
+
+type Stamp = time.Time
+
+type Status string
+
+const (
+	StatusActive    Status = "active"
+	StatusSuspended Status = "suspended"
+	StatusDisabled  Status = "suspended" // Older name of StatusSuspended
+)
+
+// @proto.message
+type Account struct {
+	ID        UserID
+	Friends   []UserID
+	Score     Score
+	Status    Status
+	CreatedAt Stamp
+}
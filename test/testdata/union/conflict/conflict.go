@@ -0,0 +1,18 @@
+package conflict
+
+// @proto.message
+type Circle struct {
+	Radius float64
+}
+
+// @proto.union(variants="Circle")
+type Shape interface{}
+
+// @proto.union(variants="Circle")
+type Marker interface{}
+
+// @proto.message
+type Drawing struct {
+	Shape  Shape
+	Marker Marker
+}
@@ -0,0 +1,18 @@
+package repeated
+
+// @proto.union
+type Shape interface {
+	isShape()
+}
+
+// @proto.message
+type Circle struct {
+	Radius float64
+}
+
+func (Circle) isShape() {}
+
+// @proto.message
+type Drawing struct {
+	Shapes []Shape
+}
@@ -0,0 +1,42 @@
+package union
+
+// @proto.union
+type Shape interface {
+	isShape()
+}
+
+// @proto.message
+type Circle struct {
+	Radius float64
+}
+
+func (Circle) isShape() {}
+
+// @proto.message
+type Square struct {
+	Side float64
+}
+
+func (*Square) isShape() {}
+
+// Point doesn't implement Shape
+// @proto.message
+type Point struct {
+	X float64
+	Y float64
+}
+
+// @proto.message
+type Label struct {
+	Text string
+}
+
+// @proto.union(variants="Point,Label")
+type Marker interface{}
+
+// @proto.message
+type Drawing struct {
+	Title  string
+	Shape  Shape
+	Marker Marker
+}
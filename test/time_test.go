@@ -0,0 +1,116 @@
+package main_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pablor21/protoschemagen/plugin"
+)
+
+// TestTimeTypes verifies that time.Time and time.Duration fields, their pointers, slices and map
+// values are written as the well-known types, or as strings and nanoseconds with
+// preserve_time_types: false, and converted both ways by the adapters
+func TestTimeTypes(t *testing.T) {
+	tests := []struct {
+		name       string
+		preserve   bool
+		schema     []string
+		types      []string
+		unexpected []string
+	}{
+		{
+			name:     "well-known types",
+			preserve: true,
+			schema: []string{
+				`import "google/protobuf/duration.proto";`,
+				`import "google/protobuf/timestamp.proto";`,
+				"google.protobuf.Timestamp created_at = 1;",
+				"google.protobuf.Timestamp updated_at = 2;",
+				"repeated google.protobuf.Timestamp logins = 3;",
+				"google.protobuf.Duration timeout = 4;",
+				"map<string, google.protobuf.Timestamp> expiries = 5;",
+			},
+			types: []string{
+				`"google.golang.org/protobuf/types/known/durationpb"`,
+				`"google.golang.org/protobuf/types/known/timestamppb"`,
+				"CreatedAt: time_TimeToProto(orig.CreatedAt),",
+				"UpdatedAt: ConvertPointerToProto_time_Time(orig.UpdatedAt),",
+				"Logins: time_TimeSliceToProto(orig.Logins),",
+				"Timeout: time_DurationToProto(orig.Timeout),",
+				"Expiries: ConvertMapToProto_string_time_Time(orig.Expiries),",
+				"CreatedAt: time_TimeFromProto(proto.CreatedAt),",
+				"UpdatedAt: ConvertPointerFromProto_time_Time(proto.UpdatedAt),",
+				"return timestamppb.New(orig)",
+				"return proto.AsTime()",
+				"return durationpb.New(orig)",
+				"return proto.AsDuration()",
+			},
+		},
+		{
+			name:     "scalars",
+			preserve: false,
+			schema: []string{
+				"string created_at = 1;",
+				"optional string updated_at = 2;",
+				"repeated string logins = 3;",
+				"int64 timeout = 4;",
+				"map<string, string> expiries = 5;",
+			},
+			types: []string{
+				"CreatedAt: time_TimeToProto(orig.CreatedAt),",
+				"UpdatedAt: builtinOptionalToProto(orig.UpdatedAt, time_TimeToProto),",
+				"Logins: time_TimeSliceToProto(orig.Logins),",
+				"Timeout: time_DurationToProto(orig.Timeout),",
+				"UpdatedAt: builtinOptionalFromProto(proto.UpdatedAt, time_TimeFromProto),",
+				"func ConvertMapToProto_string_time_Time(orig map[string]time.Time) map[string]string {",
+				"return orig.Format(time.RFC3339Nano)",
+				"func time_DurationToProto(orig time.Duration) int64 {",
+			},
+			unexpected: []string{"timestamppb", "durationpb", "ConvertSliceToProto_time_Time", "string(v)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			preserve := tt.preserve
+			config := &plugin.Config{
+				Package:          "acme.v1",
+				Syntax:           "proto3",
+				AutoNumberFields: true,
+				StartFieldNumber: 1,
+				Options:          map[string]string{"go_package": "github.com/acme/session/v1"},
+				GenerateStubs: &plugin.StubConfig{
+					Enabled:      true,
+					TypeMappings: plugin.TypeMappingConfig{PreserveTimeTypes: &preserve},
+				},
+			}
+			file := filepath.Join("testdata", "time", "session.go")
+
+			stubs, err := generateStubs(t, config, file)
+			if err != nil {
+				t.Fatalf("Failed to generate stubs: %v", err)
+			}
+			schema, err := generateProto(t, config, file)
+			if err != nil {
+				t.Fatalf("Failed to generate protobuf schema: %v", err)
+			}
+			for _, expected := range tt.schema {
+				if !strings.Contains(schema, expected) {
+					t.Errorf("Expected to find: %s\nIn schema:\n%s", expected, schema)
+				}
+			}
+			types := stubs["types.go"]
+			for _, expected := range tt.types {
+				if !strings.Contains(types, expected) {
+					t.Errorf("Expected to find: %s\nIn types.go:\n%s", expected, types)
+				}
+			}
+			for _, unexpected := range tt.unexpected {
+				if strings.Contains(types, unexpected) {
+					t.Errorf("Expected not to find: %s\nIn types.go:\n%s", unexpected, types)
+				}
+			}
+		})
+	}
+}
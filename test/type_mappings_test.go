@@ -0,0 +1,126 @@
+package main_test
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/pablor21/protoschemagen/plugin"
+)
+
+// TestTypeMappingYAML verifies that a type mapping is read from the protobuf type alone as well
+// as from the mapping with its import and converters
+func TestTypeMappingYAML(t *testing.T) {
+	data := `
+custom_mappings:
+  uuid.UUID: string
+  decimal.Decimal:
+    proto_type: google.type.Decimal
+    import: google/type/decimal.proto
+    go_type: "*google.golang.org/genproto/googleapis/type/decimal.Decimal"
+    to_proto: github.com/acme/pbconv.DecimalToProto
+    from_proto: github.com/acme/pbconv.DecimalFromProto
+`
+	var config plugin.TypeMappingConfig
+	if err := yaml.Unmarshal([]byte(data), &config); err != nil {
+		t.Fatalf("Failed to read type mappings: %v", err)
+	}
+
+	expected := map[string]plugin.TypeMapping{
+		"uuid.UUID": {ProtoType: "string"},
+		"decimal.Decimal": {
+			ProtoType: "google.type.Decimal",
+			Import:    "google/type/decimal.proto",
+			GoType:    "*google.golang.org/genproto/googleapis/type/decimal.Decimal",
+			ToProto:   "github.com/acme/pbconv.DecimalToProto",
+			FromProto: "github.com/acme/pbconv.DecimalFromProto",
+		},
+	}
+	if !reflect.DeepEqual(config.CustomMappings, expected) {
+		t.Errorf("Expected mappings %+v, got %+v", expected, config.CustomMappings)
+	}
+}
+
+func typeMappingConfig(goType string) *plugin.Config {
+	return &plugin.Config{
+		Package:          "acme.v1",
+		Syntax:           "proto3",
+		AutoNumberFields: true,
+		StartFieldNumber: 1,
+		Options:          map[string]string{"go_package": "github.com/acme/prices/v1"},
+		GenerateStubs: &plugin.StubConfig{
+			Enabled: true,
+			TypeMappings: plugin.TypeMappingConfig{
+				CustomMappings: map[string]plugin.TypeMapping{
+					"money.Decimal": {
+						ProtoType: "acme.num.Decimal",
+						Import:    "acme/num/decimal.proto",
+						GoType:    goType,
+						ToProto:   "github.com/acme/pbconv.DecimalToProto",
+						FromProto: "github.com/acme/pbconv.DecimalFromProto",
+					},
+				},
+			},
+		},
+	}
+}
+
+// TestTypeMappingStubs verifies that the schema imports the proto file of a mapped type and that
+// the adapters convert its values, pointers and slices with the converters of the mapping
+func TestTypeMappingStubs(t *testing.T) {
+	file := filepath.Join("testdata", "imports", "pricing", "pricing.go")
+	config := typeMappingConfig("*github.com/acme/pbconv/num.Decimal")
+
+	stubs, err := generateStubs(t, config, file)
+	if err != nil {
+		t.Fatalf("Failed to generate stubs: %v", err)
+	}
+	schema, err := generateProto(t, config, file)
+	if err != nil {
+		t.Fatalf("Failed to generate protobuf schema: %v", err)
+	}
+	for _, expected := range []string{
+		`import "acme/num/decimal.proto";`,
+		"acme.num.Decimal rate = 1;",
+		"acme.num.Decimal floor = 2;",
+		"repeated acme.num.Decimal history = 3;",
+	} {
+		if !strings.Contains(schema, expected) {
+			t.Errorf("Expected to find: %s\nIn schema:\n%s", expected, schema)
+		}
+	}
+
+	types := stubs["types.go"]
+	for _, expected := range []string{
+		`"github.com/acme/pbconv"`,
+		`"github.com/acme/pbconv/num"`,
+		`"github.com/pablor21/protoschemagen/test/testdata/imports/money"`,
+		"Rate: pbconv.DecimalToProto(orig.Rate),",
+		"Floor: ConvertPointerToProto_money_Decimal(orig.Floor),",
+		"History: money_DecimalSliceToProto(orig.History),",
+		"Rate: pbconv.DecimalFromProto(proto.Rate),",
+		"Floor: ConvertPointerFromProto_money_Decimal(proto.Floor),",
+		"History: money_DecimalSliceFromProto(proto.History),",
+		"func ConvertPointerToProto_money_Decimal(orig *money.Decimal) *num.Decimal {",
+		"func money_DecimalSliceFromProto(proto []*num.Decimal) []money.Decimal {",
+	} {
+		if !strings.Contains(types, expected) {
+			t.Errorf("Expected to find: %s\nIn types.go:\n%s", expected, types)
+		}
+	}
+}
+
+// TestTypeMappingWithoutGoType verifies that the stubs fail when a pointer or slice of a mapped
+// type needs the go_type the mapping doesn't declare
+func TestTypeMappingWithoutGoType(t *testing.T) {
+	_, err := generateStubs(t, typeMappingConfig(""), filepath.Join("testdata", "imports", "pricing", "pricing.go"))
+	if err == nil {
+		t.Fatal("Expected an error for a mapping without go_type")
+	}
+	if !strings.Contains(err.Error(), "field Price.Floor: the type mapping of money.Decimal needs a go_type") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
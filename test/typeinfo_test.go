@@ -0,0 +1,82 @@
+package main_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pablor21/protoschemagen/plugin"
+)
+
+func typeInfoConfig() *plugin.Config {
+	return &plugin.Config{
+		Package:          "acme.v1",
+		Syntax:           "proto3",
+		AutoNumberFields: true,
+		StartFieldNumber: 1,
+		Options:          map[string]string{"go_package": "github.com/acme/ids/v1"},
+	}
+}
+
+// TestNamedTypes verifies that named primitives map to their underlying scalar, aliases to the
+// type they stand for and string-backed constants to an enum
+func TestNamedTypes(t *testing.T) {
+	schema, err := generateProto(t, typeInfoConfig(), filepath.Join("testdata", "types", "ids", "account.go"))
+	if err != nil {
+		t.Fatalf("Failed to generate protobuf schema: %v", err)
+	}
+
+	expected := []string{
+		`import "google/protobuf/timestamp.proto";`,
+		"string id = 1;",
+		"repeated string friends = 2;",
+		"int64 score = 3;",
+		"Status status = 4;",
+		"google.protobuf.Timestamp created_at = 5;",
+		"enum Status {\n  STATUS_ACTIVE = 0;\n  STATUS_SUSPENDED = 1;",
+		"STATUS_DISABLED = 2;",
+	}
+	for _, expected := range expected {
+		if !strings.Contains(schema, expected) {
+			t.Errorf("Expected to find: %s\nIn schema:\n%s", expected, schema)
+		}
+	}
+	for _, unexpected := range []string{"UserID", "enum Score", "Stamp"} {
+		if strings.Contains(schema, unexpected) {
+			t.Errorf("Expected not to find: %s\nIn schema:\n%s", unexpected, schema)
+		}
+	}
+}
+
+// TestNamedTypeStubs verifies that the adapters convert named primitives with type conversions,
+// aliases as the type they stand for and string-backed enums through lookup tables, leaving the
+// constants that repeat the value of an earlier one out of the Go keyed table
+func TestNamedTypeStubs(t *testing.T) {
+	stubs, err := generateStubs(t, typeInfoConfig(), filepath.Join("testdata", "types", "ids", "account.go"))
+	if err != nil {
+		t.Fatalf("Failed to generate stubs: %v", err)
+	}
+	types := stubs["types.go"]
+
+	expected := []string{
+		"Id: string(orig.ID),",
+		"ID: ids.UserID(proto.Id),",
+		"Friends: ConvertSliceToProto_ids_UserID(orig.Friends),",
+		"Score: int64(orig.Score),",
+		"Score: ids.Score(proto.Score),",
+		"CreatedAt: time_TimeToProto(orig.CreatedAt),",
+		"CreatedAt: time_TimeFromProto(proto.CreatedAt),",
+		"var StatusToProtoValues = map[ids.Status]pb.Status{\n\tids.StatusActive: pb.Status_STATUS_ACTIVE,\n\tids.StatusSuspended: pb.Status_STATUS_SUSPENDED,\n}",
+		"\tpb.Status_STATUS_DISABLED: ids.StatusDisabled,\n",
+	}
+	for _, expected := range expected {
+		if !strings.Contains(types, expected) {
+			t.Errorf("Expected to find: %s\nIn types.go:\n%s", expected, types)
+		}
+	}
+	for _, unexpected := range []string{"UserIDToProto", "StampToProto"} {
+		if strings.Contains(types, unexpected) {
+			t.Errorf("Expected not to find: %s\nIn types.go:\n%s", unexpected, types)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pablor21/protoschemagen/plugin"
+)
+
+func unionConfig() *plugin.Config {
+	return &plugin.Config{
+		Package:          "acme.v1",
+		Syntax:           "proto3",
+		AutoNumberFields: true,
+		StartFieldNumber: 1,
+		Options:          map[string]string{"go_package": "github.com/acme/shapes/v1"},
+	}
+}
+
+// TestUnionOneofs verifies that an @union field becomes a oneof with one case per variant, the
+// variants being discovered from the method set of the interface or listed in order
+func TestUnionOneofs(t *testing.T) {
+	schema, err := generateProto(t, unionConfig(), filepath.Join("testdata", "union", "shape.go"))
+	if err != nil {
+		t.Fatalf("Failed to generate protobuf schema: %v", err)
+	}
+
+	expected := []string{
+		// Point doesn't implement isShape, so it isn't a case of shape
+		"  oneof shape {\n    Circle circle = 2;\n    Square square = 3;\n  }",
+		"  oneof marker {\n    Point point = 4;\n    Label label = 5;\n  }",
+	}
+	for _, expected := range expected {
+		if !strings.Contains(schema, expected) {
+			t.Errorf("Expected to find: %s\nIn schema:\n%s", expected, schema)
+		}
+	}
+	if strings.Contains(schema, "google.protobuf.Any") {
+		t.Errorf("Expected union fields not to be written as Any\nIn schema:\n%s", schema)
+	}
+}
+
+// TestUnionStubs verifies that the adapters type-switch across the variants of a union, matching
+// the variants that implement it with pointer receivers as pointers only
+func TestUnionStubs(t *testing.T) {
+	stubs, err := generateStubs(t, unionConfig(), filepath.Join("testdata", "union", "shape.go"))
+	if err != nil {
+		t.Fatalf("Failed to generate stubs: %v", err)
+	}
+	types := stubs["types.go"]
+
+	expected := []string{
+		"Drawing_ShapeToProto(proto, orig.Shape)",
+		"orig.Shape = Drawing_ShapeFromProto(proto)",
+		"func Drawing_ShapeToProto(proto *pb.Drawing, orig union.Shape) {\n\tswitch v := orig.(type) {\n\tcase union.Circle:\n\t\tproto.Shape = &pb.Drawing_Circle{Circle: CircleToProto(v)}\n\tcase *union.Circle:",
+		"\tcase *union.Square:\n\t\tif v != nil {\n\t\t\tproto.Shape = &pb.Drawing_Square{Square: SquareToProto(*v)}",
+		"\tcase *pb.Drawing_Circle:\n\t\treturn CircleFromProto(v.Circle)",
+		"\tcase *pb.Drawing_Square:\n\t\tresult := SquareFromProto(v.Square)\n\t\treturn &result",
+		// Variants listed without methods are matched in both forms
+		"\tcase union.Label:\n\t\tproto.Marker = &pb.Drawing_Label{Label: LabelToProto(v)}\n\tcase *union.Label:",
+	}
+	for _, expected := range expected {
+		if !strings.Contains(types, expected) {
+			t.Errorf("Expected to find: %s\nIn types.go:\n%s", expected, types)
+		}
+	}
+	// Square only implements Shape as a pointer, a value case wouldn't compile
+	if strings.Contains(types, "case union.Square:") {
+		t.Errorf("Expected Square to be matched as a pointer only\nIn types.go:\n%s", types)
+	}
+}
+
+// TestUnionValidation verifies that union fields can't be repeated and that unions of a message
+// can't share variants
+func TestUnionValidation(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+	}{
+		{name: "repeated union", file: filepath.Join("testdata", "union", "repeated", "repeated.go")},
+		{name: "shared variant", file: filepath.Join("testdata", "union", "conflict", "conflict.go")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := generateProto(t, unionConfig(), tt.file)
+			if err == nil {
+				t.Fatal("Expected the generation to fail")
+			}
+			if !strings.Contains(err.Error(), "1 error(s)") {
+				t.Errorf("Expected the union field to be reported, got: %v", err)
+			}
+		})
+	}
+}